@@ -6,6 +6,7 @@ package api
 
 import (
 	"context"
+	"iter"
 
 	"github.com/influxdata/influxdb-client-go/v2/domain"
 )
@@ -14,6 +15,9 @@ import (
 type AuthorizationsAPI interface {
 	// GetAuthorizations returns all authorizations
 	GetAuthorizations(ctx context.Context) (*[]domain.Authorization, error)
+	// IterateAuthorizations returns an iterator over all authorizations.
+	// Authorizations are not paged by the server, they are fetched by a single request when the iteration starts.
+	IterateAuthorizations(ctx context.Context) iter.Seq2[domain.Authorization, error]
 	// FindAuthorizationsByUserName returns all authorizations for given userName
 	FindAuthorizationsByUserName(ctx context.Context, userName string) (*[]domain.Authorization, error)
 	// FindAuthorizationsByUserID returns all authorizations for given userID
@@ -53,6 +57,12 @@ func (a *authorizationsAPI) GetAuthorizations(ctx context.Context) (*[]domain.Au
 	return a.listAuthorizations(ctx, authQuery)
 }
 
+func (a *authorizationsAPI) IterateAuthorizations(ctx context.Context) iter.Seq2[domain.Authorization, error] {
+	return iterateSlice(func() (*[]domain.Authorization, error) {
+		return a.GetAuthorizations(ctx)
+	})
+}
+
 func (a *authorizationsAPI) FindAuthorizationsByUserName(ctx context.Context, userName string) (*[]domain.Authorization, error) {
 	authQuery := &domain.GetAuthorizationsParams{User: &userName}
 	return a.listAuthorizations(ctx, authQuery)
@@ -0,0 +1,155 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/influxdata/influxdb-client-go/v2/internal/schedule"
+)
+
+// BackfillOption is the function type for applying backfill option
+type BackfillOption func(b *backfillOptions)
+
+// backfillOptions holds parameters of TasksAPI.Backfill
+type backfillOptions struct {
+	// Maximum number of runs processed at the same time
+	concurrency int
+	// Whether to wait for runs to finish
+	wait bool
+	// Interval of checking run status when waiting for a run
+	pollInterval time.Duration
+}
+
+// defaultBackfillOptions returns default backfill options: concurrency 4, waiting for runs with 1s poll interval
+func defaultBackfillOptions() *backfillOptions {
+	return &backfillOptions{concurrency: 4, wait: true, pollInterval: time.Second}
+}
+
+// BackfillWithConcurrency sets maximum number of runs submitted and waited for at the same time. Default 4.
+func BackfillWithConcurrency(concurrency int) BackfillOption {
+	return func(b *backfillOptions) {
+		b.concurrency = concurrency
+	}
+}
+
+// BackfillWithWait sets whether Backfill waits for each run to finish. Default true.
+// When false, a run is reported as successful once it is submitted.
+func BackfillWithWait(wait bool) BackfillOption {
+	return func(b *backfillOptions) {
+		b.wait = wait
+	}
+}
+
+// BackfillWithPollInterval sets interval of checking status of a run being waited for. Default 1s.
+func BackfillWithPollInterval(pollInterval time.Duration) BackfillOption {
+	return func(b *backfillOptions) {
+		b.pollInterval = pollInterval
+	}
+}
+
+// BackfillResult holds result of a single task run submitted by TasksAPI.Backfill
+type BackfillResult struct {
+	// ScheduledFor is the time used for the run's `now` option
+	ScheduledFor time.Time
+	// Run is the submitted run, or the finished run when waiting for runs. Nil if the run couldn't be submitted.
+	Run *domain.Run
+	// Logs holds log events of the finished run, when waiting for runs
+	Logs []domain.LogEvent
+	// Err is set when the run couldn't be submitted, waited for, or when it didn't finish successfully
+	Err error
+}
+
+// Succeeded returns true if the run was submitted, and finished successfully when waiting for runs
+func (r *BackfillResult) Succeeded() bool {
+	return r.Err == nil
+}
+
+func (t *tasksAPI) Backfill(ctx context.Context, task *domain.Task, start, stop time.Time, options ...BackfillOption) ([]BackfillResult, error) {
+	opts := defaultBackfillOptions()
+	for _, opt := range options {
+		opt(opts)
+	}
+	if opts.concurrency < 1 {
+		opts.concurrency = 1
+	}
+	sched, err := taskSchedule(task)
+	if err != nil {
+		return nil, err
+	}
+	times := schedule.Times(sched, start, stop)
+	results := make([]BackfillResult, len(times))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency && i < len(times); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				t.backfillRun(ctx, task.Id, &results[i], opts)
+			}
+		}()
+	}
+	for i, tm := range times {
+		results[i].ScheduledFor = tm
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results, nil
+}
+
+// backfillRun submits a run scheduled for result.ScheduledFor and, when required, waits for it to finish
+func (t *tasksAPI) backfillRun(ctx context.Context, taskID string, result *BackfillResult, opts *backfillOptions) {
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return
+	}
+	run, err := t.RunManuallyAtWithID(ctx, taskID, result.ScheduledFor)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	result.Run = run
+	if !opts.wait {
+		return
+	}
+	finished, logs, err := t.WaitForRun(ctx, run, opts.pollInterval)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	result.Run = finished
+	result.Logs = logs
+	if *finished.Status != domain.RunStatusSuccess {
+		result.Err = fmt.Errorf("run '%s' scheduled for %s finished with status '%s'", *finished.Id, result.ScheduledFor.Format(time.RFC3339), *finished.Status)
+	}
+}
+
+// taskSchedule returns schedule of the task, defined by its every or cron option
+func taskSchedule(task *domain.Task) (schedule.Schedule, error) {
+	switch {
+	case task.Every != nil && *task.Every != "":
+		every, err := schedule.ParseDuration(*task.Every)
+		if err != nil {
+			return nil, fmt.Errorf("task '%s': %w", task.Name, err)
+		}
+		if every <= 0 {
+			return nil, fmt.Errorf("task '%s': every must be positive", task.Name)
+		}
+		return schedule.Every(every), nil
+	case task.Cron != nil && *task.Cron != "":
+		sched, err := schedule.ParseCron(*task.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("task '%s': %w", task.Name, err)
+		}
+		return sched, nil
+	}
+	return nil, fmt.Errorf("task '%s' has neither every nor cron set", task.Name)
+}
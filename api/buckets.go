@@ -7,6 +7,8 @@ package api
 import (
 	"context"
 	"fmt"
+	"iter"
+
 	"github.com/influxdata/influxdb-client-go/v2/domain"
 )
 
@@ -15,6 +17,11 @@ type BucketsAPI interface {
 	// GetBuckets returns all buckets.
 	// GetBuckets supports PagingOptions: Offset, Limit, After. Empty pagingOptions means the default paging (first 20 results).
 	GetBuckets(ctx context.Context, pagingOptions ...PagingOption) (*[]domain.Bucket, error)
+	// IterateBuckets returns an iterator over all buckets, which are fetched page by page on demand.
+	// IterateBuckets supports PagingOptions: Offset, Limit, After. Limit sets the page size, default is 100.
+	// Pages are followed using After, if it is set, otherwise using Offset.
+	// Iteration ends with the first error.
+	IterateBuckets(ctx context.Context, pagingOptions ...PagingOption) iter.Seq2[domain.Bucket, error]
 	// FindBucketByName returns a bucket found using bucketName.
 	FindBucketByName(ctx context.Context, bucketName string) (*domain.Bucket, error)
 	// FindBucketByID returns a bucket found using bucketID.
@@ -60,6 +67,22 @@ type BucketsAPI interface {
 	RemoveOwner(ctx context.Context, bucket *domain.Bucket, user *domain.User) error
 	// RemoveOwnerWithID removes a member with id memberID from a bucket with bucketID.
 	RemoveOwnerWithID(ctx context.Context, bucketID, memberID string) error
+	// FindLabels returns labels of a bucket.
+	FindLabels(ctx context.Context, bucket *domain.Bucket) ([]domain.Label, error)
+	// FindLabelsWithID returns labels of a bucket with bucketID.
+	FindLabelsWithID(ctx context.Context, bucketID string) ([]domain.Label, error)
+	// AddLabel adds a label to a bucket.
+	AddLabel(ctx context.Context, bucket *domain.Bucket, label *domain.Label) (*domain.Label, error)
+	// AddLabelWithID adds a label with id labelID to a bucket with bucketID.
+	AddLabelWithID(ctx context.Context, bucketID, labelID string) (*domain.Label, error)
+	// RemoveLabel removes a label from a bucket.
+	RemoveLabel(ctx context.Context, bucket *domain.Bucket, label *domain.Label) error
+	// RemoveLabelWithID removes a label with id labelID from a bucket with bucketID.
+	RemoveLabelWithID(ctx context.Context, bucketID, labelID string) error
+	// FindMeasurementSchemas returns measurement schemas of a bucket with explicit schema type.
+	FindMeasurementSchemas(ctx context.Context, bucket *domain.Bucket) ([]domain.MeasurementSchema, error)
+	// FindMeasurementSchemasWithID returns measurement schemas of a bucket with bucketID, belonging to the organization with orgID.
+	FindMeasurementSchemasWithID(ctx context.Context, orgID, bucketID string) ([]domain.MeasurementSchema, error)
 }
 
 // bucketsAPI implements BucketsAPI
@@ -79,17 +102,21 @@ func (b *bucketsAPI) GetBuckets(ctx context.Context, pagingOptions ...PagingOpti
 }
 
 func (b *bucketsAPI) getBuckets(ctx context.Context, params *domain.GetBucketsParams, pagingOptions ...PagingOption) (*[]domain.Bucket, error) {
+	return b.getBucketsPage(ctx, params, newPaging(pagingOptions...))
+}
+
+func (b *bucketsAPI) getBucketsPage(ctx context.Context, params *domain.GetBucketsParams, options *Paging) (*[]domain.Bucket, error) {
 	if params == nil {
 		params = &domain.GetBucketsParams{}
 	}
-	options := defaultPaging()
-	for _, opt := range pagingOptions {
-		opt(options)
-	}
 	if options.limit > 0 {
 		params.Limit = &options.limit
 	}
-	params.Offset = &options.offset
+	if options.after != "" {
+		params.After = &options.after
+	} else {
+		params.Offset = &options.offset
+	}
 
 	response, err := b.apiClient.GetBuckets(ctx, params)
 	if err != nil {
@@ -98,6 +125,23 @@ func (b *bucketsAPI) getBuckets(ctx context.Context, params *domain.GetBucketsPa
 	return response.Buckets, nil
 }
 
+func (b *bucketsAPI) IterateBuckets(ctx context.Context, pagingOptions ...PagingOption) iter.Seq2[domain.Bucket, error] {
+	paging := newPaging(pagingOptions...)
+	cursor := offsetCursor
+	if paging.after != "" {
+		cursor = afterCursor
+	}
+	return iteratePages(paging, cursor, func(p *Paging) ([]domain.Bucket, error) {
+		buckets, err := b.getBucketsPage(ctx, nil, p)
+		if err != nil || buckets == nil {
+			return nil, err
+		}
+		return *buckets, nil
+	}, func(bucket *domain.Bucket) *string {
+		return bucket.Id
+	})
+}
+
 func (b *bucketsAPI) FindBucketByName(ctx context.Context, bucketName string) (*domain.Bucket, error) {
 	params := &domain.GetBucketsParams{Name: &bucketName}
 	response, err := b.apiClient.GetBuckets(ctx, params)
@@ -255,6 +299,52 @@ func (b *bucketsAPI) RemoveOwnerWithID(ctx context.Context, bucketID, memberID s
 	return b.apiClient.DeleteBucketsIDOwnersID(ctx, params)
 }
 
+func (b *bucketsAPI) FindLabels(ctx context.Context, bucket *domain.Bucket) ([]domain.Label, error) {
+	return b.FindLabelsWithID(ctx, *bucket.Id)
+}
+
+func (b *bucketsAPI) FindLabelsWithID(ctx context.Context, bucketID string) ([]domain.Label, error) {
+	params := &domain.GetBucketsIDLabelsAllParams{
+		BucketID: bucketID,
+	}
+	response, err := b.apiClient.GetBucketsIDLabels(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if response.Labels == nil {
+		return nil, fmt.Errorf("labels for bucket '%s' not found", bucketID)
+	}
+	return *response.Labels, nil
+}
+
+func (b *bucketsAPI) AddLabel(ctx context.Context, bucket *domain.Bucket, label *domain.Label) (*domain.Label, error) {
+	return b.AddLabelWithID(ctx, *bucket.Id, *label.Id)
+}
+
+func (b *bucketsAPI) AddLabelWithID(ctx context.Context, bucketID, labelID string) (*domain.Label, error) {
+	params := &domain.PostBucketsIDLabelsAllParams{
+		BucketID: bucketID,
+		Body:     domain.PostBucketsIDLabelsJSONRequestBody{LabelID: &labelID},
+	}
+	response, err := b.apiClient.PostBucketsIDLabels(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return response.Label, nil
+}
+
+func (b *bucketsAPI) RemoveLabel(ctx context.Context, bucket *domain.Bucket, label *domain.Label) error {
+	return b.RemoveLabelWithID(ctx, *bucket.Id, *label.Id)
+}
+
+func (b *bucketsAPI) RemoveLabelWithID(ctx context.Context, bucketID, labelID string) error {
+	params := &domain.DeleteBucketsIDLabelsIDAllParams{
+		BucketID: bucketID,
+		LabelID:  labelID,
+	}
+	return b.apiClient.DeleteBucketsIDLabelsID(ctx, params)
+}
+
 func retentionRulesToPatchRetentionRules(rrs *domain.RetentionRules) *domain.PatchRetentionRules {
 	if rrs == nil {
 		return nil
@@ -273,3 +363,19 @@ func retentionRulesToPatchRetentionRules(rrs *domain.RetentionRules) *domain.Pat
 	dprrs := domain.PatchRetentionRules(prrs)
 	return &dprrs
 }
+
+func (b *bucketsAPI) FindMeasurementSchemas(ctx context.Context, bucket *domain.Bucket) ([]domain.MeasurementSchema, error) {
+	return b.FindMeasurementSchemasWithID(ctx, *bucket.OrgID, *bucket.Id)
+}
+
+func (b *bucketsAPI) FindMeasurementSchemasWithID(ctx context.Context, orgID, bucketID string) ([]domain.MeasurementSchema, error) {
+	params := &domain.GetMeasurementSchemasAllParams{
+		GetMeasurementSchemasParams: domain.GetMeasurementSchemasParams{OrgID: &orgID},
+		BucketID:                    bucketID,
+	}
+	response, err := b.apiClient.GetMeasurementSchemas(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return response.MeasurementSchemas, nil
+}
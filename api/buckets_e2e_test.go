@@ -227,6 +227,13 @@ func TestBucketsAPI_paging(t *testing.T) {
 	require.Nil(t, err, err)
 	require.NotNil(t, buckets)
 	assert.Len(t, *buckets, 30+bucketsNum)
+	// test iterating over all buckets with small pages
+	count := 0
+	for _, err := range bucketsAPI.IterateBuckets(ctx, api.PagingWithLimit(7)) {
+		require.Nil(t, err, err)
+		count++
+	}
+	assert.Equal(t, 30+bucketsNum, count)
 	// test filtering buckets by org id
 	buckets, err = bucketsAPI.FindBucketsByOrgID(ctx, *org.Id, api.PagingWithLimit(100))
 	require.Nil(t, err, err)
@@ -256,6 +263,53 @@ func TestBucketsAPI_paging(t *testing.T) {
 	assert.Nil(t, err, err)
 
 }
+func TestBucketsAPI_Labels(t *testing.T) {
+	ctx := context.Background()
+	client := influxdb2.NewClient(serverURL, authToken)
+	bucketsAPI := client.BucketsAPI()
+	labelsAPI := client.LabelsAPI()
+
+	org, err := client.OrganizationsAPI().FindOrganizationByName(ctx, "my-org")
+	require.Nil(t, err)
+	require.NotNil(t, org)
+
+	bucket, err := bucketsAPI.CreateBucketWithName(ctx, org, "bucket-labels")
+	require.Nil(t, err, err)
+	require.NotNil(t, bucket)
+
+	labels, err := bucketsAPI.FindLabels(ctx, bucket)
+	require.Nil(t, err, err)
+	require.NotNil(t, labels)
+	assert.Len(t, labels, 0)
+
+	label, err := labelsAPI.CreateLabelWithName(ctx, org, "bucket-label", nil)
+	require.Nil(t, err, err)
+	require.NotNil(t, label)
+
+	labelx, err := bucketsAPI.AddLabel(ctx, bucket, label)
+	require.Nil(t, err, err)
+	require.NotNil(t, labelx)
+
+	labels, err = bucketsAPI.FindLabels(ctx, bucket)
+	require.Nil(t, err, err)
+	require.NotNil(t, labels)
+	assert.Len(t, labels, 1)
+
+	err = bucketsAPI.RemoveLabel(ctx, bucket, label)
+	require.Nil(t, err, err)
+
+	labels, err = bucketsAPI.FindLabels(ctx, bucket)
+	require.Nil(t, err, err)
+	require.NotNil(t, labels)
+	assert.Len(t, labels, 0)
+
+	err = labelsAPI.DeleteLabel(ctx, label)
+	assert.Nil(t, err, err)
+
+	err = bucketsAPI.DeleteBucket(ctx, bucket)
+	assert.Nil(t, err, err)
+}
+
 func TestBucketsAPI_failures(t *testing.T) {
 	ctx := context.Background()
 	client := influxdb2.NewClient(serverURL, authToken)
@@ -297,6 +351,15 @@ func TestBucketsAPI_failures(t *testing.T) {
 	err = bucketsAPI.RemoveOwnerWithID(ctx, invalidID, notExistingID)
 	assert.NotNil(t, err)
 
+	_, err = bucketsAPI.AddLabelWithID(ctx, notExistingID, invalidID)
+	assert.NotNil(t, err)
+
+	_, err = bucketsAPI.FindLabelsWithID(ctx, invalidID)
+	assert.NotNil(t, err)
+
+	err = bucketsAPI.RemoveLabelWithID(ctx, notExistingID, invalidID)
+	assert.NotNil(t, err)
+
 	//delete with invalid id
 	err = bucketsAPI.DeleteBucketWithID(ctx, invalidID)
 	assert.NotNil(t, err)
@@ -347,4 +410,13 @@ func TestBucketsAPI_requestFailing(t *testing.T) {
 
 	err = bucketsAPI.RemoveOwnerWithID(ctx, *bucket.Id, *user.Id)
 	assert.NotNil(t, err)
+
+	_, err = bucketsAPI.FindLabels(ctx, bucket)
+	assert.NotNil(t, err)
+
+	_, err = bucketsAPI.AddLabelWithID(ctx, *bucket.Id, anID)
+	assert.NotNil(t, err)
+
+	err = bucketsAPI.RemoveLabelWithID(ctx, *bucket.Id, anID)
+	assert.NotNil(t, err)
 }
@@ -506,3 +506,53 @@ fruitCollected
 	// Close the client
 	client.Close()
 }
+
+func ExampleTemplatesAPI() {
+	// Create a new client using an InfluxDB server base URL and an authentication token
+	client := influxdb2.NewClient("http://localhost:8086", "my-token")
+
+	ctx := context.Background()
+	// Get Templates API client
+	templatesAPI := client.TemplatesAPI()
+	// Get organization that will own resources of the template
+	myorg, err := client.OrganizationsAPI().FindOrganizationByName(ctx, "my-org")
+	if err != nil {
+		panic(err)
+	}
+	template, err := api.ParseTemplate([]byte(`apiVersion: influxdata.com/v2alpha1
+kind: Bucket
+metadata:
+  name: sensors
+spec:
+  name:
+    envRef:
+      key: bucket-name
+`))
+	if err != nil {
+		panic(err)
+	}
+	params := &api.TemplateApplyParams{
+		Templates: []domain.Template{template},
+		EnvRefs:   map[string]interface{}{"bucket-name": "sensors-prod"},
+		DryRun:    true,
+	}
+	// Review changes
+	res, err := templatesAPI.ApplyTemplate(ctx, myorg, params)
+	if err != nil {
+		panic(err)
+	}
+	for _, change := range res.Changes {
+		fmt.Println(change.Kind, change.TemplateMetaName, change.Status, change.Modified)
+	}
+
+	// Apply the template and keep the stack ID for updating resources later
+	params.DryRun = false
+	res, err = templatesAPI.ApplyTemplate(ctx, myorg, params)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Stack ID:", res.StackID)
+
+	// Close the client
+	client.Close()
+}
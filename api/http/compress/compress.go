@@ -0,0 +1,216 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+// Package compress provides http.Codec implementations of zstd and snappy content encodings, for servers which support them.
+// They are in a separate package, so that clients using only gzip don't depend on the compression library.
+package compress
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdCodec is zstd Codec with pooled encoders and decoders
+type zstdCodec struct {
+	level    zstd.EncoderLevel
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+var (
+	zstdCodecsMu sync.Mutex
+	zstdCodecs   = make(map[zstd.EncoderLevel]*zstdCodec)
+)
+
+// ZstdCodec returns zstd Codec compressing with the encoder level closest to zstd level, from 1 (fastest) to 22 (best compression).
+// Codecs of the same encoder level share pooled encoders.
+func ZstdCodec(level int) http.Codec {
+	l := zstd.EncoderLevelFromZstd(level)
+	zstdCodecsMu.Lock()
+	defer zstdCodecsMu.Unlock()
+	c, ok := zstdCodecs[l]
+	if !ok {
+		c = &zstdCodec{level: l}
+		zstdCodecs[l] = c
+	}
+	return c
+}
+
+// Encoding returns zstd
+func (c *zstdCodec) Encoding() string {
+	return "zstd"
+}
+
+// NewWriter returns pooled zstd encoder compressing into w
+func (c *zstdCodec) NewWriter(w io.Writer) io.WriteCloser {
+	enc, ok := c.encoders.Get().(*zstd.Encoder)
+	if ok {
+		enc.Reset(w)
+	} else {
+		// options are valid
+		enc, _ = zstd.NewWriter(w, zstd.WithEncoderLevel(c.level), zstd.WithEncoderConcurrency(1))
+	}
+	return &zstdWriter{Encoder: enc, codec: c}
+}
+
+// NewReader returns pooled zstd decoder decompressing r
+func (c *zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	dec, ok := c.decoders.Get().(*zstd.Decoder)
+	var err error
+	if ok {
+		err = dec.Reset(r)
+	} else {
+		// decoding without concurrency doesn't start goroutines, so that the decoder needn't be closed
+		dec, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &zstdReader{Decoder: dec, codec: c}, nil
+}
+
+// zstdWriter returns zstd encoder to the pool of codec when closed
+type zstdWriter struct {
+	*zstd.Encoder
+	codec *zstdCodec
+}
+
+// Write compresses p, it fails after Close
+func (w *zstdWriter) Write(p []byte) (int, error) {
+	if w.Encoder == nil {
+		return 0, os.ErrClosed
+	}
+	return w.Encoder.Write(p)
+}
+
+// Close flushes compressed data and returns the encoder to the pool
+func (w *zstdWriter) Close() error {
+	if w.Encoder == nil {
+		return nil
+	}
+	err := w.Encoder.Close()
+	w.codec.encoders.Put(w.Encoder)
+	w.Encoder = nil
+	return err
+}
+
+// zstdReader returns zstd decoder to the pool of codec when closed
+type zstdReader struct {
+	*zstd.Decoder
+	codec *zstdCodec
+}
+
+// Read reads decompressed data, it fails after Close
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.Decoder == nil {
+		return 0, os.ErrClosed
+	}
+	return r.Decoder.Read(p)
+}
+
+// Close releases the decompressed reader and returns the decoder to the pool
+func (r *zstdReader) Close() error {
+	if r.Decoder == nil {
+		return nil
+	}
+	err := r.Decoder.Reset(nil)
+	r.codec.decoders.Put(r.Decoder)
+	r.Decoder = nil
+	return err
+}
+
+// snappyCodec is snappy Codec with pooled writers and readers
+type snappyCodec struct {
+	writers sync.Pool
+	readers sync.Pool
+}
+
+var defaultSnappyCodec = &snappyCodec{}
+
+// SnappyCodec returns Codec of snappy framing format with content encoding x-snappy-framed
+func SnappyCodec() http.Codec {
+	return defaultSnappyCodec
+}
+
+// Encoding returns x-snappy-framed
+func (c *snappyCodec) Encoding() string {
+	return "x-snappy-framed"
+}
+
+// NewWriter returns pooled snappy writer compressing into w
+func (c *snappyCodec) NewWriter(w io.Writer) io.WriteCloser {
+	sw, ok := c.writers.Get().(*s2.Writer)
+	if ok {
+		sw.Reset(w)
+	} else {
+		sw = s2.NewWriter(w, s2.WriterSnappyCompat(), s2.WriterConcurrency(1))
+	}
+	return &snappyWriter{Writer: sw, codec: c}
+}
+
+// NewReader returns pooled snappy reader decompressing r
+func (c *snappyCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	sr, ok := c.readers.Get().(*s2.Reader)
+	if ok {
+		sr.Reset(r)
+	} else {
+		sr = s2.NewReader(r)
+	}
+	return &snappyReader{Reader: sr, codec: c}, nil
+}
+
+// snappyWriter returns snappy writer to the pool of codec when closed
+type snappyWriter struct {
+	*s2.Writer
+	codec *snappyCodec
+}
+
+// Write compresses p, it fails after Close
+func (w *snappyWriter) Write(p []byte) (int, error) {
+	if w.Writer == nil {
+		return 0, os.ErrClosed
+	}
+	return w.Writer.Write(p)
+}
+
+// Close flushes compressed data and returns the writer to the pool
+func (w *snappyWriter) Close() error {
+	if w.Writer == nil {
+		return nil
+	}
+	err := w.Writer.Close()
+	w.codec.writers.Put(w.Writer)
+	w.Writer = nil
+	return err
+}
+
+// snappyReader returns snappy reader to the pool of codec when closed
+type snappyReader struct {
+	*s2.Reader
+	codec *snappyCodec
+}
+
+// Read reads decompressed data, it fails after Close
+func (r *snappyReader) Read(p []byte) (int, error) {
+	if r.Reader == nil {
+		return 0, os.ErrClosed
+	}
+	return r.Reader.Read(p)
+}
+
+// Close returns the reader to the pool
+func (r *snappyReader) Close() error {
+	if r.Reader == nil {
+		return nil
+	}
+	r.Reader.Reset(nil)
+	r.codec.readers.Put(r.Reader)
+	r.Reader = nil
+	return nil
+}
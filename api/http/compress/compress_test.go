@@ -0,0 +1,106 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package compress_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/http/compress"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCodec(t *testing.T, codec http.Codec, decode func([]byte) ([]byte, error)) {
+	text := strings.Repeat("cpu,host=server01 usage=0.5 1600000000000000000\n", 100)
+	for range 2 {
+		var buf bytes.Buffer
+		w := codec.NewWriter(&buf)
+		_, err := io.WriteString(w, text)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		// double close is harmless
+		require.NoError(t, w.Close())
+		_, err = w.Write([]byte(text))
+		assert.ErrorIs(t, err, os.ErrClosed)
+
+		// compressed data is readable by the compression library
+		res, err := decode(buf.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, text, string(res))
+
+		r, err := codec.NewReader(&buf)
+		require.NoError(t, err)
+		res, err = io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, text, string(res))
+		require.NoError(t, r.Close())
+		require.NoError(t, r.Close())
+		_, err = r.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	}
+
+	r, err := codec.NewReader(strings.NewReader("not compressed"))
+	if err == nil {
+		_, err = io.ReadAll(r)
+		require.NoError(t, r.Close())
+	}
+	assert.Error(t, err)
+}
+
+func testCodecConcurrent(t *testing.T, codec http.Codec) {
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text := strings.Repeat("m v=1i\n", i+1)
+			var buf bytes.Buffer
+			w := codec.NewWriter(&buf)
+			_, _ = io.WriteString(w, text)
+			assert.NoError(t, w.Close())
+			r, err := codec.NewReader(&buf)
+			if assert.NoError(t, err) {
+				res, _ := io.ReadAll(r)
+				assert.Equal(t, text, string(res))
+				assert.NoError(t, r.Close())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestZstdCodec(t *testing.T) {
+	dec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer dec.Close()
+	for _, level := range []int{1, 3, 7, 22} {
+		codec := compress.ZstdCodec(level)
+		assert.Equal(t, "zstd", codec.Encoding())
+		testCodec(t, codec, func(b []byte) ([]byte, error) {
+			return dec.DecodeAll(b, nil)
+		})
+	}
+	assert.Same(t, compress.ZstdCodec(3), compress.ZstdCodec(3))
+	assert.Same(t, compress.ZstdCodec(3), compress.ZstdCodec(4))
+	testCodecConcurrent(t, compress.ZstdCodec(1))
+}
+
+func TestSnappyCodec(t *testing.T) {
+	codec := compress.SnappyCodec()
+	assert.Equal(t, "x-snappy-framed", codec.Encoding())
+	testCodec(t, codec, func(b []byte) ([]byte, error) {
+		return io.ReadAll(s2.NewReader(bytes.NewReader(b)))
+	})
+	assert.Same(t, codec, compress.SnappyCodec())
+	testCodecConcurrent(t, codec)
+}
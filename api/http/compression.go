@@ -0,0 +1,135 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"sync"
+)
+
+// Codec compresses request bodies and decompresses response bodies with a content encoding.
+// GzipCodec is provided, zstd and snappy codecs are provided by package compress,
+// if the server supports them.
+// Codec must be safe for concurrent use.
+type Codec interface {
+	// Encoding returns name of the content encoding, e.g. gzip
+	Encoding() string
+	// NewWriter returns writer compressing data into w, Close flushes compressed data and releases the writer
+	NewWriter(w io.Writer) io.WriteCloser
+	// NewReader returns reader decompressing data read from r, Close releases the reader, but doesn't close r
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// gzipCodec is gzip Codec with pooled writers and readers
+type gzipCodec struct {
+	level   int
+	writers sync.Pool
+	readers sync.Pool
+}
+
+var (
+	gzipCodecsMu sync.Mutex
+	gzipCodecs   = make(map[int]*gzipCodec)
+)
+
+// GzipCodec returns gzip Codec compressing with level, from gzip.HuffmanOnly to gzip.BestCompression.
+// Invalid level is replaced by gzip.DefaultCompression. Codecs of the same level share pooled writers.
+func GzipCodec(level int) Codec {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	gzipCodecsMu.Lock()
+	defer gzipCodecsMu.Unlock()
+	c, ok := gzipCodecs[level]
+	if !ok {
+		c = &gzipCodec{level: level}
+		gzipCodecs[level] = c
+	}
+	return c
+}
+
+// Encoding returns gzip
+func (c *gzipCodec) Encoding() string {
+	return "gzip"
+}
+
+// NewWriter returns pooled gzip writer compressing into w
+func (c *gzipCodec) NewWriter(w io.Writer) io.WriteCloser {
+	gw, ok := c.writers.Get().(*gzip.Writer)
+	if ok {
+		gw.Reset(w)
+	} else {
+		// level is valid
+		gw, _ = gzip.NewWriterLevel(w, c.level)
+	}
+	return &gzipWriter{Writer: gw, codec: c}
+}
+
+// NewReader returns pooled gzip reader decompressing r
+func (c *gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	gr, ok := c.readers.Get().(*gzip.Reader)
+	var err error
+	if ok {
+		err = gr.Reset(r)
+	} else {
+		gr, err = gzip.NewReader(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &gzipReader{Reader: gr, codec: c}, nil
+}
+
+// gzipWriter returns gzip writer to the pool of codec when closed
+type gzipWriter struct {
+	*gzip.Writer
+	codec *gzipCodec
+}
+
+// Write compresses p, it fails after Close
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	if w.Writer == nil {
+		return 0, os.ErrClosed
+	}
+	return w.Writer.Write(p)
+}
+
+// Close flushes compressed data and returns the writer to the pool
+func (w *gzipWriter) Close() error {
+	if w.Writer == nil {
+		return nil
+	}
+	err := w.Writer.Close()
+	w.codec.writers.Put(w.Writer)
+	w.Writer = nil
+	return err
+}
+
+// gzipReader returns gzip reader to the pool of codec when closed
+type gzipReader struct {
+	*gzip.Reader
+	codec *gzipCodec
+}
+
+// Read reads decompressed data, it fails after Close
+func (r *gzipReader) Read(p []byte) (int, error) {
+	if r.Reader == nil {
+		return 0, os.ErrClosed
+	}
+	return r.Reader.Read(p)
+}
+
+// Close returns the reader to the pool
+func (r *gzipReader) Close() error {
+	if r.Reader == nil {
+		return nil
+	}
+	err := r.Reader.Close()
+	r.codec.readers.Put(r.Reader)
+	r.Reader = nil
+	return err
+}
@@ -0,0 +1,82 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipCodec(t *testing.T) {
+	text := strings.Repeat("cpu,host=server01 usage=0.5 1600000000000000000\n", 100)
+	for _, level := range []int{gzip.HuffmanOnly, gzip.DefaultCompression, gzip.NoCompression, gzip.BestSpeed, gzip.BestCompression} {
+		codec := http.GzipCodec(level)
+		assert.Equal(t, "gzip", codec.Encoding())
+		for range 2 {
+			var buf bytes.Buffer
+			w := codec.NewWriter(&buf)
+			_, err := io.WriteString(w, text)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			// double close is harmless
+			require.NoError(t, w.Close())
+			_, err = w.Write([]byte(text))
+			assert.ErrorIs(t, err, os.ErrClosed)
+
+			// compressed data is readable by standard gzip
+			gr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			res, err := io.ReadAll(gr)
+			require.NoError(t, err)
+			assert.Equal(t, text, string(res))
+
+			r, err := codec.NewReader(&buf)
+			require.NoError(t, err)
+			res, err = io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, text, string(res))
+			require.NoError(t, r.Close())
+			_, err = r.Read(make([]byte, 1))
+			assert.ErrorIs(t, err, os.ErrClosed)
+		}
+	}
+	assert.Same(t, http.GzipCodec(gzip.BestSpeed), http.GzipCodec(gzip.BestSpeed))
+	assert.Same(t, http.GzipCodec(gzip.DefaultCompression), http.GzipCodec(42))
+
+	_, err := http.GzipCodec(gzip.BestSpeed).NewReader(strings.NewReader("not gzip"))
+	assert.Error(t, err)
+}
+
+func TestGzipCodecConcurrent(t *testing.T) {
+	codec := http.GzipCodec(gzip.BestSpeed)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text := strings.Repeat("m v=1i\n", i+1)
+			var buf bytes.Buffer
+			w := codec.NewWriter(&buf)
+			_, _ = io.WriteString(w, text)
+			assert.NoError(t, w.Close())
+			r, err := codec.NewReader(&buf)
+			if assert.NoError(t, err) {
+				res, _ := io.ReadAll(r)
+				assert.Equal(t, text, string(res))
+				assert.NoError(t, r.Close())
+			}
+		}()
+	}
+	wg.Wait()
+}
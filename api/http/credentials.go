@@ -0,0 +1,139 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/internal/log"
+)
+
+// CredentialsProvider provides authorization of requests, e.g. a token read from a secret store, which rotates it.
+// Service caches the authorization until it expires or the server rejects it with 401 Unauthorized.
+// CredentialsProvider must be safe for concurrent use.
+type CredentialsProvider interface {
+	// Authorization returns value of the Authorization header, e.g. "Token my-token", and time when it expires.
+	// Zero expiration means the authorization is valid until the server rejects it.
+	Authorization(ctx context.Context) (authorization string, expires time.Time, err error)
+}
+
+// CredentialsProviderFunc is an adapter to use a function as CredentialsProvider
+type CredentialsProviderFunc func(ctx context.Context) (string, time.Time, error)
+
+// Authorization calls f(ctx)
+func (f CredentialsProviderFunc) Authorization(ctx context.Context) (string, time.Time, error) {
+	return f(ctx)
+}
+
+// authenticate sets authentication of a request
+type authenticate func(req *http.Request) *http.Request
+
+// authenticator authenticates requests and renews authentication rejected by the server
+type authenticator interface {
+	// current returns authentication and its generation
+	current(ctx context.Context) (authenticate, uint64, error)
+	// renew renews authentication, unless it was renewed since generation
+	renew(ctx context.Context, generation uint64) (authenticate, error)
+}
+
+// providedCredentials caches authorization of a CredentialsProvider
+type providedCredentials struct {
+	provider      CredentialsProvider
+	mu            sync.Mutex
+	authorization string
+	expires       time.Time
+	// generation is incremented with each refresh, so that concurrent requests failed with 401 refresh only once
+	generation uint64
+}
+
+// current returns cached authorization, it is refreshed if not cached or expired
+func (c *providedCredentials) current(ctx context.Context) (authenticate, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == 0 || (!c.expires.IsZero() && !time.Now().Before(c.expires)) {
+		if err := c.refresh(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+	return c.authenticate(), c.generation, nil
+}
+
+// renew refreshes authorization, unless it was refreshed since generation
+func (c *providedCredentials) renew(ctx context.Context, generation uint64) (authenticate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c.authenticate(), nil
+}
+
+// refresh gets authorization from the provider, c.mu must be locked
+func (c *providedCredentials) refresh(ctx context.Context) error {
+	authorization, expires, err := c.provider.Authorization(ctx)
+	if err != nil {
+		return err
+	}
+	c.authorization = authorization
+	c.expires = expires
+	c.generation++
+	return nil
+}
+
+// authenticate returns authentication by the cached authorization, c.mu must be locked
+func (c *providedCredentials) authenticate() authenticate {
+	authorization := c.authorization
+	return func(req *http.Request) *http.Request {
+		req.Header.Set("Authorization", authorization)
+		return req
+	}
+}
+
+// doAuthenticated sends req authenticated by a. When the server responds with 401 Unauthorized,
+// the authentication is renewed and req is sent once again, if its body can be read again.
+func (s *service) doAuthenticated(req *http.Request, a authenticator, requestCallback RequestCallback) (*http.Response, error) {
+	auth, generation, err := a.current(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(auth(req), "", requestCallback)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !replayable(req) {
+		return resp, err
+	}
+	log.Info("Authorization rejected, renewing it")
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if auth, err = a.renew(req.Context(), generation); err != nil {
+		return nil, err
+	}
+	if req, err = rewind(req); err != nil {
+		return nil, err
+	}
+	return s.do(auth(req), "", requestCallback)
+}
+
+// replayable returns true if req can be sent again, its body is empty or can be read again
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns copy of req with body to be sent again
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatingTokens is a credentials provider and a server accepting only the latest token
+type rotatingTokens struct {
+	mu      sync.Mutex
+	token   int
+	calls   atomic.Int32
+	expires time.Duration
+	bodies  []string
+}
+
+func (r *rotatingTokens) Authorization(context.Context) (string, time.Time, error) {
+	r.calls.Add(1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expires time.Time
+	if r.expires > 0 {
+		expires = time.Now().Add(r.expires)
+	}
+	return fmt.Sprintf("Token t%d", r.token), expires, nil
+}
+
+// rotate makes the current token invalid
+func (r *rotatingTokens) rotate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.token++
+}
+
+func (r *rotatingTokens) ServeHTTP(w nethttp.ResponseWriter, req *nethttp.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.Header.Get("Authorization") != fmt.Sprintf("Token t%d", r.token) {
+		w.WriteHeader(nethttp.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(body))
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	tokens := &rotatingTokens{}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+	srv := http.NewService(server.URL+"/", "Token ignored", http.DefaultOptions().SetCredentialsProvider(tokens))
+	ctx := context.Background()
+	post := func(body string) *http.Error {
+		return srv.DoPostRequest(ctx, server.URL+"/api/v2/write", strings.NewReader(body), nil, nil)
+	}
+
+	// authorization is cached
+	require.Nil(t, post("a"))
+	require.Nil(t, post("b"))
+	assert.EqualValues(t, 1, tokens.calls.Load())
+
+	// rejected authorization is refreshed and the request is sent again
+	tokens.rotate()
+	require.Nil(t, post("c"))
+	assert.EqualValues(t, 2, tokens.calls.Load())
+	assert.Equal(t, []string{"a", "b", "c"}, tokens.bodies)
+
+	// concurrent requests refresh once
+	tokens.rotate()
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, post(fmt.Sprint(i)))
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 3, tokens.calls.Load())
+	assert.Len(t, tokens.bodies, 8)
+
+	// the request is sent again only once
+	srv = http.NewService(server.URL+"/", "", http.DefaultOptions().SetCredentialsProvider(
+		http.CredentialsProviderFunc(func(context.Context) (string, time.Time, error) {
+			return "Token invalid", time.Time{}, nil
+		})))
+	perr := srv.DoPostRequest(ctx, server.URL+"/api/v2/write", strings.NewReader("d"), nil, nil)
+	require.NotNil(t, perr)
+	assert.Equal(t, nethttp.StatusUnauthorized, perr.StatusCode)
+}
+
+func TestCredentialsProviderExpiration(t *testing.T) {
+	tokens := &rotatingTokens{expires: 20 * time.Millisecond}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+	srv := http.NewService(server.URL+"/", "", http.DefaultOptions().SetCredentialsProvider(tokens))
+	ctx := context.Background()
+
+	require.Nil(t, srv.DoPostRequest(ctx, server.URL+"/api/v2/write", strings.NewReader("a"), nil, nil))
+	require.Nil(t, srv.DoPostRequest(ctx, server.URL+"/api/v2/write", strings.NewReader("b"), nil, nil))
+	assert.EqualValues(t, 1, tokens.calls.Load())
+	<-time.After(30 * time.Millisecond)
+	require.Nil(t, srv.DoPostRequest(ctx, server.URL+"/api/v2/write", strings.NewReader("c"), nil, nil))
+	assert.EqualValues(t, 2, tokens.calls.Load())
+}
+
+func TestCredentialsProviderError(t *testing.T) {
+	srv := http.NewService("http://localhost:8086/", "", http.DefaultOptions().SetCredentialsProvider(
+		http.CredentialsProviderFunc(func(context.Context) (string, time.Time, error) {
+			return "", time.Time{}, errors.New("vault unavailable")
+		})))
+	perr := srv.DoPostRequest(context.Background(), "http://localhost:8086/api/v2/write", strings.NewReader("a"), nil, nil)
+	require.NotNil(t, perr)
+	assert.EqualError(t, perr, "vault unavailable")
+}
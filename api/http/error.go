@@ -18,6 +18,8 @@ type Error struct {
 	Err        error
 	RetryAfter uint
 	Header     http.Header
+	// Body is the response body of a failed request, nil if it could not be read
+	Body []byte `json:"-"`
 }
 
 // Error fulfils error interface
@@ -0,0 +1,259 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/internal/log"
+)
+
+// FailoverService is Service sending requests to one of multiple servers, the first server URL is the primary.
+// When the active server fails with a connection error or a 5xx status code, the service switches to the first healthy server,
+// checked by the ping endpoint as Client.Ping does, and sends the request again to it, if its body can be read again.
+// After a failover, it checks in intervals whether a server preferred to the active one is healthy again and switches back to it.
+// ServerURL and ServerAPIURL return URLs of the active server, requests to URLs of any of the servers are sent to the active server.
+type FailoverService interface {
+	Service
+	// ServerURLs returns URLs of the servers in order of preference
+	ServerURLs() []string
+}
+
+// failoverService implements FailoverService
+type failoverService struct {
+	*service
+}
+
+// NewFailoverService creates instance of http Service sending requests to one of servers with serverURLs, in order of preference.
+// Health check interval and failover callback are set by httpOptions. It panics if serverURLs is empty.
+func NewFailoverService(serverURLs []string, authorization string, httpOptions *Options) FailoverService {
+	if len(serverURLs) == 0 {
+		panic("NewFailoverService called without server URLs")
+	}
+	s := NewService(serverURLs[0], authorization, httpOptions).(*service)
+	e := &endpoints{
+		client:        s.client,
+		userAgent:     s.userAgent,
+		checkInterval: time.Duration(httpOptions.HealthCheckInterval()) * time.Millisecond,
+		onFailover:    httpOptions.FailoverCallback(),
+	}
+	for _, u := range serverURLs {
+		ep := endpoint{url: u, apiURL: apiURLOf(u)}
+		if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+			ep.parsed = parsed
+		}
+		e.list = append(e.list, ep)
+	}
+	s.endpoints = e
+	return &failoverService{service: s}
+}
+
+// ServerURLs returns URLs of the servers in order of preference
+func (s *failoverService) ServerURLs() []string {
+	urls := make([]string, len(s.endpoints.list))
+	for i, e := range s.endpoints.list {
+		urls[i] = e.url
+	}
+	return urls
+}
+
+// endpoint is a server of FailoverService
+type endpoint struct {
+	url    string
+	apiURL string
+	// parsed url, nil if url is not a valid absolute URL
+	parsed *url.URL
+}
+
+// basePath returns path of the server URL without the trailing slash
+func (ep *endpoint) basePath() string {
+	return strings.TrimSuffix(ep.parsed.Path, "/")
+}
+
+// endpoints holds servers of FailoverService and index of the active server
+type endpoints struct {
+	list          []endpoint
+	client        Doer
+	userAgent     string
+	checkInterval time.Duration
+	onFailover    func(from, to string)
+	mu            sync.Mutex
+	active        int
+	// lastCheck is time of the last failover or check of preferred servers
+	lastCheck time.Time
+	checking  bool
+}
+
+// current returns index of the active server
+func (e *endpoints) current() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.active
+}
+
+// do sends req to the active server, it fails over to another server when the active server fails
+func (e *endpoints) do(req *http.Request) (*http.Response, error) {
+	from := e.indexOf(req.URL)
+	if from < 0 {
+		return e.client.Do(req)
+	}
+	active := e.current()
+	resp, err := e.client.Do(e.redirect(req, from, active))
+	if !failed(req, resp, err) {
+		if active > 0 {
+			e.checkPreferred()
+		}
+		return resp, err
+	}
+	next, ok := e.failover(req.Context(), active)
+	if !ok || !replayable(req) {
+		return resp, err
+	}
+	log.Warnf("Server %s failed, sending request to %s", e.list[active].url, e.list[next].url)
+	if resp != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	r, err := rewind(req)
+	if err != nil {
+		return nil, err
+	}
+	return e.client.Do(e.redirect(r, from, next))
+}
+
+// indexOf returns index of the server of u, or -1 if u is not URL of any of the servers.
+// Scheme and host of u must be equal to the server URL, and its path must be within the path of the server URL.
+func (e *endpoints) indexOf(u *url.URL) int {
+	for i := range e.list {
+		ep := &e.list[i]
+		if ep.parsed == nil || !strings.EqualFold(ep.parsed.Scheme, u.Scheme) || !strings.EqualFold(ep.parsed.Host, u.Host) {
+			continue
+		}
+		if base := ep.basePath(); u.Path == base || strings.HasPrefix(u.Path, base+"/") {
+			return i
+		}
+	}
+	return -1
+}
+
+// redirect returns req with URL of server from replaced by URL of server to
+func (e *endpoints) redirect(req *http.Request, from, to int) *http.Request {
+	src, dst := &e.list[from], &e.list[to]
+	if from == to || dst.parsed == nil {
+		return req
+	}
+	u := *req.URL
+	u.Scheme = dst.parsed.Scheme
+	u.Host = dst.parsed.Host
+	u.Path = dst.basePath() + strings.TrimPrefix(req.URL.Path, src.basePath())
+	u.RawPath = ""
+	r := req.Clone(req.Context())
+	r.URL = &u
+	r.Host = u.Host
+	return r
+}
+
+// failed returns true if the server failed to process req, by a connection error or a 5xx status code
+func failed(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode >= 500
+}
+
+// failover switches from the failed server to the first healthy server and returns its index.
+// If another request switched from the failed server already, the active server is returned without checks.
+// Servers are checked without holding the lock, a server found by another request meanwhile is preferred to the checked one.
+func (e *endpoints) failover(ctx context.Context, failed int) (int, bool) {
+	if active := e.current(); active != failed {
+		return active, true
+	}
+	for i := range e.list {
+		if i == failed || !e.healthy(ctx, i) {
+			continue
+		}
+		e.mu.Lock()
+		if e.active != failed {
+			active := e.active
+			e.mu.Unlock()
+			return active, true
+		}
+		e.active = i
+		e.lastCheck = time.Now()
+		e.mu.Unlock()
+		e.notify(failed, i)
+		return i, true
+	}
+	if active := e.current(); active != failed {
+		return active, true
+	}
+	return failed, false
+}
+
+// checkPreferred checks in background whether a server preferred to the active one is healthy, if the check interval elapsed
+func (e *endpoints) checkPreferred() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.checking || time.Since(e.lastCheck) < e.checkInterval {
+		return
+	}
+	e.checking = true
+	active := e.active
+	go func() {
+		for i := range active {
+			if e.healthy(context.Background(), i) {
+				e.switchBack(i)
+				break
+			}
+		}
+		e.mu.Lock()
+		e.checking = false
+		e.lastCheck = time.Now()
+		e.mu.Unlock()
+	}()
+}
+
+// switchBack switches to the preferred server i, unless the active server is preferred to it
+func (e *endpoints) switchBack(i int) {
+	e.mu.Lock()
+	from := e.active
+	if from <= i {
+		e.mu.Unlock()
+		return
+	}
+	e.active = i
+	e.mu.Unlock()
+	log.Infof("Server %s is healthy again, switching to it", e.list[i].url)
+	e.notify(from, i)
+}
+
+// notify calls the failover callback
+func (e *endpoints) notify(from, to int) {
+	if e.onFailover != nil {
+		e.onFailover(e.list[from].url, e.list[to].url)
+	}
+}
+
+// healthy returns true if server i responds to ping
+func (e *endpoints) healthy(ctx context.Context, i int) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.list[i].url+"ping", nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("User-Agent", e.userAgent)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return false
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
@@ -0,0 +1,187 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingServer is a test server, which responds with 503 when it is down
+type failingServer struct {
+	*httptest.Server
+	down   atomic.Bool
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newFailingServer(t *testing.T) *failingServer {
+	s := &failingServer{}
+	s.Server = httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if s.down.Load() {
+			w.WriteHeader(nethttp.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/ping" {
+			body, _ := io.ReadAll(r.Body)
+			s.mu.Lock()
+			s.bodies = append(s.bodies, string(body))
+			s.mu.Unlock()
+		}
+		w.WriteHeader(nethttp.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *failingServer) Bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bodies
+}
+
+// failovers records calls of the failover callback
+type failovers struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *failovers) callback(from, to string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, from+" -> "+to)
+}
+
+func (f *failovers) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestFailoverService(t *testing.T) {
+	primary := newFailingServer(t)
+	closed := newFailingServer(t)
+	closed.Close()
+	secondary := newFailingServer(t)
+	urls := []string{primary.URL + "/", closed.URL + "/", secondary.URL + "/"}
+	var fo failovers
+	srv := http.NewFailoverService(urls, "Token my-token",
+		http.DefaultOptions().SetHealthCheckInterval(10).SetFailoverCallback(fo.callback))
+	assert.Equal(t, urls, srv.ServerURLs())
+	assert.Equal(t, urls[0], srv.ServerURL())
+	assert.Equal(t, primary.URL+"/api/v2/", srv.ServerAPIURL())
+	ctx := context.Background()
+	writeURL := srv.ServerAPIURL() + "write"
+	post := func(body string) *http.Error {
+		return srv.DoPostRequest(ctx, writeURL, strings.NewReader(body), nil, nil)
+	}
+
+	require.Nil(t, post("a"))
+	assert.Equal(t, []string{"a"}, primary.Bodies())
+
+	// fails over to the first healthy server and sends the request again
+	primary.down.Store(true)
+	require.Nil(t, post("b"))
+	assert.Equal(t, []string{"b"}, secondary.Bodies())
+	assert.Equal(t, urls[2], srv.ServerURL())
+	assert.Equal(t, secondary.URL+"/api/v2/", srv.ServerAPIURL())
+	assert.Equal(t, []string{urls[0] + " -> " + urls[2]}, fo.Calls())
+
+	// requests to URL of any server are sent to the active server
+	require.Nil(t, post("c"))
+	require.Nil(t, srv.DoPostRequest(ctx, srv.ServerAPIURL()+"write", strings.NewReader("d"), nil, nil))
+	assert.Equal(t, []string{"b", "c", "d"}, secondary.Bodies())
+
+	// switches back to the primary server, when it is healthy again
+	primary.down.Store(false)
+	assert.Eventually(t, func() bool {
+		return post("e") == nil && srv.ServerURL() == urls[0]
+	}, time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{urls[0] + " -> " + urls[2], urls[2] + " -> " + urls[0]}, fo.Calls())
+
+	// without a healthy server, the error is returned
+	primary.down.Store(true)
+	secondary.down.Store(true)
+	perr := post("f")
+	require.NotNil(t, perr)
+	assert.Equal(t, nethttp.StatusServiceUnavailable, perr.StatusCode)
+	assert.Equal(t, urls[0], srv.ServerURL())
+	assert.Len(t, fo.Calls(), 2)
+}
+
+func TestFailoverServiceNotReplayable(t *testing.T) {
+	primary := newFailingServer(t)
+	secondary := newFailingServer(t)
+	urls := []string{primary.URL + "/", secondary.URL + "/"}
+	srv := http.NewFailoverService(urls, "", http.DefaultOptions())
+	ctx := context.Background()
+
+	// request with a body, which cannot be read again, is not sent again, next requests are sent to the healthy server
+	primary.down.Store(true)
+	perr := srv.DoPostRequest(ctx, urls[0]+"api/v2/write", io.MultiReader(strings.NewReader("a")), nil, nil)
+	require.NotNil(t, perr)
+	assert.Equal(t, nethttp.StatusServiceUnavailable, perr.StatusCode)
+	assert.Equal(t, urls[1], srv.ServerURL())
+	require.Nil(t, srv.DoPostRequest(ctx, urls[0]+"api/v2/write", strings.NewReader("b"), nil, nil))
+	assert.Equal(t, []string{"b"}, secondary.Bodies())
+
+	// requests to other URLs are not redirected
+	other := newFailingServer(t)
+	require.Nil(t, srv.DoPostRequest(ctx, other.URL+"/api/v2/write", strings.NewReader("c"), nil, nil))
+	assert.Equal(t, []string{"c"}, other.Bodies())
+}
+
+func TestFailoverServiceConcurrentChecks(t *testing.T) {
+	primary := newFailingServer(t)
+	release := make(chan struct{})
+	secondary := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path == "/ping" {
+			<-release
+		}
+		w.WriteHeader(nethttp.StatusNoContent)
+	}))
+	t.Cleanup(secondary.Close)
+	urls := []string{primary.URL + "/", secondary.URL + "/"}
+	var fo failovers
+	srv := http.NewFailoverService(urls, "", http.DefaultOptions().SetFailoverCallback(fo.callback))
+	ctx := context.Background()
+
+	primary.down.Store(true)
+	var wg sync.WaitGroup
+	for _, body := range []string{"a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, srv.DoPostRequest(ctx, urls[0]+"api/v2/write", strings.NewReader(body), nil, nil))
+		}()
+	}
+	// the active server is available while servers are checked
+	<-time.After(50 * time.Millisecond)
+	done := make(chan string)
+	go func() {
+		done <- srv.ServerURL()
+	}()
+	select {
+	case u := <-done:
+		assert.Equal(t, urls[0], u)
+	case <-time.After(time.Second):
+		assert.Fail(t, "health check blocks the active server")
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, urls[1], srv.ServerURL())
+	assert.Equal(t, []string{urls[0] + " -> " + urls[1]}, fo.Calls())
+}
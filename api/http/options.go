@@ -5,6 +5,7 @@
 package http
 
 import (
+	"compress/gzip"
 	"crypto/tls"
 	"net"
 	"net/http"
@@ -25,6 +26,14 @@ type Options struct {
 	httpRequestTimeout uint
 	// Application name in the User-Agent HTTP header string
 	appName string
+	// Codecs accepted for compressed query responses. Default gzip.
+	queryCompression []Codec
+	// Provider of authorization of requests. Default nil.
+	credentialsProvider CredentialsProvider
+	// Interval in ms of checking whether a preferred server is healthy again after a failover. Default 30s.
+	healthCheckInterval uint
+	// Callback called when FailoverService switches servers. Default nil.
+	failoverCallback func(from, to string)
 }
 
 // HTTPClient returns the http.Client that is configured to be used
@@ -133,7 +142,56 @@ func (o *Options) SetApplicationName(appName string) *Options {
 	return o
 }
 
+// QueryCompression returns codecs accepted for compressed query responses
+func (o *Options) QueryCompression() []Codec {
+	return o.queryCompression
+}
+
+// SetQueryCompression sets codecs accepted for compressed query responses, in order of preference.
+// Without codecs, query responses are requested uncompressed.
+func (o *Options) SetQueryCompression(codecs ...Codec) *Options {
+	o.queryCompression = codecs
+	return o
+}
+
+// CredentialsProvider returns provider of authorization of requests
+func (o *Options) CredentialsProvider() CredentialsProvider {
+	return o.credentialsProvider
+}
+
+// SetCredentialsProvider sets provider of authorization of requests, which is asked for the authorization before requests,
+// instead of using the authorization token. The authorization is cached until it expires,
+// when the server responds with 401 Unauthorized, it is refreshed and the request is sent once again.
+func (o *Options) SetCredentialsProvider(provider CredentialsProvider) *Options {
+	o.credentialsProvider = provider
+	return o
+}
+
+// HealthCheckInterval returns interval in ms of checking whether a preferred server is healthy again after a failover
+func (o *Options) HealthCheckInterval() uint {
+	return o.healthCheckInterval
+}
+
+// SetHealthCheckInterval sets interval in ms of checking whether a server preferred to the active server
+// of FailoverService is healthy again after a failover
+func (o *Options) SetHealthCheckInterval(healthCheckIntervalMs uint) *Options {
+	o.healthCheckInterval = healthCheckIntervalMs
+	return o
+}
+
+// FailoverCallback returns callback called when FailoverService switches servers
+func (o *Options) FailoverCallback() func(from, to string) {
+	return o.failoverCallback
+}
+
+// SetFailoverCallback sets callback called with server URLs when FailoverService switches from a failed server to a healthy server,
+// or back to a preferred server. The callback must not block.
+func (o *Options) SetFailoverCallback(callback func(from, to string)) *Options {
+	o.failoverCallback = callback
+	return o
+}
+
 // DefaultOptions returns Options object with default values
 func DefaultOptions() *Options {
-	return &Options{httpRequestTimeout: 20}
+	return &Options{httpRequestTimeout: 20, queryCompression: []Codec{GzipCodec(gzip.DefaultCompression)}, healthCheckInterval: 30_000}
 }
@@ -5,6 +5,7 @@
 package http_test
 
 import (
+	"context"
 	"crypto/tls"
 	"github.com/stretchr/testify/require"
 	nethttp "net/http"
@@ -25,6 +26,11 @@ func TestDefaultOptions(t *testing.T) {
 	require.True(t, ok)
 	assert.NotNil(t, transport.Proxy)
 	assert.EqualValues(t, "", opts.ApplicationName())
+	require.Len(t, opts.QueryCompression(), 1)
+	assert.Equal(t, "gzip", opts.QueryCompression()[0].Encoding())
+	assert.Nil(t, opts.CredentialsProvider())
+	assert.EqualValues(t, 30_000, opts.HealthCheckInterval())
+	assert.Nil(t, opts.FailoverCallback())
 }
 
 func TestOptionsSetting(t *testing.T) {
@@ -34,8 +40,18 @@ func TestOptionsSetting(t *testing.T) {
 	opts := http.DefaultOptions().
 		SetTLSConfig(tlsConfig).
 		SetHTTPRequestTimeout(50).
-		SetApplicationName("Monitor/1.1")
+		SetApplicationName("Monitor/1.1").
+		SetQueryCompression().
+		SetCredentialsProvider(http.CredentialsProviderFunc(func(context.Context) (string, time.Time, error) {
+			return "Token my-token", time.Time{}, nil
+		})).
+		SetHealthCheckInterval(5_000).
+		SetFailoverCallback(func(from, to string) {})
 	assert.Equal(t, tlsConfig, opts.TLSConfig())
+	assert.EqualValues(t, 5_000, opts.HealthCheckInterval())
+	assert.NotNil(t, opts.FailoverCallback())
+	assert.Empty(t, opts.QueryCompression())
+	assert.NotNil(t, opts.CredentialsProvider())
 	assert.Equal(t, uint(50), opts.HTTPRequestTimeout())
 	assert.EqualValues(t, "Monitor/1.1", opts.ApplicationName())
 	if client := opts.HTTPClient(); assert.NotNil(t, client) {
@@ -14,6 +14,7 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
@@ -57,32 +58,50 @@ type service struct {
 	authorization string
 	client        Doer
 	userAgent     string
+	// authenticator of requests, the session or credentials of a CredentialsProvider, nil if not used
+	authenticator authenticator
+	// endpoints of FailoverService, nil if there is a single server URL
+	endpoints *endpoints
 }
 
 // NewService creates instance of http Service with given parameters
 func NewService(serverURL, authorization string, httpOptions *Options) Service {
+	s := &service{
+		serverAPIURL:  apiURLOf(serverURL),
+		serverURL:     serverURL,
+		authorization: authorization,
+		client:        httpOptions.HTTPDoer(),
+		userAgent:     http2.FormatUserAgent(httpOptions.ApplicationName()),
+	}
+	if provider := httpOptions.CredentialsProvider(); provider != nil {
+		s.authenticator = &providedCredentials{provider: provider}
+	}
+	return s
+}
+
+// apiURLOf returns URL of the API space of server with serverURL
+func apiURLOf(serverURL string) string {
 	apiURL, err := url.Parse(serverURL)
-	serverAPIURL := serverURL
 	if err == nil {
 		apiURL, err = apiURL.Parse("api/v2/")
 		if err == nil {
-			serverAPIURL = apiURL.String()
+			return apiURL.String()
 		}
 	}
-	return &service{
-		serverAPIURL:  serverAPIURL,
-		serverURL:     serverURL,
-		authorization: authorization,
-		client:        httpOptions.HTTPDoer(),
-		userAgent:     http2.FormatUserAgent(httpOptions.ApplicationName()),
-	}
+	return serverURL
 }
 
 func (s *service) ServerAPIURL() string {
+	if s.endpoints != nil {
+		return s.endpoints.list[s.endpoints.current()].apiURL
+	}
 	return s.serverAPIURL
 }
 
 func (s *service) ServerURL() string {
+	if s.endpoints != nil {
+		return s.endpoints.list[s.endpoints.current()].url
+	}
 	return s.serverURL
 }
 
@@ -109,6 +128,10 @@ func (s *service) doHTTPRequestWithURL(ctx context.Context, method, url string,
 func (s *service) DoHTTPRequest(req *http.Request, requestCallback RequestCallback, responseCallback ResponseCallback) *Error {
 	resp, err := s.DoHTTPRequestWithResponse(req, requestCallback)
 	if err != nil {
+		// failed sign in of a session is already Error
+		if perror, ok := err.(*Error); ok {
+			return perror
+		}
 		return NewError(err)
 	}
 
@@ -125,9 +148,17 @@ func (s *service) DoHTTPRequest(req *http.Request, requestCallback RequestCallba
 }
 
 func (s *service) DoHTTPRequestWithResponse(req *http.Request, requestCallback RequestCallback) (*http.Response, error) {
+	if s.authenticator != nil {
+		return s.doAuthenticated(req, s.authenticator, requestCallback)
+	}
+	return s.do(req, s.authorization, requestCallback)
+}
+
+// do sends req with the authorization, if not empty, and user agent headers
+func (s *service) do(req *http.Request, authorization string, requestCallback RequestCallback) (*http.Response, error) {
 	log.Infof("HTTP %s req to %s", req.Method, req.URL.String())
-	if len(s.authorization) > 0 {
-		req.Header.Set("Authorization", s.authorization)
+	if len(authorization) > 0 {
+		req.Header.Set("Authorization", authorization)
 	}
 	if req.Header.Get("User-Agent") == "" {
 		req.Header.Set("User-Agent", s.userAgent)
@@ -135,6 +166,9 @@ func (s *service) DoHTTPRequestWithResponse(req *http.Request, requestCallback R
 	if requestCallback != nil {
 		requestCallback(req)
 	}
+	if s.endpoints != nil {
+		return s.endpoints.do(req)
+	}
 	return s.client.Do(req)
 }
 
@@ -160,17 +194,18 @@ func (s *service) parseHTTPError(r *http.Response) *Error {
 		}
 	}
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		perror.Err = err
+		return perror
+	}
+	perror.Body = body
+
 	// json encoded error
 	ctype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if ctype == "application/json" {
-		perror.Err = json.NewDecoder(r.Body).Decode(perror)
+		perror.Err = json.NewDecoder(bytes.NewReader(body)).Decode(perror)
 	} else {
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			perror.Err = err
-			return perror
-		}
-
 		perror.Code = r.Status
 		perror.Message = string(body)
 	}
@@ -5,9 +5,15 @@
 package http
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestService(t *testing.T) {
@@ -16,3 +22,50 @@ func TestService(t *testing.T) {
 	assert.Equal(t, "http://localhost:8086/aa/api/v2/", srv.ServerAPIURL())
 	assert.Equal(t, "Token my-token", srv.Authorization())
 }
+
+func TestServiceErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"invalid","message":"bad","line":2}`))
+	}))
+	defer server.Close()
+	srv := NewService(server.URL, "Token my-token", DefaultOptions())
+	err := srv.DoPostRequest(context.Background(), server.URL, strings.NewReader("a"), nil, nil)
+	require.NotNil(t, err)
+	assert.Equal(t, "invalid: bad", err.Error())
+	assert.Equal(t, `{"code":"invalid","message":"bad","line":2}`, string(err.Body))
+}
+
+func TestFailoverEndpoints(t *testing.T) {
+	e := NewFailoverService([]string{"http://host:8086", "https://Other.example.com/influx/", "not a url"}, "", DefaultOptions()).(*failoverService).endpoints
+	for u, index := range map[string]int{
+		"http://host:8086/api/v2/write":              0,
+		"http://HOST:8086/ping":                      0,
+		"http://host:80861/api/v2/write":             -1,
+		"https://host:8086/api/v2/write":             -1,
+		"http://host/api/v2/write":                   -1,
+		"https://other.example.com/influx/api/v2/x":  1,
+		"https://other.example.com/influx":           1,
+		"https://other.example.com/influxdb/api/v2/": -1,
+		"https://other.example.com/api/v2/write":     -1,
+	} {
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		assert.Equal(t, index, e.indexOf(parsed), u)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://host:8086/api/v2/write?org=my%20org", nil)
+	require.NoError(t, err)
+	r := e.redirect(req, 0, 1)
+	assert.Equal(t, "https://Other.example.com/influx/api/v2/write?org=my%20org", r.URL.String())
+	assert.Equal(t, "Other.example.com", r.Host)
+	r = e.redirect(r, 1, 0)
+	assert.Equal(t, "http://host:8086/api/v2/write?org=my%20org", r.URL.String())
+	// invalid URL is never redirected to
+	assert.Same(t, req, e.redirect(req, 0, 2))
+
+	assert.PanicsWithValue(t, "NewFailoverService called without server URLs", func() {
+		NewFailoverService(nil, "", DefaultOptions())
+	})
+}
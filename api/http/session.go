@@ -0,0 +1,151 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// SessionService is Service, which authenticates requests with a session cookie obtained by signing in with username and password.
+// It signs in before the first request and signs in again when the server responds with 401 Unauthorized,
+// the request is then sent again, if its body can be read again.
+type SessionService interface {
+	Service
+	// SignIn signs in with username and password, the received session cookie authenticates subsequent requests
+	SignIn(ctx context.Context) error
+	// SignOut ends the session, the next request signs in again
+	SignOut(ctx context.Context) error
+}
+
+// session holds the session cookie of a signed-in user
+type session struct {
+	service  *service
+	username string
+	password string
+	mu       sync.Mutex
+	cookies  []*http.Cookie
+	// generation is incremented with each sign-in, so that concurrent requests failed with 401 sign in only once
+	generation uint64
+}
+
+// sessionService implements SessionService
+type sessionService struct {
+	*service
+	session *session
+}
+
+// NewSessionService creates instance of http Service authenticated by a session of the user with username and password
+func NewSessionService(serverURL, username, password string, httpOptions *Options) SessionService {
+	s := NewService(serverURL, "", httpOptions).(*service)
+	sess := &session{service: s, username: username, password: password}
+	s.authenticator = sess
+	return &sessionService{service: s, session: sess}
+}
+
+// SignIn signs in with username and password
+func (s *sessionService) SignIn(ctx context.Context) error {
+	s.session.mu.Lock()
+	defer s.session.mu.Unlock()
+	return s.session.signIn(ctx)
+}
+
+// SignOut ends the session
+func (s *sessionService) SignOut(ctx context.Context) error {
+	return s.session.signOut(ctx)
+}
+
+// current returns session cookies and their generation, it signs in if there is no session
+func (s *session) current(ctx context.Context) (authenticate, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cookies == nil {
+		if err := s.signIn(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+	return s.authenticate(), s.generation, nil
+}
+
+// renew signs in again, unless the session was renewed since generation
+func (s *session) renew(ctx context.Context, generation uint64) (authenticate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation == generation || s.cookies == nil {
+		if err := s.signIn(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.authenticate(), nil
+}
+
+// authenticate returns authentication by the session cookies, s.mu must be locked
+func (s *session) authenticate() authenticate {
+	cookies := s.cookies
+	return func(req *http.Request) *http.Request {
+		return withCookies(req, cookies)
+	}
+}
+
+// signIn signs in and stores the session cookies, s.mu must be locked
+func (s *session) signIn(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.service.serverAPIURL+"signin", nil)
+	if err != nil {
+		return err
+	}
+	creds := base64.StdEncoding.EncodeToString([]byte(s.username + ":" + s.password))
+	resp, err := s.service.do(req, "", func(req *http.Request) {
+		req.Header.Set("Authorization", "Basic "+creds)
+	})
+	if err != nil {
+		return err
+	}
+	if perror := s.service.parseHTTPError(resp); perror != nil {
+		return perror
+	}
+	_ = resp.Body.Close()
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return errors.New("sign in response has no session cookie")
+	}
+	s.cookies = cookies
+	s.generation++
+	return nil
+}
+
+// signOut ends the session, if any
+func (s *session) signOut(ctx context.Context) error {
+	s.mu.Lock()
+	cookies := s.cookies
+	s.cookies = nil
+	s.mu.Unlock()
+	if cookies == nil {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.service.serverAPIURL+"signout", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.service.do(withCookies(req, cookies), "", nil)
+	if err != nil {
+		return err
+	}
+	if perror := s.service.parseHTTPError(resp); perror != nil {
+		return perror
+	}
+	return resp.Body.Close()
+}
+
+// withCookies returns req with cookies replacing cookies set by a previous attempt
+func withCookies(req *http.Request, cookies []*http.Cookie) *http.Request {
+	req.Header.Del("Cookie")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
@@ -0,0 +1,148 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionServer is a test server with session authentication
+type sessionServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	session  string
+	signIns  int
+	signOuts int
+	bodies   []string
+}
+
+func newSessionServer(t *testing.T) *sessionServer {
+	s := &sessionServer{}
+	s.Server = httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v2/signin":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "my-user" || pass != "my-password" {
+				w.WriteHeader(nethttp.StatusUnauthorized)
+				return
+			}
+			s.signIns++
+			s.session = fmt.Sprintf("s%d", s.signIns)
+			nethttp.SetCookie(w, &nethttp.Cookie{Name: "influxdb-oss-session", Value: s.session, Path: "/api/"})
+			w.WriteHeader(nethttp.StatusNoContent)
+		case "/api/v2/signout":
+			s.signOuts++
+			s.session = ""
+			w.WriteHeader(nethttp.StatusNoContent)
+		default:
+			c, err := r.Cookie("influxdb-oss-session")
+			if err != nil || c.Value != s.session {
+				w.WriteHeader(nethttp.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			s.bodies = append(s.bodies, string(body))
+			w.WriteHeader(nethttp.StatusNoContent)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// expire ends the current session on the server
+func (s *sessionServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = "expired"
+}
+
+func (s *sessionServer) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signIns, s.signOuts
+}
+
+func TestSessionService(t *testing.T) {
+	server := newSessionServer(t)
+	srv := http.NewSessionService(server.URL+"/", "my-user", "my-password", http.DefaultOptions())
+	ctx := context.Background()
+	post := func(body string) *http.Error {
+		return srv.DoPostRequest(ctx, server.URL+"/api/v2/write", strings.NewReader(body), nil, nil)
+	}
+
+	// signs in before the first request
+	require.Nil(t, post("a"))
+	require.Nil(t, post("b"))
+	signIns, _ := server.counts()
+	assert.Equal(t, 1, signIns)
+
+	// signs in again and replays the request when the session expires
+	server.expire()
+	require.Nil(t, post("c"))
+	signIns, _ = server.counts()
+	assert.Equal(t, 2, signIns)
+	assert.Equal(t, []string{"a", "b", "c"}, server.bodies)
+
+	// concurrent requests sign in once
+	server.expire()
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, post(fmt.Sprint(i)))
+		}()
+	}
+	wg.Wait()
+	signIns, _ = server.counts()
+	assert.Equal(t, 3, signIns)
+	assert.Len(t, server.bodies, 8)
+
+	// request with a body, which cannot be read again, is not replayed
+	server.expire()
+	perr := srv.DoPostRequest(ctx, server.URL+"/api/v2/write", io.MultiReader(strings.NewReader("d")), nil, nil)
+	require.NotNil(t, perr)
+	assert.Equal(t, nethttp.StatusUnauthorized, perr.StatusCode)
+
+	// sign out ends the session, the next request signs in again
+	require.NoError(t, srv.SignIn(ctx))
+	require.NoError(t, srv.SignOut(ctx))
+	require.NoError(t, srv.SignOut(ctx))
+	signIns, signOuts := server.counts()
+	assert.Equal(t, 4, signIns)
+	assert.Equal(t, 1, signOuts)
+	require.Nil(t, post("e"))
+	signIns, _ = server.counts()
+	assert.Equal(t, 5, signIns)
+
+	// service authenticated by a token has no session
+	_, ok := http.NewService(server.URL+"/", "Token my-token", http.DefaultOptions()).(http.SessionService)
+	assert.False(t, ok)
+}
+
+func TestSessionServiceInvalidCredentials(t *testing.T) {
+	server := newSessionServer(t)
+	srv := http.NewSessionService(server.URL+"/", "my-user", "wrong", http.DefaultOptions())
+	perr := srv.DoPostRequest(context.Background(), server.URL+"/api/v2/write", strings.NewReader("a"), nil, nil)
+	require.NotNil(t, perr)
+	assert.Equal(t, nethttp.StatusUnauthorized, perr.StatusCode)
+	assert.Empty(t, server.bodies)
+
+	var herr *http.Error
+	require.ErrorAs(t, srv.SignIn(context.Background()), &herr)
+	assert.Equal(t, nethttp.StatusUnauthorized, herr.StatusCode)
+}
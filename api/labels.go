@@ -6,7 +6,9 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"iter"
 
 	"github.com/influxdata/influxdb-client-go/v2/domain"
 )
@@ -15,6 +17,9 @@ import (
 type LabelsAPI interface {
 	// GetLabels returns all labels.
 	GetLabels(ctx context.Context) (*[]domain.Label, error)
+	// IterateLabels returns an iterator over all labels.
+	// Labels are not paged by the server, they are fetched by a single request when the iteration starts.
+	IterateLabels(ctx context.Context) iter.Seq2[domain.Label, error]
 	// FindLabelsByOrg returns labels belonging to organization org.
 	FindLabelsByOrg(ctx context.Context, org *domain.Organization) (*[]domain.Label, error)
 	// FindLabelsByOrgID returns labels belonging to organization with id orgID.
@@ -38,6 +43,21 @@ type LabelsAPI interface {
 	DeleteLabelWithID(ctx context.Context, labelID string) error
 	// DeleteLabel deletes a label.
 	DeleteLabel(ctx context.Context, label *domain.Label) error
+	// FindResourceLabels returns labels attached to a resource of resourceType with resourceID.
+	// Supported resource types are buckets, dashboards, scrapers, tasks, telegrafs and variables.
+	FindResourceLabels(ctx context.Context, resourceType domain.ResourceType, resourceID string) (*[]domain.Label, error)
+	// AddResourceLabel attaches a label with labelID to a resource of resourceType with resourceID.
+	// Supported resource types are buckets, dashboards, scrapers, tasks, telegrafs and variables.
+	AddResourceLabel(ctx context.Context, resourceType domain.ResourceType, resourceID, labelID string) (*domain.Label, error)
+	// RemoveResourceLabel removes a label with labelID from a resource of resourceType with resourceID.
+	// Supported resource types are buckets, dashboards, scrapers, tasks, telegrafs and variables.
+	RemoveResourceLabel(ctx context.Context, resourceType domain.ResourceType, resourceID, labelID string) error
+	// FindResourcesByLabel returns all resources of the label's organization the label is attached to.
+	// Buckets, dashboards, scrapers, tasks, telegrafs and variables are searched.
+	FindResourcesByLabel(ctx context.Context, label *domain.Label) (*[]domain.Resource, error)
+	// FindResourcesByLabelWithID returns all resources the label with labelID is attached to.
+	// Buckets, dashboards, scrapers, tasks, telegrafs and variables are searched.
+	FindResourcesByLabelWithID(ctx context.Context, labelID string) (*[]domain.Resource, error)
 }
 
 // labelsAPI implements LabelsAPI
@@ -57,6 +77,12 @@ func (u *labelsAPI) GetLabels(ctx context.Context) (*[]domain.Label, error) {
 	return u.getLabels(ctx, params)
 }
 
+func (u *labelsAPI) IterateLabels(ctx context.Context) iter.Seq2[domain.Label, error] {
+	return iterateSlice(func() (*[]domain.Label, error) {
+		return u.GetLabels(ctx)
+	})
+}
+
 func (u *labelsAPI) getLabels(ctx context.Context, params *domain.GetLabelsParams) (*[]domain.Label, error) {
 	response, err := u.apiClient.GetLabels(ctx, params)
 	if err != nil {
@@ -153,3 +179,287 @@ func (u *labelsAPI) DeleteLabelWithID(ctx context.Context, labelID string) error
 	}
 	return u.apiClient.DeleteLabelsID(ctx, params)
 }
+
+func (u *labelsAPI) FindResourceLabels(ctx context.Context, resourceType domain.ResourceType, resourceID string) (*[]domain.Label, error) {
+	var response *domain.LabelsResponse
+	var err error
+	switch resourceType {
+	case domain.ResourceTypeBuckets:
+		response, err = u.apiClient.GetBucketsIDLabels(ctx, &domain.GetBucketsIDLabelsAllParams{BucketID: resourceID})
+	case domain.ResourceTypeDashboards:
+		response, err = u.apiClient.GetDashboardsIDLabels(ctx, &domain.GetDashboardsIDLabelsAllParams{DashboardID: resourceID})
+	case domain.ResourceTypeScrapers:
+		response, err = u.apiClient.GetScrapersIDLabels(ctx, &domain.GetScrapersIDLabelsAllParams{ScraperTargetID: resourceID})
+	case domain.ResourceTypeTasks:
+		response, err = u.apiClient.GetTasksIDLabels(ctx, &domain.GetTasksIDLabelsAllParams{TaskID: resourceID})
+	case domain.ResourceTypeTelegrafs:
+		response, err = u.apiClient.GetTelegrafsIDLabels(ctx, &domain.GetTelegrafsIDLabelsAllParams{TelegrafID: resourceID})
+	case domain.ResourceTypeVariables:
+		response, err = u.apiClient.GetVariablesIDLabels(ctx, &domain.GetVariablesIDLabelsAllParams{VariableID: resourceID})
+	default:
+		return nil, unsupportedLabelResourceError(resourceType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return (*[]domain.Label)(response.Labels), nil
+}
+
+func (u *labelsAPI) AddResourceLabel(ctx context.Context, resourceType domain.ResourceType, resourceID, labelID string) (*domain.Label, error) {
+	var response *domain.LabelResponse
+	var err error
+	switch resourceType {
+	case domain.ResourceTypeBuckets:
+		response, err = u.apiClient.PostBucketsIDLabels(ctx, &domain.PostBucketsIDLabelsAllParams{
+			BucketID: resourceID,
+			Body:     domain.PostBucketsIDLabelsJSONRequestBody{LabelID: &labelID},
+		})
+	case domain.ResourceTypeDashboards:
+		response, err = u.apiClient.PostDashboardsIDLabels(ctx, &domain.PostDashboardsIDLabelsAllParams{
+			DashboardID: resourceID,
+			Body:        domain.PostDashboardsIDLabelsJSONRequestBody{LabelID: &labelID},
+		})
+	case domain.ResourceTypeScrapers:
+		response, err = u.apiClient.PostScrapersIDLabels(ctx, &domain.PostScrapersIDLabelsAllParams{
+			ScraperTargetID: resourceID,
+			Body:            domain.PostScrapersIDLabelsJSONRequestBody{LabelID: &labelID},
+		})
+	case domain.ResourceTypeTasks:
+		response, err = u.apiClient.PostTasksIDLabels(ctx, &domain.PostTasksIDLabelsAllParams{
+			TaskID: resourceID,
+			Body:   domain.PostTasksIDLabelsJSONRequestBody{LabelID: &labelID},
+		})
+	case domain.ResourceTypeTelegrafs:
+		response, err = u.apiClient.PostTelegrafsIDLabels(ctx, &domain.PostTelegrafsIDLabelsAllParams{
+			TelegrafID: resourceID,
+			Body:       domain.PostTelegrafsIDLabelsJSONRequestBody{LabelID: &labelID},
+		})
+	case domain.ResourceTypeVariables:
+		response, err = u.apiClient.PostVariablesIDLabels(ctx, &domain.PostVariablesIDLabelsAllParams{
+			VariableID: resourceID,
+			Body:       domain.PostVariablesIDLabelsJSONRequestBody{LabelID: &labelID},
+		})
+	default:
+		return nil, unsupportedLabelResourceError(resourceType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.Label, nil
+}
+
+func (u *labelsAPI) RemoveResourceLabel(ctx context.Context, resourceType domain.ResourceType, resourceID, labelID string) error {
+	switch resourceType {
+	case domain.ResourceTypeBuckets:
+		return u.apiClient.DeleteBucketsIDLabelsID(ctx, &domain.DeleteBucketsIDLabelsIDAllParams{BucketID: resourceID, LabelID: labelID})
+	case domain.ResourceTypeDashboards:
+		return u.apiClient.DeleteDashboardsIDLabelsID(ctx, &domain.DeleteDashboardsIDLabelsIDAllParams{DashboardID: resourceID, LabelID: labelID})
+	case domain.ResourceTypeScrapers:
+		return u.apiClient.DeleteScrapersIDLabelsID(ctx, &domain.DeleteScrapersIDLabelsIDAllParams{ScraperTargetID: resourceID, LabelID: labelID})
+	case domain.ResourceTypeTasks:
+		return u.apiClient.DeleteTasksIDLabelsID(ctx, &domain.DeleteTasksIDLabelsIDAllParams{TaskID: resourceID, LabelID: labelID})
+	case domain.ResourceTypeTelegrafs:
+		return u.apiClient.DeleteTelegrafsIDLabelsID(ctx, &domain.DeleteTelegrafsIDLabelsIDAllParams{TelegrafID: resourceID, LabelID: labelID})
+	case domain.ResourceTypeVariables:
+		return u.apiClient.DeleteVariablesIDLabelsID(ctx, &domain.DeleteVariablesIDLabelsIDAllParams{VariableID: resourceID, LabelID: labelID})
+	default:
+		return unsupportedLabelResourceError(resourceType)
+	}
+}
+
+func (u *labelsAPI) FindResourcesByLabelWithID(ctx context.Context, labelID string) (*[]domain.Resource, error) {
+	label, err := u.FindLabelByID(ctx, labelID)
+	if err != nil {
+		return nil, err
+	}
+	return u.FindResourcesByLabel(ctx, label)
+}
+
+func (u *labelsAPI) FindResourcesByLabel(ctx context.Context, label *domain.Label) (*[]domain.Resource, error) {
+	if label.Id == nil {
+		return nil, errors.New("label has no ID")
+	}
+	if label.OrgID == nil {
+		return nil, fmt.Errorf("label '%s' has no organization", *label.Id)
+	}
+	finders := []func(ctx context.Context, orgID, labelID string) ([]domain.Resource, error){
+		u.findLabeledBuckets,
+		u.findLabeledDashboards,
+		u.findLabeledScrapers,
+		u.findLabeledTasks,
+		u.findLabeledTelegrafs,
+		u.findLabeledVariables,
+	}
+	resources := make([]domain.Resource, 0)
+	for _, find := range finders {
+		found, err := find(ctx, *label.OrgID, *label.Id)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, found...)
+	}
+	return &resources, nil
+}
+
+// labelsPageSize is the number of resources requested in a single page when searching for labeled resources
+const labelsPageSize = 100
+
+func (u *labelsAPI) findLabeledBuckets(ctx context.Context, orgID, labelID string) ([]domain.Resource, error) {
+	var resources []domain.Resource
+	limit := domain.Limit(labelsPageSize)
+	offset := domain.Offset(0)
+	for {
+		params := &domain.GetBucketsParams{OrgID: &orgID, Limit: &limit, Offset: &offset}
+		response, err := u.apiClient.GetBuckets(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if response.Buckets == nil {
+			break
+		}
+		for _, b := range *response.Buckets {
+			if hasLabel(b.Labels, labelID) {
+				resources = append(resources, newLabeledResource(domain.ResourceTypeBuckets, b.Id, &b.Name, b.OrgID))
+			}
+		}
+		if len(*response.Buckets) < labelsPageSize {
+			break
+		}
+		offset += domain.Offset(len(*response.Buckets))
+	}
+	return resources, nil
+}
+
+func (u *labelsAPI) findLabeledDashboards(ctx context.Context, orgID, labelID string) ([]domain.Resource, error) {
+	var resources []domain.Resource
+	limit := domain.Limit(labelsPageSize)
+	offset := domain.Offset(0)
+	for {
+		params := &domain.GetDashboardsParams{OrgID: &orgID, Limit: &limit, Offset: &offset}
+		response, err := u.apiClient.GetDashboards(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if response.Dashboards == nil {
+			break
+		}
+		for _, d := range *response.Dashboards {
+			if hasLabel(d.Labels, labelID) {
+				resources = append(resources, newLabeledResource(domain.ResourceTypeDashboards, d.Id, &d.Name, &d.OrgID))
+			}
+		}
+		if len(*response.Dashboards) < labelsPageSize {
+			break
+		}
+		offset += domain.Offset(len(*response.Dashboards))
+	}
+	return resources, nil
+}
+
+func (u *labelsAPI) findLabeledScrapers(ctx context.Context, orgID, labelID string) ([]domain.Resource, error) {
+	var resources []domain.Resource
+	response, err := u.apiClient.GetScrapers(ctx, &domain.GetScrapersParams{OrgID: &orgID})
+	if err != nil {
+		return nil, err
+	}
+	if response.Configurations == nil {
+		return nil, nil
+	}
+	// scraper targets are listed without labels, they must be fetched separately
+	for _, s := range *response.Configurations {
+		if s.Id == nil {
+			continue
+		}
+		labels, err := u.FindResourceLabels(ctx, domain.ResourceTypeScrapers, *s.Id)
+		if err != nil {
+			return nil, err
+		}
+		if hasLabel((*domain.Labels)(labels), labelID) {
+			resources = append(resources, newLabeledResource(domain.ResourceTypeScrapers, s.Id, s.Name, s.OrgID))
+		}
+	}
+	return resources, nil
+}
+
+func (u *labelsAPI) findLabeledTasks(ctx context.Context, orgID, labelID string) ([]domain.Resource, error) {
+	var resources []domain.Resource
+	limit := labelsPageSize
+	after := ""
+	for {
+		params := &domain.GetTasksParams{OrgID: &orgID, Limit: &limit}
+		if after != "" {
+			params.After = &after
+		}
+		response, err := u.apiClient.GetTasks(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if response.Tasks == nil || len(*response.Tasks) == 0 {
+			break
+		}
+		for _, t := range *response.Tasks {
+			if hasLabel(t.Labels, labelID) {
+				resources = append(resources, newLabeledResource(domain.ResourceTypeTasks, &t.Id, &t.Name, &t.OrgID))
+			}
+		}
+		if len(*response.Tasks) < labelsPageSize {
+			break
+		}
+		after = (*response.Tasks)[len(*response.Tasks)-1].Id
+	}
+	return resources, nil
+}
+
+func (u *labelsAPI) findLabeledTelegrafs(ctx context.Context, orgID, labelID string) ([]domain.Resource, error) {
+	var resources []domain.Resource
+	response, err := u.apiClient.GetTelegrafs(ctx, &domain.GetTelegrafsParams{OrgID: &orgID})
+	if err != nil {
+		return nil, err
+	}
+	if response.Configurations == nil {
+		return nil, nil
+	}
+	for _, t := range *response.Configurations {
+		if hasLabel(t.Labels, labelID) {
+			resources = append(resources, newLabeledResource(domain.ResourceTypeTelegrafs, t.Id, t.Name, t.OrgID))
+		}
+	}
+	return resources, nil
+}
+
+func (u *labelsAPI) findLabeledVariables(ctx context.Context, orgID, labelID string) ([]domain.Resource, error) {
+	var resources []domain.Resource
+	response, err := u.apiClient.GetVariables(ctx, &domain.GetVariablesParams{OrgID: &orgID})
+	if err != nil {
+		return nil, err
+	}
+	if response.Variables == nil {
+		return nil, nil
+	}
+	for _, v := range *response.Variables {
+		if hasLabel(v.Labels, labelID) {
+			resources = append(resources, newLabeledResource(domain.ResourceTypeVariables, v.Id, &v.Name, &v.OrgID))
+		}
+	}
+	return resources, nil
+}
+
+func newLabeledResource(resourceType domain.ResourceType, id, name, orgID *string) domain.Resource {
+	return domain.Resource{Type: resourceType, Id: id, Name: name, OrgID: orgID}
+}
+
+func hasLabel(labels *domain.Labels, labelID string) bool {
+	if labels == nil {
+		return false
+	}
+	for _, l := range *labels {
+		if l.Id != nil && *l.Id == labelID {
+			return true
+		}
+	}
+	return false
+}
+
+func unsupportedLabelResourceError(resourceType domain.ResourceType) error {
+	return fmt.Errorf("labels are not supported for resource type '%s'", resourceType)
+}
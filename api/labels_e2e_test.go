@@ -90,6 +90,54 @@ func TestLabelsAPI(t *testing.T) {
 	assert.NotNil(t, err, err)
 }
 
+func TestLabelsAPI_Resources(t *testing.T) {
+	client := influxdb2.NewClient(serverURL, authToken)
+	labelsAPI := client.LabelsAPI()
+	ctx := context.Background()
+
+	org, err := client.OrganizationsAPI().FindOrganizationByName(ctx, "my-org")
+	require.Nil(t, err, err)
+	require.NotNil(t, org)
+
+	bucket, err := client.BucketsAPI().FindBucketByName(ctx, "my-bucket")
+	require.Nil(t, err, err)
+	require.NotNil(t, bucket)
+
+	label, err := labelsAPI.CreateLabelWithName(ctx, org, "inventory", nil)
+	require.Nil(t, err, err)
+	require.NotNil(t, label)
+
+	resources, err := labelsAPI.FindResourcesByLabel(ctx, label)
+	require.Nil(t, err, err)
+	require.NotNil(t, resources)
+	assert.Len(t, *resources, 0)
+
+	_, err = labelsAPI.AddResourceLabel(ctx, domain.ResourceTypeBuckets, *bucket.Id, *label.Id)
+	require.Nil(t, err, err)
+
+	labels, err := labelsAPI.FindResourceLabels(ctx, domain.ResourceTypeBuckets, *bucket.Id)
+	require.Nil(t, err, err)
+	require.NotNil(t, labels)
+	assert.Len(t, *labels, 1)
+
+	resources, err = labelsAPI.FindResourcesByLabelWithID(ctx, *label.Id)
+	require.Nil(t, err, err)
+	require.NotNil(t, resources)
+	require.Len(t, *resources, 1)
+	assert.Equal(t, domain.ResourceTypeBuckets, (*resources)[0].Type)
+	assert.Equal(t, *bucket.Id, *(*resources)[0].Id)
+
+	err = labelsAPI.RemoveResourceLabel(ctx, domain.ResourceTypeBuckets, *bucket.Id, *label.Id)
+	require.Nil(t, err, err)
+
+	resources, err = labelsAPI.FindResourcesByLabel(ctx, label)
+	require.Nil(t, err, err)
+	assert.Len(t, *resources, 0)
+
+	err = labelsAPI.DeleteLabel(ctx, label)
+	assert.Nil(t, err, err)
+}
+
 func TestLabelsAPI_failing(t *testing.T) {
 	client := influxdb2.NewClient(serverURL, authToken)
 	clientUnAuth := influxdb2.NewClient(serverURL, "invalid_token")
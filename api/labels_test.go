@@ -0,0 +1,157 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIClient(t *testing.T, handler http.Handler) *domain.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	apiClient, err := domain.NewClient(server.URL, server.Client())
+	require.NoError(t, err)
+	return apiClient
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+func TestLabelsAPI_ResourceLabels(t *testing.T) {
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, `{"labels":[{"id":"l1","name":"red","orgID":"o1"}]}`)
+		case http.MethodPost:
+			writeJSON(w, http.StatusCreated, `{"label":{"id":"l1","name":"red","orgID":"o1"}}`)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	labelsAPI := NewLabelsAPI(newTestAPIClient(t, mux))
+	ctx := context.Background()
+
+	resources := map[domain.ResourceType]string{
+		domain.ResourceTypeBuckets:    "buckets",
+		domain.ResourceTypeDashboards: "dashboards",
+		domain.ResourceTypeScrapers:   "scrapers",
+		domain.ResourceTypeTasks:      "tasks",
+		domain.ResourceTypeTelegrafs:  "telegrafs",
+		domain.ResourceTypeVariables:  "variables",
+	}
+	for resourceType, path := range resources {
+		requests = requests[:0]
+		labels, err := labelsAPI.FindResourceLabels(ctx, resourceType, "r1")
+		require.NoError(t, err)
+		require.NotNil(t, labels)
+		require.Len(t, *labels, 1)
+		assert.Equal(t, "red", *(*labels)[0].Name)
+
+		label, err := labelsAPI.AddResourceLabel(ctx, resourceType, "r1", "l1")
+		require.NoError(t, err)
+		require.NotNil(t, label)
+		assert.Equal(t, "l1", *label.Id)
+
+		err = labelsAPI.RemoveResourceLabel(ctx, resourceType, "r1", "l1")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			fmt.Sprintf("GET /api/v2/%s/r1/labels", path),
+			fmt.Sprintf("POST /api/v2/%s/r1/labels", path),
+			fmt.Sprintf("DELETE /api/v2/%s/r1/labels/l1", path),
+		}, requests)
+	}
+
+	_, err := labelsAPI.FindResourceLabels(ctx, domain.ResourceTypeOrgs, "r1")
+	assert.EqualError(t, err, "labels are not supported for resource type 'orgs'")
+	_, err = labelsAPI.AddResourceLabel(ctx, domain.ResourceTypeOrgs, "r1", "l1")
+	assert.Error(t, err)
+	err = labelsAPI.RemoveResourceLabel(ctx, domain.ResourceTypeOrgs, "r1", "l1")
+	assert.Error(t, err)
+}
+
+func TestLabelsAPI_FindResourcesByLabel(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/labels/l1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"label":{"id":"l1","name":"red","orgID":"o1"}}`)
+	})
+	mux.HandleFunc("/api/v2/buckets", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "o1", r.URL.Query().Get("orgID"))
+		if r.URL.Query().Get("offset") == "0" {
+			// full page forces reading of the next one
+			body := `{"buckets":[`
+			for i := 0; i < labelsPageSize; i++ {
+				if i > 0 {
+					body += ","
+				}
+				body += fmt.Sprintf(`{"id":"b%d","name":"bucket%d","retentionRules":[]}`, i, i)
+			}
+			writeJSON(w, http.StatusOK, body+`]}`)
+			return
+		}
+		assert.Equal(t, fmt.Sprintf("%d", labelsPageSize), r.URL.Query().Get("offset"))
+		writeJSON(w, http.StatusOK, `{"buckets":[{"id":"bx","name":"labeled","orgID":"o1","retentionRules":[],"labels":[{"id":"l1"}]}]}`)
+	})
+	mux.HandleFunc("/api/v2/dashboards", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"dashboards":[{"id":"d1","name":"dash","orgID":"o1","labels":[{"id":"l2"},{"id":"l1"}]},{"id":"d2","name":"other","orgID":"o1"}]}`)
+	})
+	mux.HandleFunc("/api/v2/scrapers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"configurations":[{"id":"s1","name":"scraper1","orgID":"o1"},{"id":"s2","name":"scraper2","orgID":"o1"}]}`)
+	})
+	mux.HandleFunc("/api/v2/scrapers/s1/labels", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"labels":[]}`)
+	})
+	mux.HandleFunc("/api/v2/scrapers/s2/labels", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"labels":[{"id":"l1"}]}`)
+	})
+	mux.HandleFunc("/api/v2/tasks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"tasks":[{"id":"t1","name":"task","orgID":"o1","flux":"","labels":[{"id":"l1"}]}]}`)
+	})
+	mux.HandleFunc("/api/v2/telegrafs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"configurations":[{"id":"tg1","name":"telegraf","orgID":"o1","labels":[{"id":"l3"}]}]}`)
+	})
+	mux.HandleFunc("/api/v2/variables", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"variables":[{"id":"v1","name":"var","orgID":"o1","arguments":{"type":"constant","values":[]},"labels":[{"id":"l1"}]}]}`)
+	})
+	labelsAPI := NewLabelsAPI(newTestAPIClient(t, mux))
+
+	resources, err := labelsAPI.FindResourcesByLabelWithID(context.Background(), "l1")
+	require.NoError(t, err)
+	require.NotNil(t, resources)
+	type found struct {
+		resourceType domain.ResourceType
+		id, name     string
+	}
+	var res []found
+	for _, r := range *resources {
+		res = append(res, found{r.Type, *r.Id, *r.Name})
+	}
+	assert.Equal(t, []found{
+		{domain.ResourceTypeBuckets, "bx", "labeled"},
+		{domain.ResourceTypeDashboards, "d1", "dash"},
+		{domain.ResourceTypeScrapers, "s2", "scraper2"},
+		{domain.ResourceTypeTasks, "t1", "task"},
+		{domain.ResourceTypeVariables, "v1", "var"},
+	}, res)
+
+	_, err = labelsAPI.FindResourcesByLabel(context.Background(), &domain.Label{Id: &[]string{"l1"}[0]})
+	assert.EqualError(t, err, "label 'l1' has no organization")
+	_, err = labelsAPI.FindResourcesByLabel(context.Background(), &domain.Label{Name: &[]string{"red"}[0]})
+	assert.EqualError(t, err, "label has no ID")
+}
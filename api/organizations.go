@@ -7,6 +7,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"iter"
 
 	"github.com/influxdata/influxdb-client-go/v2/domain"
 )
@@ -16,6 +17,10 @@ type OrganizationsAPI interface {
 	// GetOrganizations returns all organizations.
 	// GetOrganizations supports PagingOptions: Offset, Limit, Descending
 	GetOrganizations(ctx context.Context, pagingOptions ...PagingOption) (*[]domain.Organization, error)
+	// IterateOrganizations returns an iterator over all organizations, which are fetched page by page on demand.
+	// IterateOrganizations supports PagingOptions: Offset, Limit, Descending. Limit sets the page size, default is 100.
+	// Iteration ends with the first error.
+	IterateOrganizations(ctx context.Context, pagingOptions ...PagingOption) iter.Seq2[domain.Organization, error]
 	// FindOrganizationByName returns an organization found using orgName.
 	FindOrganizationByName(ctx context.Context, orgName string) (*domain.Organization, error)
 	// FindOrganizationByID returns an organization found using orgID.
@@ -72,10 +77,10 @@ func NewOrganizationsAPI(apiClient *domain.Client) OrganizationsAPI {
 }
 
 func (o *organizationsAPI) getOrganizations(ctx context.Context, params *domain.GetOrgsParams, pagingOptions ...PagingOption) (*[]domain.Organization, error) {
-	options := defaultPaging()
-	for _, opt := range pagingOptions {
-		opt(options)
-	}
+	return o.getOrganizationsPage(ctx, params, newPaging(pagingOptions...))
+}
+
+func (o *organizationsAPI) getOrganizationsPage(ctx context.Context, params *domain.GetOrgsParams, options *Paging) (*[]domain.Organization, error) {
 	if options.limit > 0 {
 		params.Limit = &options.limit
 	}
@@ -92,6 +97,16 @@ func (o *organizationsAPI) GetOrganizations(ctx context.Context, pagingOptions .
 	return o.getOrganizations(ctx, params, pagingOptions...)
 }
 
+func (o *organizationsAPI) IterateOrganizations(ctx context.Context, pagingOptions ...PagingOption) iter.Seq2[domain.Organization, error] {
+	return iteratePages(newPaging(pagingOptions...), offsetCursor, func(p *Paging) ([]domain.Organization, error) {
+		orgs, err := o.getOrganizationsPage(ctx, &domain.GetOrgsParams{}, p)
+		if err != nil || orgs == nil {
+			return nil, err
+		}
+		return *orgs, nil
+	}, nil)
+}
+
 func (o *organizationsAPI) FindOrganizationByName(ctx context.Context, orgName string) (*domain.Organization, error) {
 	params := &domain.GetOrgsParams{Org: &orgName}
 	organizations, err := o.getOrganizations(ctx, params)
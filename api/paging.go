@@ -4,7 +4,12 @@
 
 package api
 
-import "github.com/influxdata/influxdb-client-go/v2/domain"
+import (
+	"errors"
+	"iter"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+)
 
 // PagingOption is the function type for applying paging option
 type PagingOption func(p *Paging)
@@ -67,3 +72,86 @@ func PagingWithAfter(after string) PagingOption {
 		p.after = domain.After(after)
 	}
 }
+
+// defaultIteratorPageSize is the number of items requested in a single page by Iterate* functions,
+// when no limit is set.
+const defaultIteratorPageSize = 100
+
+// pagingCursor defines how Iterate* functions move to the next page
+type pagingCursor int
+
+const (
+	// offsetCursor moves to the next page by increasing offset
+	offsetCursor pagingCursor = iota
+	// afterCursor moves to the next page by setting after to ID of the last item
+	afterCursor
+)
+
+// iteratePages returns an iterator over all items, which are retrieved page by page using fetch.
+// Paging limit is used as a page size, defaultIteratorPageSize is used when not set.
+// Iteration stops after an empty or an incomplete page. Fetch error is yielded along with the zero item and ends the iteration.
+// id returns ID of an item, it is used when cursor is afterCursor. An error is yielded when the last item of a page has no ID.
+func iteratePages[T any](paging *Paging, cursor pagingCursor, fetch func(paging *Paging) ([]T, error), id func(item *T) *string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		p := *paging
+		if p.limit <= 0 {
+			p.limit = defaultIteratorPageSize
+		}
+		for {
+			page, err := fetch(&p)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(page) == 0 || len(page) < int(p.limit) {
+				return
+			}
+			if cursor == afterCursor {
+				last := id(&page[len(page)-1])
+				if last == nil {
+					var zero T
+					yield(zero, errors.New("cannot fetch the next page, the last item has no ID"))
+					return
+				}
+				p.after = domain.After(*last)
+			} else {
+				p.offset += domain.Offset(len(page))
+			}
+		}
+	}
+}
+
+// iterateSlice returns an iterator over items of a single, not paged, result retrieved using fetch.
+func iterateSlice[T any](fetch func() (*[]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		items, err := fetch()
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		if items == nil {
+			return
+		}
+		for _, item := range *items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// newPaging creates Paging from pagingOptions
+func newPaging(pagingOptions ...PagingOption) *Paging {
+	options := defaultPaging()
+	for _, opt := range pagingOptions {
+		opt(options)
+	}
+	return options
+}
@@ -5,10 +5,16 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
 	"testing"
 
 	"github.com/influxdata/influxdb-client-go/v2/domain"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPaging(t *testing.T) {
@@ -32,3 +38,164 @@ func TestPaging(t *testing.T) {
 	PagingWithLimit(1000)(paging)
 	assert.Equal(t, domain.Limit(1000), paging.limit)
 }
+
+func TestIteratePages(t *testing.T) {
+	items := make([]string, 25)
+	for i := range items {
+		items[i] = fmt.Sprintf("%02d", i)
+	}
+	var requests []Paging
+	fetch := func(p *Paging) ([]string, error) {
+		requests = append(requests, *p)
+		start := int(p.offset)
+		if p.after != "" {
+			start, _ = strconv.Atoi(string(p.after))
+			start++
+		}
+		end := min(start+int(p.limit), len(items))
+		return items[start:end], nil
+	}
+	id := func(s *string) *string { return s }
+
+	// offset cursor, default page size
+	var res []string
+	for item, err := range iteratePages(newPaging(), offsetCursor, fetch, id) {
+		require.NoError(t, err)
+		res = append(res, item)
+	}
+	assert.Equal(t, items, res)
+	require.Len(t, requests, 1)
+	assert.Equal(t, domain.Limit(defaultIteratorPageSize), requests[0].limit)
+
+	// offset cursor, page size divides items
+	requests = requests[:0]
+	res = res[:0]
+	for item, err := range iteratePages(newPaging(PagingWithLimit(5)), offsetCursor, fetch, id) {
+		require.NoError(t, err)
+		res = append(res, item)
+	}
+	assert.Equal(t, items, res)
+	require.Len(t, requests, 6)
+	assert.Equal(t, domain.Offset(25), requests[5].offset)
+
+	// after cursor
+	requests = requests[:0]
+	res = res[:0]
+	for item, err := range iteratePages(newPaging(PagingWithLimit(10), PagingWithAfter("04")), afterCursor, fetch, id) {
+		require.NoError(t, err)
+		res = append(res, item)
+	}
+	assert.Equal(t, items[5:], res)
+	require.Len(t, requests, 3)
+	assert.Equal(t, domain.After("14"), requests[1].after)
+	assert.Equal(t, domain.After("24"), requests[2].after)
+
+	// stop iteration
+	requests = requests[:0]
+	res = res[:0]
+	for item, err := range iteratePages(newPaging(PagingWithLimit(3)), offsetCursor, fetch, id) {
+		require.NoError(t, err)
+		res = append(res, item)
+		if len(res) == 4 {
+			break
+		}
+	}
+	assert.Equal(t, items[:4], res)
+	assert.Len(t, requests, 2)
+
+	// error
+	n := 0
+	for item, err := range iteratePages(newPaging(PagingWithLimit(3)), offsetCursor, func(p *Paging) ([]string, error) {
+		if p.offset > 0 {
+			return nil, errors.New("failed")
+		}
+		return fetch(p)
+	}, id) {
+		n++
+		if n <= 3 {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.EqualError(t, err, "failed")
+		assert.Equal(t, "", item)
+	}
+	assert.Equal(t, 4, n)
+
+	// item without ID
+	n = 0
+	for item, err := range iteratePages(newPaging(PagingWithLimit(3)), afterCursor, fetch, func(s *string) *string { return nil }) {
+		n++
+		if n <= 3 {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.EqualError(t, err, "cannot fetch the next page, the last item has no ID")
+		assert.Equal(t, "", item)
+	}
+	assert.Equal(t, 4, n)
+}
+
+func TestIterateBuckets(t *testing.T) {
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/buckets", func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("after") == "0" {
+			writeJSON(w, http.StatusOK, `{"buckets":[{"id":"1","name":"a","retentionRules":[]},{"id":"2","name":"b","retentionRules":[]}]}`)
+		} else {
+			writeJSON(w, http.StatusOK, `{"buckets":[{"id":"3","name":"c","retentionRules":[]}]}`)
+		}
+	})
+	bucketsAPI := NewBucketsAPI(newTestAPIClient(t, mux))
+
+	var names []string
+	for b, err := range bucketsAPI.IterateBuckets(context.Background(), PagingWithLimit(2), PagingWithAfter("0")) {
+		require.NoError(t, err)
+		names = append(names, b.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+	assert.Equal(t, []string{"after=0&limit=2", "after=2&limit=2"}, queries)
+}
+
+func TestIterateBuckets_NoID(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/buckets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"buckets":[{"name":"a","retentionRules":[]}]}`)
+	})
+	bucketsAPI := NewBucketsAPI(newTestAPIClient(t, mux))
+
+	var names []string
+	var errs []error
+	for b, err := range bucketsAPI.IterateBuckets(context.Background(), PagingWithLimit(1), PagingWithAfter("0")) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		names = append(names, b.Name)
+	}
+	assert.Equal(t, []string{"a"}, names)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "cannot fetch the next page, the last item has no ID")
+}
+
+func TestIterateTasks(t *testing.T) {
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/tasks", func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("after") == "" {
+			writeJSON(w, http.StatusOK, `{"tasks":[{"id":"1","name":"a","orgID":"o","flux":""}]}`)
+		} else {
+			writeJSON(w, http.StatusOK, `{"tasks":[]}`)
+		}
+	})
+	tasksAPI := NewTasksAPI(newTestAPIClient(t, mux))
+
+	var names []string
+	for task, err := range tasksAPI.IterateTasks(context.Background(), &TaskFilter{OrgID: "o", Limit: 1}) {
+		require.NoError(t, err)
+		names = append(names, task.Name)
+	}
+	assert.Equal(t, []string{"a"}, names)
+	assert.Equal(t, []string{"limit=1&orgID=o", "after=1&limit=1&orgID=o"}, queries)
+}
@@ -73,11 +73,19 @@ type QueryAPI interface {
 	QueryWithParams(ctx context.Context, query string, params interface{}) (*QueryTableResult, error)
 }
 
-// NewQueryAPI returns new query client for querying buckets belonging to org
+// NewQueryAPI returns new query client for querying buckets belonging to org, query responses are gzip compressed
 func NewQueryAPI(org string, service http2.Service) QueryAPI {
+	return NewQueryAPIWithCompression(org, service, http2.GzipCodec(gzip.DefaultCompression))
+}
+
+// NewQueryAPIWithCompression returns new query client for querying buckets belonging to org,
+// which accepts query responses compressed by codecs, in order of preference.
+// Without codecs, query responses are requested uncompressed.
+func NewQueryAPIWithCompression(org string, service http2.Service, codecs ...http2.Codec) QueryAPI {
 	return &queryAPI{
 		org:         org,
 		httpService: service,
+		codecs:      codecs,
 	}
 }
 
@@ -109,6 +117,8 @@ type queryAPI struct {
 	httpService http2.Service
 	url         string
 	lock        sync.Mutex
+	// codecs accepted for compressed responses
+	codecs []http2.Codec
 }
 
 // queryBody holds the body for an HTTP query request.
@@ -147,14 +157,11 @@ func (q *queryAPI) QueryRawWithParams(ctx context.Context, query string, dialect
 	var body string
 	perror := q.httpService.DoPostRequest(ctx, queryURL, bytes.NewReader(qrJSON), func(req *http.Request) {
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept-Encoding", "gzip")
+		q.setAcceptEncoding(req)
 	},
 		func(resp *http.Response) error {
-			if resp.Header.Get("Content-Encoding") == "gzip" {
-				resp.Body, err = gzip.NewReader(resp.Body)
-				if err != nil {
-					return err
-				}
+			if err := q.decompress(resp); err != nil {
+				return err
 			}
 			respBody, err := io.ReadAll(resp.Body)
 			if err != nil {
@@ -169,6 +176,52 @@ func (q *queryAPI) QueryRawWithParams(ctx context.Context, query string, dialect
 	return body, nil
 }
 
+// setAcceptEncoding sets Accept-Encoding header of req to encodings of the query codecs
+func (q *queryAPI) setAcceptEncoding(req *http.Request) {
+	if len(q.codecs) == 0 {
+		req.Header.Set("Accept-Encoding", "identity")
+		return
+	}
+	encodings := make([]string, len(q.codecs))
+	for i, c := range q.codecs {
+		encodings[i] = c.Encoding()
+	}
+	req.Header.Set("Accept-Encoding", strings.Join(encodings, ", "))
+}
+
+// decompress replaces body of resp with a reader decompressing it by the codec of its Content-Encoding, if any.
+// It returns an error and closes the body, if the body cannot be decompressed by the query codecs.
+func (q *queryAPI) decompress(resp *http.Response) error {
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || encoding == "identity" {
+		return nil
+	}
+	for _, c := range q.codecs {
+		if c.Encoding() == encoding {
+			r, err := c.NewReader(resp.Body)
+			if err != nil {
+				_ = resp.Body.Close()
+				return err
+			}
+			resp.Body = &decompressedBody{ReadCloser: r, body: resp.Body}
+			return nil
+		}
+	}
+	_ = resp.Body.Close()
+	return fmt.Errorf("unsupported content encoding of query response: %s", encoding)
+}
+
+// decompressedBody reads decompressed response body, Close closes both the decompressing reader and the body
+type decompressedBody struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
+
+// Close closes the decompressing reader and the response body
+func (b *decompressedBody) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.body.Close())
+}
+
 // DefaultDialect return flux query Dialect with full annotations (datatype, group, default), header and comma char as a delimiter
 func DefaultDialect() *domain.Dialect {
 	annotations := []domain.DialectAnnotations{domain.DialectAnnotationsDatatype, domain.DialectAnnotationsGroup, domain.DialectAnnotationsDefault}
@@ -209,14 +262,11 @@ func (q *queryAPI) QueryWithParams(ctx context.Context, query string, params int
 	}
 	perror := q.httpService.DoPostRequest(ctx, queryURL, bytes.NewReader(qrJSON), func(req *http.Request) {
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept-Encoding", "gzip")
+		q.setAcceptEncoding(req)
 	},
 		func(resp *http.Response) error {
-			if resp.Header.Get("Content-Encoding") == "gzip" {
-				resp.Body, err = gzip.NewReader(resp.Body)
-				if err != nil {
-					return err
-				}
+			if err := q.decompress(resp); err != nil {
+				return err
 			}
 			csvReader := csv.NewReader(resp.Body)
 			csvReader.FieldsPerRecord = -1
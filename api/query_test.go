@@ -5,6 +5,8 @@
 package api
 
 import (
+	"bytes"
+	egzip "compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -16,7 +18,6 @@ import (
 
 	http2 "github.com/influxdata/influxdb-client-go/v2/api/http"
 	"github.com/influxdata/influxdb-client-go/v2/api/query"
-	"github.com/influxdata/influxdb-client-go/v2/internal/gzip"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -485,7 +486,7 @@ func TestQueryRawResult(t *testing.T) {
 		if r.Method == http.MethodPost {
 			rbody, _ := io.ReadAll(r.Body)
 			fmt.Printf("Req: %s\n", string(rbody))
-			body, err := gzip.CompressWithGzip(strings.NewReader(csvTable))
+			body, err := compressWithGzip(strings.NewReader(csvTable))
 			if err == nil {
 				var bytes []byte
 				bytes, err = io.ReadAll(body)
@@ -513,6 +514,16 @@ func TestQueryRawResult(t *testing.T) {
 	assert.Equal(t, csvTable, result)
 }
 
+// compressWithGzip returns data compressed by gzip
+func compressWithGzip(data io.Reader) (io.Reader, error) {
+	var buf bytes.Buffer
+	gw := egzip.NewWriter(&buf)
+	if _, err := io.Copy(gw, data); err != nil {
+		return nil, err
+	}
+	return &buf, gw.Close()
+}
+
 func TestErrorInRow(t *testing.T) {
 	csvRowsError := []string{
 		`#datatype,string,string`,
@@ -1066,3 +1077,57 @@ func makeCSVstring(rows []string) string {
 	csvTable := strings.Join(rows, "\r\n")
 	return fmt.Sprintf("%s\r\n", csvTable)
 }
+
+func TestQueryCompression(t *testing.T) {
+	csvTable := "#datatype,string,long,double\r\n#group,false,false,false\r\n#default,_result,,\r\n,result,table,_value\r\n,,0,1.5\r\n\r\n"
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/csv")
+		if strings.Contains(acceptEncoding, "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gw := egzip.NewWriter(w)
+			_, _ = gw.Write([]byte(csvTable))
+			_ = gw.Close()
+			return
+		}
+		_, _ = w.Write([]byte(csvTable))
+	}))
+	defer server.Close()
+	service := http2.NewService(server.URL, "a", http2.DefaultOptions())
+
+	queryAPI := NewQueryAPIWithCompression("org", service, http2.GzipCodec(egzip.BestSpeed))
+	res, err := queryAPI.QueryRaw(context.Background(), "flux", nil)
+	require.NoError(t, err)
+	assert.Equal(t, csvTable, res)
+	assert.Equal(t, "gzip", acceptEncoding)
+
+	result, err := queryAPI.Query(context.Background(), "flux")
+	require.NoError(t, err)
+	require.True(t, result.Next())
+	assert.Equal(t, 1.5, result.Record().Value())
+	assert.False(t, result.Next())
+	require.NoError(t, result.Err())
+	require.NoError(t, result.Close())
+
+	queryAPI = NewQueryAPIWithCompression("org", service)
+	res, err = queryAPI.QueryRaw(context.Background(), "flux", nil)
+	require.NoError(t, err)
+	assert.Equal(t, csvTable, res)
+	assert.Equal(t, "identity", acceptEncoding)
+}
+
+func TestQueryUnsupportedEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Encoding", "zstd")
+		_, _ = w.Write([]byte{0x28, 0xb5, 0x2f, 0xfd})
+	}))
+	defer server.Close()
+	queryAPI := NewQueryAPI("org", http2.NewService(server.URL, "a", http2.DefaultOptions()))
+
+	_, err := queryAPI.QueryRaw(context.Background(), "flux", nil)
+	assert.EqualError(t, err, "unsupported content encoding of query response: zstd")
+	_, err = queryAPI.Query(context.Background(), "flux")
+	assert.EqualError(t, err, "unsupported content encoding of query response: zstd")
+}
@@ -0,0 +1,46 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package reconcile_test
+
+import (
+	"context"
+	"fmt"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/reconcile"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+)
+
+func ExampleReconciler() {
+	// Create a new client using an InfluxDB server base URL and an authentication token
+	client := influxdb2.NewClient("http://localhost:8086", "my-token")
+	// Ensures background processes finishes
+	defer client.Close()
+
+	// Read desired state
+	state, err := reconcile.ReadStateFile("influxdb-state.yaml")
+	if err != nil {
+		panic(err)
+	}
+	reconciler := reconcile.NewReconciler(client)
+	ctx := context.Background()
+	// Compute changes, including deletion of resources missing in the desired state
+	plan, err := reconciler.Plan(ctx, state, reconcile.PlanWithPrune(true))
+	if err != nil {
+		panic(err)
+	}
+	// Print changes (dry-run)
+	fmt.Println(plan)
+	// Apply changes
+	if err := reconciler.Apply(ctx, plan); err != nil {
+		panic(err)
+	}
+	// Print tokens of created authorizations
+	for _, change := range plan.Changes {
+		if auth, ok := change.Resource.(*domain.Authorization); ok && change.Action != reconcile.ActionUpdate {
+			fmt.Println(change.Name, *auth.Token)
+		}
+	}
+}
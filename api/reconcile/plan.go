@@ -0,0 +1,111 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Action is the kind of change of a resource
+type Action string
+
+const (
+	// ActionCreate creates a resource, or adds a member or an owner
+	ActionCreate Action = "create"
+	// ActionUpdate updates a resource in place
+	ActionUpdate Action = "update"
+	// ActionReplace deletes a resource and creates it again, used for authorizations, whose permissions cannot be updated
+	ActionReplace Action = "replace"
+	// ActionDelete deletes a resource, or removes a member or an owner
+	ActionDelete Action = "delete"
+)
+
+// Kind is the kind of changed resource
+type Kind string
+
+// Kinds of resources managed by Reconciler
+const (
+	KindOrganization  Kind = "organization"
+	KindLabel         Kind = "label"
+	KindBucket        Kind = "bucket"
+	KindTask          Kind = "task"
+	KindAuthorization Kind = "authorization"
+	KindMember        Kind = "member"
+	KindOwner         Kind = "owner"
+)
+
+// Change is a single change required to reach the desired state
+type Change struct {
+	Action Action
+	Kind   Kind
+	// Name identifies the resource, resources of an organization are prefixed with the organization name, e.g. my-org/my-bucket
+	Name string
+	// Details describe changed properties
+	Details []string
+	// Applied is true once the change was successfully applied
+	Applied bool
+	// Resource is the created or updated resource, set once the change is applied,
+	// e.g. *domain.Authorization holding the token of a created authorization
+	Resource interface{}
+	// apply performs the change
+	apply func(ctx context.Context, ids map[string]string) (interface{}, error)
+}
+
+// symbols maps actions to prefixes used in the plan output
+var symbols = map[Action]string{
+	ActionCreate:  "+",
+	ActionUpdate:  "~",
+	ActionReplace: "-/+",
+	ActionDelete:  "-",
+}
+
+// String returns a human-readable description of the change
+func (c *Change) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s '%s'", symbols[c.Action], c.Action, c.Kind, c.Name)
+	for _, d := range c.Details {
+		sb.WriteString("\n    " + d)
+	}
+	return sb.String()
+}
+
+// Plan is an ordered list of changes required to reach the desired state
+type Plan struct {
+	Changes []*Change
+	// ids maps keys of resources, see resourceKey, to IDs of existing resources. IDs of created resources are added when applied.
+	ids map[string]string
+}
+
+// HasChanges returns true if the server differs from the desired state
+func (p *Plan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// String returns a human-readable list of changes, which serves as a dry-run output
+func (p *Plan) String() string {
+	if !p.HasChanges() {
+		return "No changes"
+	}
+	lines := make([]string, len(p.Changes))
+	for i, c := range p.Changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// add appends a change to the plan
+func (p *Plan) add(action Action, kind Kind, name string, details []string, apply func(ctx context.Context, ids map[string]string) (interface{}, error)) {
+	p.Changes = append(p.Changes, &Change{Action: action, Kind: kind, Name: name, Details: details, apply: apply})
+}
+
+// resourceKey returns key of a resource in the ids map
+func resourceKey(kind Kind, orgName, name string) string {
+	if kind == KindOrganization {
+		return string(kind) + ":" + orgName
+	}
+	return string(kind) + ":" + orgName + "/" + name
+}
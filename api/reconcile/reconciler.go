@@ -0,0 +1,1016 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/influxdata/influxdb-client-go/v2/internal/schedule"
+)
+
+// Client provides APIs used by Reconciler. It is implemented by influxdb2.Client.
+type Client interface {
+	OrganizationsAPI() api.OrganizationsAPI
+	UsersAPI() api.UsersAPI
+	LabelsAPI() api.LabelsAPI
+	BucketsAPI() api.BucketsAPI
+	TasksAPI() api.TasksAPI
+	AuthorizationsAPI() api.AuthorizationsAPI
+}
+
+// Reconciler brings the server to the desired state
+type Reconciler interface {
+	// Plan compares the desired state with the server and returns changes required to reach the desired state.
+	// The plan can be printed to review changes before they are applied (dry-run).
+	Plan(ctx context.Context, state *State, options ...PlanOption) (*Plan, error)
+	// Apply applies changes of the plan in order. It stops at the first change which fails.
+	Apply(ctx context.Context, plan *Plan) error
+}
+
+// PlanOption is the function type for applying plan option
+type PlanOption func(o *planOptions)
+
+// planOptions holds parameters of Reconciler.Plan
+type planOptions struct {
+	// Whether to delete resources not in the desired state
+	prune bool
+	// Whether to delete authorizations not in the desired state
+	pruneAuthorizations bool
+}
+
+// PlanWithPrune sets whether labels, buckets and tasks of managed organizations,
+// which are not in the desired state, are deleted. Default false. System buckets are never deleted.
+// Authorizations are deleted only with PlanWithPruneAuthorizations.
+func PlanWithPrune(prune bool) PlanOption {
+	return func(o *planOptions) {
+		o.prune = prune
+	}
+}
+
+// PlanWithPruneAuthorizations sets whether authorizations of managed organizations, which are not in the desired state,
+// are deleted. Default false, as tokens of deleted authorizations cannot be restored.
+// The authorization of the token used by the client and authorizations whose token is not returned by the server are never deleted.
+func PlanWithPruneAuthorizations(prune bool) PlanOption {
+	return func(o *planOptions) {
+		o.pruneAuthorizations = prune
+	}
+}
+
+// reconciler implements Reconciler interface
+type reconciler struct {
+	client Client
+}
+
+// NewReconciler creates instance of Reconciler using APIs of the client
+func NewReconciler(client Client) Reconciler {
+	return &reconciler{client: client}
+}
+
+func (r *reconciler) Apply(ctx context.Context, plan *Plan) error {
+	if plan.ids == nil {
+		plan.ids = make(map[string]string)
+	}
+	for _, c := range plan.Changes {
+		if c.Applied {
+			continue
+		}
+		res, err := c.apply(ctx, plan.ids)
+		if err != nil {
+			return fmt.Errorf("%s %s '%s': %w", c.Action, c.Kind, c.Name, err)
+		}
+		c.Resource = res
+		c.Applied = true
+	}
+	return nil
+}
+
+func (r *reconciler) Plan(ctx context.Context, state *State, options ...PlanOption) (*Plan, error) {
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+	opts := &planOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	p := &planner{reconciler: r, opts: opts, plan: &Plan{ids: make(map[string]string)}, token: clientToken(r.client)}
+	orgs := make(map[string]*domain.Organization)
+	for org, err := range r.client.OrganizationsAPI().IterateOrganizations(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		orgs[org.Name] = &org
+	}
+	for i := range state.Orgs {
+		if err := p.planOrg(ctx, &state.Orgs[i], orgs[state.Orgs[i].Name]); err != nil {
+			return nil, fmt.Errorf("organization '%s': %w", state.Orgs[i].Name, err)
+		}
+	}
+	return p.plan, nil
+}
+
+// planner holds state of a single Plan call
+type planner struct {
+	*reconciler
+	opts *planOptions
+	plan *Plan
+	// token used by the client, empty if unknown
+	token string
+	// users maps user names to IDs, loaded on demand
+	users map[string]string
+	// buckets groups existing buckets by org ID, loaded on demand
+	buckets map[string][]domain.Bucket
+}
+
+// clientToken returns the token of the authorization header of the client, or empty string if the client doesn't use a token
+func clientToken(client Client) string {
+	if c, ok := client.(interface{ HTTPService() http.Service }); ok {
+		if authorization, ok := strings.CutPrefix(c.HTTPService().Authorization(), "Token "); ok {
+			return authorization
+		}
+	}
+	return ""
+}
+
+// userID returns ID of the user with name
+func (p *planner) userID(ctx context.Context, name string) (string, error) {
+	if p.users == nil {
+		p.users = make(map[string]string)
+		for user, err := range p.client.UsersAPI().IterateUsers(ctx) {
+			if err != nil {
+				p.users = nil
+				return "", err
+			}
+			p.users[user.Name] = *user.Id
+		}
+	}
+	id, ok := p.users[name]
+	if !ok {
+		return "", fmt.Errorf("user '%s' not found", name)
+	}
+	return id, nil
+}
+
+// orgBuckets returns existing buckets of the organization
+func (p *planner) orgBuckets(ctx context.Context, orgID string) ([]domain.Bucket, error) {
+	if p.buckets == nil {
+		p.buckets = make(map[string][]domain.Bucket)
+		for bucket, err := range p.client.BucketsAPI().IterateBuckets(ctx) {
+			if err != nil {
+				p.buckets = nil
+				return nil, err
+			}
+			if bucket.OrgID != nil {
+				p.buckets[*bucket.OrgID] = append(p.buckets[*bucket.OrgID], bucket)
+			}
+		}
+	}
+	return p.buckets[orgID], nil
+}
+
+// existingOrg holds existing resources of an organization
+type existingOrg struct {
+	labels  map[string]*domain.Label
+	buckets map[string]*domain.Bucket
+	tasks   map[string]*domain.Task
+	auths   map[string]*domain.Authorization
+	// names maps IDs of labels, buckets and tasks to names
+	names map[string]string
+}
+
+// load fetches existing resources of the organization
+func (p *planner) load(ctx context.Context, orgID string) (*existingOrg, error) {
+	e := &existingOrg{
+		labels:  make(map[string]*domain.Label),
+		buckets: make(map[string]*domain.Bucket),
+		tasks:   make(map[string]*domain.Task),
+		auths:   make(map[string]*domain.Authorization),
+		names:   make(map[string]string),
+	}
+	if orgID == "" {
+		return e, nil
+	}
+	labels, err := p.client.LabelsAPI().FindLabelsByOrgID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range *labels {
+		e.labels[*l.Name] = &(*labels)[i]
+		e.names[*l.Id] = *l.Name
+	}
+	buckets, err := p.orgBuckets(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range buckets {
+		e.buckets[b.Name] = &buckets[i]
+		e.names[*b.Id] = b.Name
+	}
+	for task, err := range p.client.TasksAPI().IterateTasks(ctx, &api.TaskFilter{OrgID: orgID}) {
+		if err != nil {
+			return nil, err
+		}
+		e.tasks[task.Name] = &task
+		e.names[task.Id] = task.Name
+	}
+	auths, err := p.client.AuthorizationsAPI().FindAuthorizationsByOrgID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range *auths {
+		if a.Description != nil {
+			e.auths[*a.Description] = &(*auths)[i]
+		}
+	}
+	return e, nil
+}
+
+func (p *planner) planOrg(ctx context.Context, org *Organization, existing *domain.Organization) error {
+	orgKey := resourceKey(KindOrganization, org.Name, "")
+	orgID := ""
+	if existing == nil {
+		p.plan.add(ActionCreate, KindOrganization, org.Name, describe("description", org.Description),
+			func(ctx context.Context, ids map[string]string) (interface{}, error) {
+				created, err := p.client.OrganizationsAPI().CreateOrganization(ctx, &domain.Organization{Name: org.Name, Description: optional(org.Description)})
+				if err != nil {
+					return nil, err
+				}
+				ids[orgKey] = *created.Id
+				return created, nil
+			})
+	} else {
+		orgID = *existing.Id
+		p.plan.ids[orgKey] = orgID
+		if value(existing.Description) != org.Description {
+			p.plan.add(ActionUpdate, KindOrganization, org.Name, diff("description", value(existing.Description), org.Description),
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					update := *existing
+					update.Description = optional(org.Description)
+					return p.client.OrganizationsAPI().UpdateOrganization(ctx, &update)
+				})
+		}
+	}
+	e, err := p.load(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	// existing resources can be referenced by desired ones
+	for name, l := range e.labels {
+		p.plan.ids[resourceKey(KindLabel, org.Name, name)] = *l.Id
+	}
+	for name, b := range e.buckets {
+		p.plan.ids[resourceKey(KindBucket, org.Name, name)] = *b.Id
+	}
+	for name, t := range e.tasks {
+		p.plan.ids[resourceKey(KindTask, org.Name, name)] = t.Id
+	}
+	p.planLabels(org, e)
+	for i := range org.Buckets {
+		if err := p.planBucket(org, &org.Buckets[i], e); err != nil {
+			return fmt.Errorf("bucket '%s': %w", org.Buckets[i].Name, err)
+		}
+	}
+	for i := range org.Tasks {
+		if err := p.planTask(org, &org.Tasks[i], e); err != nil {
+			return fmt.Errorf("task '%s': %w", org.Tasks[i].Name, err)
+		}
+	}
+	for i := range org.Authorizations {
+		if err := p.planAuthorization(ctx, org, &org.Authorizations[i], e); err != nil {
+			return fmt.Errorf("authorization '%s': %w", org.Authorizations[i].Description, err)
+		}
+	}
+	if err := p.planMembership(ctx, org, orgID); err != nil {
+		return err
+	}
+	if p.opts.pruneAuthorizations {
+		p.planPruneAuthorizations(org, e)
+	}
+	if p.opts.prune {
+		p.planPrune(org, e)
+	}
+	return nil
+}
+
+// labelProperties returns properties of the desired label
+func labelProperties(label *Label) map[string]string {
+	return map[string]string{"color": label.Color, "description": label.Description}
+}
+
+func (p *planner) planLabels(org *Organization, e *existingOrg) {
+	orgKey := resourceKey(KindOrganization, org.Name, "")
+	for i := range org.Labels {
+		label := &org.Labels[i]
+		key := resourceKey(KindLabel, org.Name, label.Name)
+		name := org.Name + "/" + label.Name
+		existing := e.labels[label.Name]
+		if existing == nil {
+			props := labelProperties(label)
+			for k, v := range props {
+				if v == "" {
+					delete(props, k)
+				}
+			}
+			p.plan.add(ActionCreate, KindLabel, name, describe("color", label.Color, "description", label.Description),
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					created, err := p.client.LabelsAPI().CreateLabelWithNameWithID(ctx, ids[orgKey], label.Name, props)
+					if err != nil {
+						return nil, err
+					}
+					ids[key] = *created.Id
+					return created, nil
+				})
+			continue
+		}
+		var details []string
+		props := make(map[string]string)
+		for k, v := range labelProperties(label) {
+			var current string
+			if existing.Properties != nil {
+				current = existing.Properties.AdditionalProperties[k]
+			}
+			if current != v {
+				details = append(details, diff(k, current, v)...)
+				props[k] = v
+			}
+		}
+		if len(details) > 0 {
+			sort.Strings(details)
+			p.plan.add(ActionUpdate, KindLabel, name, details,
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					update := &domain.Label{Id: existing.Id, Name: existing.Name, Properties: &domain.Label_Properties{AdditionalProperties: props}}
+					return p.client.LabelsAPI().UpdateLabel(ctx, update)
+				})
+		}
+	}
+}
+
+// labelIDs resolves IDs of labels by names
+func labelIDs(ids map[string]string, orgName string, names []string) ([]string, error) {
+	res := make([]string, len(names))
+	for i, n := range names {
+		id, ok := ids[resourceKey(KindLabel, orgName, n)]
+		if !ok {
+			return nil, fmt.Errorf("label '%s' not found", n)
+		}
+		res[i] = id
+	}
+	return res, nil
+}
+
+// labelsDiff returns names of labels to add to and remove from a resource with current labels
+func labelsDiff(org *Organization, e *existingOrg, current *domain.Labels, desired []string) (add, remove []string, err error) {
+	for _, n := range desired {
+		if e.labels[n] == nil && !hasLabel(org, n) {
+			return nil, nil, fmt.Errorf("label '%s' not found", n)
+		}
+	}
+	if desired == nil {
+		return nil, nil, nil
+	}
+	has := make(map[string]bool)
+	if current != nil {
+		for _, l := range *current {
+			has[value(l.Name)] = true
+		}
+	}
+	want := make(map[string]bool)
+	for _, n := range desired {
+		want[n] = true
+		if !has[n] {
+			add = append(add, n)
+		}
+	}
+	if current != nil {
+		for _, l := range *current {
+			if !want[value(l.Name)] {
+				remove = append(remove, value(l.Name))
+			}
+		}
+	}
+	return add, remove, nil
+}
+
+// hasLabel returns true if the desired organization contains label with name
+func hasLabel(org *Organization, name string) bool {
+	for _, l := range org.Labels {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// labelDetails describes labels changes
+func labelDetails(add, remove []string) []string {
+	var details []string
+	for _, n := range add {
+		details = append(details, fmt.Sprintf("add label '%s'", n))
+	}
+	for _, n := range remove {
+		details = append(details, fmt.Sprintf("remove label '%s'", n))
+	}
+	return details
+}
+
+// updateLabels adds and removes labels of a resource
+func updateLabels(ctx context.Context, ids map[string]string, orgName string, add, remove []string,
+	addLabel func(ctx context.Context, labelID string) error, removeLabel func(ctx context.Context, labelID string) error) error {
+	addIDs, err := labelIDs(ids, orgName, add)
+	if err != nil {
+		return err
+	}
+	removeIDs, err := labelIDs(ids, orgName, remove)
+	if err != nil {
+		return err
+	}
+	for _, id := range addIDs {
+		if err := addLabel(ctx, id); err != nil {
+			return err
+		}
+	}
+	for _, id := range removeIDs {
+		if err := removeLabel(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retentionRules returns retention rules of the desired bucket
+func retentionRules(bucket *Bucket) domain.RetentionRules {
+	every, _ := parseRetention(bucket.Retention)
+	rule := domain.RetentionRule{EverySeconds: every}
+	if shard, _ := parseRetention(bucket.ShardGroupDuration); shard > 0 {
+		rule.ShardGroupDurationSeconds = &shard
+	}
+	return domain.RetentionRules{rule}
+}
+
+func (p *planner) planBucket(org *Organization, bucket *Bucket, e *existingOrg) error {
+	orgKey := resourceKey(KindOrganization, org.Name, "")
+	key := resourceKey(KindBucket, org.Name, bucket.Name)
+	name := org.Name + "/" + bucket.Name
+	bucketsAPI := p.client.BucketsAPI()
+	existing := e.buckets[bucket.Name]
+	var current *domain.Labels
+	if existing != nil {
+		current = existing.Labels
+	}
+	add, remove, err := labelsDiff(org, e, current, bucket.Labels)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		details := describe("description", bucket.Description, "retention", bucket.Retention, "shard group duration", bucket.ShardGroupDuration)
+		p.plan.add(ActionCreate, KindBucket, name, append(details, labelDetails(add, nil)...),
+			func(ctx context.Context, ids map[string]string) (interface{}, error) {
+				orgID := ids[orgKey]
+				created, err := bucketsAPI.CreateBucket(ctx, &domain.Bucket{Name: bucket.Name, OrgID: &orgID,
+					Description: optional(bucket.Description), RetentionRules: retentionRules(bucket)})
+				if err != nil {
+					return nil, err
+				}
+				ids[key] = *created.Id
+				return created, updateLabels(ctx, ids, org.Name, add, nil,
+					func(ctx context.Context, labelID string) error {
+						_, err := bucketsAPI.AddLabelWithID(ctx, *created.Id, labelID)
+						return err
+					}, nil)
+			})
+		return nil
+	}
+	var details []string
+	if value(existing.Description) != bucket.Description {
+		details = append(details, diff("description", value(existing.Description), bucket.Description)...)
+	}
+	var every, shard int64
+	for _, r := range existing.RetentionRules {
+		every = r.EverySeconds
+		if r.ShardGroupDurationSeconds != nil {
+			shard = *r.ShardGroupDurationSeconds
+		}
+	}
+	rules := retentionRules(bucket)
+	if rules[0].EverySeconds != every {
+		details = append(details, diff("retention", formatSeconds(every), formatSeconds(rules[0].EverySeconds))...)
+	}
+	if rules[0].ShardGroupDurationSeconds != nil && *rules[0].ShardGroupDurationSeconds != shard {
+		details = append(details, diff("shard group duration", formatSeconds(shard), formatSeconds(*rules[0].ShardGroupDurationSeconds))...)
+	} else if rules[0].ShardGroupDurationSeconds == nil && shard > 0 && rules[0].EverySeconds == every {
+		// keep shard group duration chosen by the server
+		rules[0].ShardGroupDurationSeconds = &shard
+	}
+	labelChanges := labelDetails(add, remove)
+	if len(details) == 0 && len(labelChanges) == 0 {
+		return nil
+	}
+	update := len(details) > 0
+	p.plan.add(ActionUpdate, KindBucket, name, append(details, labelChanges...),
+		func(ctx context.Context, ids map[string]string) (interface{}, error) {
+			res := existing
+			if update {
+				b := *existing
+				b.Description = optional(bucket.Description)
+				b.RetentionRules = rules
+				var err error
+				if res, err = bucketsAPI.UpdateBucket(ctx, &b); err != nil {
+					return nil, err
+				}
+			}
+			return res, updateLabels(ctx, ids, org.Name, add, remove,
+				func(ctx context.Context, labelID string) error {
+					_, err := bucketsAPI.AddLabelWithID(ctx, *existing.Id, labelID)
+					return err
+				},
+				func(ctx context.Context, labelID string) error {
+					return bucketsAPI.RemoveLabelWithID(ctx, *existing.Id, labelID)
+				})
+		})
+	return nil
+}
+
+// normalizeFlux collapses white space of a Flux script, so that scripts differing only in formatting are equal
+func normalizeFlux(flux string) string {
+	return strings.Join(strings.Fields(flux), " ")
+}
+
+// isZeroOffset returns true for empty or zero duration
+func isZeroOffset(offset string) bool {
+	d, err := schedule.ParseDuration(offset)
+	return offset == "" || (err == nil && d == 0)
+}
+
+func (p *planner) planTask(org *Organization, task *Task, e *existingOrg) error {
+	orgKey := resourceKey(KindOrganization, org.Name, "")
+	key := resourceKey(KindTask, org.Name, task.Name)
+	name := org.Name + "/" + task.Name
+	tasksAPI := p.client.TasksAPI()
+	flux, err := api.SetTaskOptions(task.Flux, task.options())
+	if err != nil {
+		return err
+	}
+	status := task.Status
+	if status == "" {
+		status = domain.TaskStatusTypeActive
+	}
+	existing := e.tasks[task.Name]
+	var current *domain.Labels
+	if existing != nil {
+		current = existing.Labels
+	}
+	add, remove, err := labelsDiff(org, e, current, task.Labels)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		details := describe("every", task.Every, "cron", task.Cron, "offset", task.Offset, "status", string(status))
+		p.plan.add(ActionCreate, KindTask, name, append(details, labelDetails(add, nil)...),
+			func(ctx context.Context, ids map[string]string) (interface{}, error) {
+				created, err := tasksAPI.CreateTask(ctx, &domain.Task{Name: task.Name, OrgID: ids[orgKey], Flux: flux,
+					Every: optional(task.Every), Cron: optional(task.Cron), Description: optional(task.Description), Status: &status})
+				if err != nil {
+					return nil, err
+				}
+				ids[key] = created.Id
+				return created, updateLabels(ctx, ids, org.Name, add, nil,
+					func(ctx context.Context, labelID string) error {
+						_, err := tasksAPI.AddLabelWithID(ctx, created.Id, labelID)
+						return err
+					}, nil)
+			})
+		return nil
+	}
+	var details []string
+	for _, d := range [][3]string{
+		{"description", value(existing.Description), task.Description},
+		{"every", value(existing.Every), task.Every},
+		{"cron", value(existing.Cron), task.Cron},
+		{"status", string(value(existing.Status)), string(status)},
+	} {
+		if d[1] != d[2] {
+			details = append(details, diff(d[0], d[1], d[2])...)
+		}
+	}
+	if !(isZeroOffset(value(existing.Offset)) && isZeroOffset(task.Offset)) && value(existing.Offset) != task.Offset {
+		details = append(details, diff("offset", value(existing.Offset), task.Offset)...)
+	}
+	// compare scripts with the same task option, which reflects the compared properties
+	existingFlux, err := api.SetTaskOptions(existing.Flux, task.options())
+	if err != nil || normalizeFlux(existingFlux) != normalizeFlux(flux) {
+		details = append(details, "flux script changed")
+	}
+	labelChanges := labelDetails(add, remove)
+	if len(details) == 0 && len(labelChanges) == 0 {
+		return nil
+	}
+	update := len(details) > 0
+	p.plan.add(ActionUpdate, KindTask, name, append(details, labelChanges...),
+		func(ctx context.Context, ids map[string]string) (interface{}, error) {
+			res := existing
+			if update {
+				offset := task.Offset
+				if offset == "" {
+					offset = "0s"
+				}
+				t := &domain.Task{Id: existing.Id, Name: task.Name, OrgID: existing.OrgID, Flux: flux, Offset: &offset,
+					Every: optional(task.Every), Cron: optional(task.Cron), Description: optional(task.Description), Status: &status}
+				var err error
+				if res, err = tasksAPI.UpdateTask(ctx, t); err != nil {
+					return nil, err
+				}
+			}
+			return res, updateLabels(ctx, ids, org.Name, add, remove,
+				func(ctx context.Context, labelID string) error {
+					_, err := tasksAPI.AddLabelWithID(ctx, existing.Id, labelID)
+					return err
+				},
+				func(ctx context.Context, labelID string) error {
+					return tasksAPI.RemoveLabelWithID(ctx, existing.Id, labelID)
+				})
+		})
+	return nil
+}
+
+// permissionKinds maps resource types, which can be referenced by name in permissions, to resource kinds
+var permissionKinds = map[domain.ResourceType]Kind{
+	domain.ResourceTypeBuckets: KindBucket,
+	domain.ResourceTypeLabels:  KindLabel,
+	domain.ResourceTypeTasks:   KindTask,
+}
+
+// permissionString returns text representation of a permission, used for comparison and plan output
+func permissionString(action domain.PermissionAction, resourceType domain.ResourceType, name string) string {
+	if name == "" {
+		return fmt.Sprintf("%s %s", action, resourceType)
+	}
+	return fmt.Sprintf("%s %s '%s'", action, resourceType, name)
+}
+
+// resolvePermissions creates permissions of the desired authorization, resolving IDs of named resources
+func resolvePermissions(ids map[string]string, orgName, orgID string, permissions []Permission) ([]domain.Permission, error) {
+	res := make([]domain.Permission, len(permissions))
+	for i, perm := range permissions {
+		id := orgID
+		res[i] = domain.Permission{Action: perm.Action, Resource: domain.Resource{Type: perm.Resource, OrgID: &id}}
+		if perm.Name != "" {
+			resID, ok := ids[resourceKey(permissionKinds[perm.Resource], orgName, perm.Name)]
+			if !ok {
+				return nil, fmt.Errorf("%s '%s' not found", perm.Resource, perm.Name)
+			}
+			res[i].Resource.Id = &resID
+		}
+	}
+	return res, nil
+}
+
+func (p *planner) planAuthorization(ctx context.Context, org *Organization, auth *Authorization, e *existingOrg) error {
+	orgKey := resourceKey(KindOrganization, org.Name, "")
+	name := org.Name + "/" + auth.Description
+	authAPI := p.client.AuthorizationsAPI()
+	var desired []string
+	for _, perm := range auth.Permissions {
+		if perm.Name != "" {
+			kind, ok := permissionKinds[perm.Resource]
+			if !ok {
+				return fmt.Errorf("permission for %s cannot reference a resource by name", perm.Resource)
+			}
+			known := map[Kind]bool{
+				KindBucket: e.buckets[perm.Name] != nil || hasBucket(org, perm.Name),
+				KindLabel:  e.labels[perm.Name] != nil || hasLabel(org, perm.Name),
+				KindTask:   e.tasks[perm.Name] != nil || hasTask(org, perm.Name),
+			}
+			if !known[kind] {
+				return fmt.Errorf("%s '%s' not found", perm.Resource, perm.Name)
+			}
+		}
+		desired = append(desired, permissionString(perm.Action, perm.Resource, perm.Name))
+	}
+	sort.Strings(desired)
+	var userID *string
+	if auth.User != "" {
+		id, err := p.userID(ctx, auth.User)
+		if err != nil {
+			return err
+		}
+		userID = &id
+	}
+	status := auth.Status
+	if status == "" {
+		status = domain.AuthorizationUpdateRequestStatusActive
+	}
+	create := func(ctx context.Context, ids map[string]string) (*domain.Authorization, error) {
+		permissions, err := resolvePermissions(ids, org.Name, ids[orgKey], auth.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		orgID := ids[orgKey]
+		description := auth.Description
+		return authAPI.CreateAuthorization(ctx, &domain.Authorization{
+			AuthorizationUpdateRequest: domain.AuthorizationUpdateRequest{Description: &description, Status: &status},
+			OrgID:                      &orgID,
+			Permissions:                &permissions,
+			UserID:                     userID,
+		})
+	}
+	permDetails := func(prefix string) []string {
+		details := make([]string, len(desired))
+		for i, d := range desired {
+			details[i] = prefix + d
+		}
+		return details
+	}
+	existing := e.auths[auth.Description]
+	if existing == nil {
+		p.plan.add(ActionCreate, KindAuthorization, name, append(describe("user", auth.User, "status", string(status)), permDetails("permission: ")...),
+			func(ctx context.Context, ids map[string]string) (interface{}, error) {
+				return create(ctx, ids)
+			})
+		return nil
+	}
+	var current []string
+	if existing.Permissions != nil {
+		for _, perm := range *existing.Permissions {
+			resName := ""
+			if perm.Resource.Id != nil {
+				resName = value(perm.Resource.Name)
+				if n, ok := e.names[*perm.Resource.Id]; ok {
+					resName = n
+				}
+			}
+			current = append(current, permissionString(perm.Action, perm.Resource.Type, resName))
+		}
+	}
+	sort.Strings(current)
+	var details []string
+	if strings.Join(current, "\n") != strings.Join(desired, "\n") {
+		details = append(details, fmt.Sprintf("permissions: %s -> %s", strings.Join(current, ", "), strings.Join(desired, ", ")))
+	}
+	if userID != nil && value(existing.UserID) != *userID {
+		details = append(details, diff("user", value(existing.User), auth.User)...)
+	}
+	if len(details) > 0 {
+		p.plan.add(ActionReplace, KindAuthorization, name, append(details, "token is recreated"),
+			func(ctx context.Context, ids map[string]string) (interface{}, error) {
+				if err := authAPI.DeleteAuthorizationWithID(ctx, *existing.Id); err != nil {
+					return nil, err
+				}
+				return create(ctx, ids)
+			})
+		return nil
+	}
+	if value(existing.Status) != status {
+		p.plan.add(ActionUpdate, KindAuthorization, name, diff("status", string(value(existing.Status)), string(status)),
+			func(ctx context.Context, ids map[string]string) (interface{}, error) {
+				return authAPI.UpdateAuthorizationStatusWithID(ctx, *existing.Id, status)
+			})
+	}
+	return nil
+}
+
+// hasBucket returns true if the desired organization contains bucket with name
+func hasBucket(org *Organization, name string) bool {
+	for _, b := range org.Buckets {
+		if b.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTask returns true if the desired organization contains task with name
+func hasTask(org *Organization, name string) bool {
+	for _, t := range org.Tasks {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *planner) planMembership(ctx context.Context, org *Organization, orgID string) error {
+	orgsAPI := p.client.OrganizationsAPI()
+	orgKey := resourceKey(KindOrganization, org.Name, "")
+	for _, m := range []struct {
+		kind    Kind
+		desired []string
+		current func() ([]domain.UserResponse, error)
+		add     func(ctx context.Context, orgID, userID string) (interface{}, error)
+		remove  func(ctx context.Context, orgID, userID string) error
+	}{
+		{
+			kind:    KindMember,
+			desired: org.Members,
+			current: func() ([]domain.UserResponse, error) {
+				members, err := orgsAPI.GetMembersWithID(ctx, orgID)
+				if err != nil {
+					return nil, err
+				}
+				users := make([]domain.UserResponse, len(*members))
+				for i, member := range *members {
+					users[i] = member.UserResponse
+				}
+				return users, nil
+			},
+			add: func(ctx context.Context, orgID, userID string) (interface{}, error) {
+				return orgsAPI.AddMemberWithID(ctx, orgID, userID)
+			},
+			remove: orgsAPI.RemoveMemberWithID,
+		},
+		{
+			kind:    KindOwner,
+			desired: org.Owners,
+			current: func() ([]domain.UserResponse, error) {
+				owners, err := orgsAPI.GetOwnersWithID(ctx, orgID)
+				if err != nil {
+					return nil, err
+				}
+				users := make([]domain.UserResponse, len(*owners))
+				for i, owner := range *owners {
+					users[i] = owner.UserResponse
+				}
+				return users, nil
+			},
+			add: func(ctx context.Context, orgID, userID string) (interface{}, error) {
+				return orgsAPI.AddOwnerWithID(ctx, orgID, userID)
+			},
+			remove: orgsAPI.RemoveOwnerWithID,
+		},
+	} {
+		if m.desired == nil {
+			continue
+		}
+		var current []domain.UserResponse
+		if orgID != "" {
+			var err error
+			if current, err = m.current(); err != nil {
+				return err
+			}
+		}
+		has := make(map[string]bool)
+		for _, u := range current {
+			has[u.Name] = true
+		}
+		want := make(map[string]bool)
+		for _, userName := range m.desired {
+			want[userName] = true
+			if has[userName] {
+				continue
+			}
+			userID, err := p.userID(ctx, userName)
+			if err != nil {
+				return err
+			}
+			add := m.add
+			p.plan.add(ActionCreate, m.kind, org.Name+"/"+userName, nil,
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					return add(ctx, ids[orgKey], userID)
+				})
+		}
+		for _, u := range current {
+			if want[u.Name] || u.Id == nil {
+				continue
+			}
+			userID, remove := *u.Id, m.remove
+			p.plan.add(ActionDelete, m.kind, org.Name+"/"+u.Name, nil,
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					return nil, remove(ctx, ids[orgKey], userID)
+				})
+		}
+	}
+	return nil
+}
+
+// planPruneAuthorizations plans deletion of authorizations of the organization, which are not in the desired state.
+// The authorization of the client's token and authorizations with unknown token are kept.
+func (p *planner) planPruneAuthorizations(org *Organization, e *existingOrg) {
+	desired := make(map[string]bool)
+	for _, a := range org.Authorizations {
+		desired[a.Description] = true
+	}
+	for _, name := range sortedKeys(e.auths) {
+		auth := e.auths[name]
+		if desired[name] || auth.Token == nil || *auth.Token == p.token {
+			continue
+		}
+		id := *auth.Id
+		p.plan.add(ActionDelete, KindAuthorization, org.Name+"/"+name, nil,
+			func(ctx context.Context, ids map[string]string) (interface{}, error) {
+				return nil, p.client.AuthorizationsAPI().DeleteAuthorizationWithID(ctx, id)
+			})
+	}
+}
+
+// planPrune plans deletion of labels, buckets and tasks of the organization, which are not in the desired state
+func (p *planner) planPrune(org *Organization, e *existingOrg) {
+	desired := make(map[string]bool)
+	for _, t := range org.Tasks {
+		desired[resourceKey(KindTask, org.Name, t.Name)] = true
+	}
+	for _, b := range org.Buckets {
+		desired[resourceKey(KindBucket, org.Name, b.Name)] = true
+	}
+	for _, l := range org.Labels {
+		desired[resourceKey(KindLabel, org.Name, l.Name)] = true
+	}
+	for _, name := range sortedKeys(e.tasks) {
+		if !desired[resourceKey(KindTask, org.Name, name)] {
+			id := e.tasks[name].Id
+			p.plan.add(ActionDelete, KindTask, org.Name+"/"+name, nil,
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					return nil, p.client.TasksAPI().DeleteTaskWithID(ctx, id)
+				})
+		}
+	}
+	for _, name := range sortedKeys(e.buckets) {
+		b := e.buckets[name]
+		if !desired[resourceKey(KindBucket, org.Name, name)] && (b.Type == nil || *b.Type != domain.BucketTypeSystem) {
+			id := *b.Id
+			p.plan.add(ActionDelete, KindBucket, org.Name+"/"+name, nil,
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					return nil, p.client.BucketsAPI().DeleteBucketWithID(ctx, id)
+				})
+		}
+	}
+	for _, name := range sortedKeys(e.labels) {
+		if !desired[resourceKey(KindLabel, org.Name, name)] {
+			id := *e.labels[name].Id
+			p.plan.add(ActionDelete, KindLabel, org.Name+"/"+name, nil,
+				func(ctx context.Context, ids map[string]string) (interface{}, error) {
+					return nil, p.client.LabelsAPI().DeleteLabelWithID(ctx, id)
+				})
+		}
+	}
+}
+
+// sortedKeys returns sorted keys of the map, so that plans are deterministic
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatSeconds formats duration in seconds for the plan output
+func formatSeconds(seconds int64) string {
+	if seconds == 0 {
+		return ""
+	}
+	for _, u := range []struct {
+		unit    string
+		seconds int64
+	}{{"w", 7 * 86400}, {"d", 86400}, {"h", 3600}, {"m", 60}} {
+		if seconds%u.seconds == 0 {
+			return fmt.Sprintf("%d%s", seconds/u.seconds, u.unit)
+		}
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// describe returns details of a created resource from name and value pairs, skipping empty values
+func describe(pairs ...string) []string {
+	var details []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			details = append(details, fmt.Sprintf("%s: %s", pairs[i], pairs[i+1]))
+		}
+	}
+	return details
+}
+
+// diff returns detail of a changed property
+func diff(name, from, to string) []string {
+	quote := func(s string) string {
+		if s == "" {
+			return "(none)"
+		}
+		return fmt.Sprintf("'%s'", s)
+	}
+	return []string{fmt.Sprintf("%s: %s -> %s", name, quote(from), quote(to))}
+}
+
+// optional returns pointer to s or nil for empty string
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// value returns value of the pointer or zero value for nil
+func value[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
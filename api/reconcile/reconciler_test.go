@@ -0,0 +1,487 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// fakeClient simulates server resources, it records calls changing resources
+type fakeClient struct {
+	orgs    []domain.Organization
+	users   []domain.User
+	members []domain.ResourceMember
+	labels  []domain.Label
+	buckets []domain.Bucket
+	tasks   []domain.Task
+	auths   []domain.Authorization
+	calls   []string
+	ids     int
+}
+
+func (f *fakeClient) OrganizationsAPI() api.OrganizationsAPI   { return &fakeOrgs{fakeClient: f} }
+func (f *fakeClient) UsersAPI() api.UsersAPI                   { return &fakeUsers{fakeClient: f} }
+func (f *fakeClient) LabelsAPI() api.LabelsAPI                 { return &fakeLabels{fakeClient: f} }
+func (f *fakeClient) BucketsAPI() api.BucketsAPI               { return &fakeBuckets{fakeClient: f} }
+func (f *fakeClient) TasksAPI() api.TasksAPI                   { return &fakeTasks{fakeClient: f} }
+func (f *fakeClient) AuthorizationsAPI() api.AuthorizationsAPI { return &fakeAuths{fakeClient: f} }
+
+func (f *fakeClient) call(format string, args ...interface{}) {
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeClient) newID() *string {
+	f.ids++
+	return ptr(fmt.Sprintf("new%d", f.ids))
+}
+
+func seq[T any](items []T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// fakeOrgs implements only methods used by reconciler, the embedded API is nil
+type fakeOrgs struct {
+	api.OrganizationsAPI
+	*fakeClient
+}
+
+func (f *fakeOrgs) IterateOrganizations(_ context.Context, _ ...api.PagingOption) iter.Seq2[domain.Organization, error] {
+	return seq(f.orgs)
+}
+
+func (f *fakeOrgs) CreateOrganization(_ context.Context, org *domain.Organization) (*domain.Organization, error) {
+	f.call("CreateOrganization %s", org.Name)
+	org.Id = f.newID()
+	return org, nil
+}
+
+func (f *fakeOrgs) UpdateOrganization(_ context.Context, org *domain.Organization) (*domain.Organization, error) {
+	f.call("UpdateOrganization %s %s", *org.Id, *org.Description)
+	return org, nil
+}
+
+func (f *fakeOrgs) GetMembersWithID(_ context.Context, _ string) (*[]domain.ResourceMember, error) {
+	return &f.members, nil
+}
+
+func (f *fakeOrgs) GetOwnersWithID(_ context.Context, _ string) (*[]domain.ResourceOwner, error) {
+	return &[]domain.ResourceOwner{}, nil
+}
+
+func (f *fakeOrgs) AddMemberWithID(_ context.Context, orgID, memberID string) (*domain.ResourceMember, error) {
+	f.call("AddMember %s %s", orgID, memberID)
+	return &domain.ResourceMember{}, nil
+}
+
+func (f *fakeOrgs) RemoveMemberWithID(_ context.Context, orgID, memberID string) error {
+	f.call("RemoveMember %s %s", orgID, memberID)
+	return nil
+}
+
+func (f *fakeOrgs) AddOwnerWithID(_ context.Context, orgID, memberID string) (*domain.ResourceOwner, error) {
+	f.call("AddOwner %s %s", orgID, memberID)
+	return &domain.ResourceOwner{}, nil
+}
+
+// fakeUsers implements only methods used by reconciler, the embedded API is nil
+type fakeUsers struct {
+	api.UsersAPI
+	*fakeClient
+}
+
+func (f *fakeUsers) IterateUsers(_ context.Context, _ ...api.PagingOption) iter.Seq2[domain.User, error] {
+	return seq(f.users)
+}
+
+// fakeLabels implements only methods used by reconciler, the embedded API is nil
+type fakeLabels struct {
+	api.LabelsAPI
+	*fakeClient
+}
+
+func (f *fakeLabels) FindLabelsByOrgID(_ context.Context, orgID string) (*[]domain.Label, error) {
+	var labels []domain.Label
+	for _, l := range f.labels {
+		if *l.OrgID == orgID {
+			labels = append(labels, l)
+		}
+	}
+	return &labels, nil
+}
+
+func (f *fakeLabels) CreateLabelWithNameWithID(_ context.Context, orgID, labelName string, properties map[string]string) (*domain.Label, error) {
+	f.call("CreateLabel %s %s %v", orgID, labelName, properties)
+	return &domain.Label{Id: f.newID(), Name: &labelName}, nil
+}
+
+func (f *fakeLabels) UpdateLabel(_ context.Context, label *domain.Label) (*domain.Label, error) {
+	f.call("UpdateLabel %s %v", *label.Id, label.Properties.AdditionalProperties)
+	return label, nil
+}
+
+func (f *fakeLabels) DeleteLabelWithID(_ context.Context, labelID string) error {
+	f.call("DeleteLabel %s", labelID)
+	return nil
+}
+
+// fakeBuckets implements only methods used by reconciler, the embedded API is nil
+type fakeBuckets struct {
+	api.BucketsAPI
+	*fakeClient
+}
+
+func (f *fakeBuckets) IterateBuckets(_ context.Context, _ ...api.PagingOption) iter.Seq2[domain.Bucket, error] {
+	return seq(f.buckets)
+}
+
+func (f *fakeBuckets) CreateBucket(_ context.Context, bucket *domain.Bucket) (*domain.Bucket, error) {
+	f.call("CreateBucket %s %s %d", *bucket.OrgID, bucket.Name, bucket.RetentionRules[0].EverySeconds)
+	bucket.Id = f.newID()
+	return bucket, nil
+}
+
+func (f *fakeBuckets) UpdateBucket(_ context.Context, bucket *domain.Bucket) (*domain.Bucket, error) {
+	f.call("UpdateBucket %s %d %v", *bucket.Id, bucket.RetentionRules[0].EverySeconds, value(bucket.RetentionRules[0].ShardGroupDurationSeconds))
+	return bucket, nil
+}
+
+func (f *fakeBuckets) DeleteBucketWithID(_ context.Context, bucketID string) error {
+	f.call("DeleteBucket %s", bucketID)
+	return nil
+}
+
+func (f *fakeBuckets) AddLabelWithID(_ context.Context, bucketID, labelID string) (*domain.Label, error) {
+	f.call("AddBucketLabel %s %s", bucketID, labelID)
+	return &domain.Label{}, nil
+}
+
+func (f *fakeBuckets) RemoveLabelWithID(_ context.Context, bucketID, labelID string) error {
+	f.call("RemoveBucketLabel %s %s", bucketID, labelID)
+	return nil
+}
+
+// fakeTasks implements only methods used by reconciler, the embedded API is nil
+type fakeTasks struct {
+	api.TasksAPI
+	*fakeClient
+}
+
+func (f *fakeTasks) IterateTasks(_ context.Context, filter *api.TaskFilter) iter.Seq2[domain.Task, error] {
+	var tasks []domain.Task
+	for _, t := range f.tasks {
+		if t.OrgID == filter.OrgID {
+			tasks = append(tasks, t)
+		}
+	}
+	return seq(tasks)
+}
+
+func (f *fakeTasks) CreateTask(_ context.Context, task *domain.Task) (*domain.Task, error) {
+	f.call("CreateTask %s %s %q", task.OrgID, task.Name, task.Flux)
+	task.Id = *f.newID()
+	return task, nil
+}
+
+func (f *fakeTasks) UpdateTask(_ context.Context, task *domain.Task) (*domain.Task, error) {
+	f.call("UpdateTask %s %q", task.Id, task.Flux)
+	return task, nil
+}
+
+func (f *fakeTasks) DeleteTaskWithID(_ context.Context, taskID string) error {
+	f.call("DeleteTask %s", taskID)
+	return nil
+}
+
+func (f *fakeTasks) AddLabelWithID(_ context.Context, taskID, labelID string) (*domain.Label, error) {
+	f.call("AddTaskLabel %s %s", taskID, labelID)
+	return &domain.Label{}, nil
+}
+
+// fakeAuths implements only methods used by reconciler, the embedded API is nil
+type fakeAuths struct {
+	api.AuthorizationsAPI
+	*fakeClient
+}
+
+func (f *fakeAuths) FindAuthorizationsByOrgID(_ context.Context, _ string) (*[]domain.Authorization, error) {
+	return &f.auths, nil
+}
+
+func (f *fakeAuths) CreateAuthorization(_ context.Context, auth *domain.Authorization) (*domain.Authorization, error) {
+	perms := ""
+	for _, p := range *auth.Permissions {
+		perms += fmt.Sprintf(" %s:%s:%s", p.Action, p.Resource.Type, value(p.Resource.Id))
+	}
+	f.call("CreateAuthorization %s %s%s", *auth.OrgID, *auth.Description, perms)
+	auth.Id = f.newID()
+	auth.Token = ptr("token")
+	return auth, nil
+}
+
+func (f *fakeAuths) DeleteAuthorizationWithID(_ context.Context, authID string) error {
+	f.call("DeleteAuthorization %s", authID)
+	return nil
+}
+
+func (f *fakeAuths) UpdateAuthorizationStatusWithID(_ context.Context, authID string, status domain.AuthorizationUpdateRequestStatus) (*domain.Authorization, error) {
+	f.call("UpdateAuthorizationStatus %s %s", authID, status)
+	return &domain.Authorization{}, nil
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		orgs: []domain.Organization{
+			{Id: ptr("o1"), Name: "my-org", Description: ptr("old")},
+			{Id: ptr("o2"), Name: "other-org"},
+		},
+		users: []domain.User{{Id: ptr("u1"), Name: "alice"}, {Id: ptr("u2"), Name: "bob"}},
+		members: []domain.ResourceMember{
+			{UserResponse: domain.UserResponse{Id: ptr("u1"), Name: "alice"}},
+		},
+		labels: []domain.Label{
+			{Id: ptr("l1"), Name: ptr("critical"), OrgID: ptr("o1"), Properties: &domain.Label_Properties{AdditionalProperties: map[string]string{"color": "ff0000"}}},
+			{Id: ptr("l2"), Name: ptr("obsolete"), OrgID: ptr("o1")},
+		},
+		buckets: []domain.Bucket{
+			{Id: ptr("b1"), Name: "metrics", OrgID: ptr("o1"), Labels: &domain.Labels{{Id: ptr("l1"), Name: ptr("critical")}},
+				RetentionRules: domain.RetentionRules{{EverySeconds: 7 * 86400, ShardGroupDurationSeconds: ptr(int64(86400))}}},
+			{Id: ptr("b2"), Name: "_monitoring", OrgID: ptr("o1"), Type: ptr(domain.BucketTypeSystem)},
+			{Id: ptr("b3"), Name: "old-bucket", OrgID: ptr("o1")},
+			{Id: ptr("b4"), Name: "other-bucket", OrgID: ptr("o2")},
+		},
+		tasks: []domain.Task{
+			{Id: "t1", Name: "downsample", OrgID: "o1", Every: ptr("1h"), Offset: ptr("0s"), Status: ptr(domain.TaskStatusTypeActive),
+				Flux: "option task = {name: \"downsample\", every: 1h}\n\nfrom(bucket: \"metrics\")"},
+			{Id: "t2", Name: "same", OrgID: "o1", Cron: ptr("0 * * * *"), Status: ptr(domain.TaskStatusTypeActive),
+				Flux: "option task = {name: \"same\", cron: \"0 * * * *\"}\nfrom(bucket: \"raw\")\n  |> count()"},
+		},
+		auths: []domain.Authorization{
+			{Id: ptr("a1"), AuthorizationUpdateRequest: domain.AuthorizationUpdateRequest{Description: ptr("telegraf"), Status: ptr(domain.AuthorizationUpdateRequestStatusActive)},
+				Permissions: &[]domain.Permission{{Action: domain.PermissionActionWrite, Resource: domain.Resource{Type: domain.ResourceTypeBuckets, Id: ptr("b1")}}}},
+			{Id: ptr("a2"), AuthorizationUpdateRequest: domain.AuthorizationUpdateRequest{Description: ptr("reader"), Status: ptr(domain.AuthorizationUpdateRequestStatusActive)},
+				Permissions: &[]domain.Permission{{Action: domain.PermissionActionRead, Resource: domain.Resource{Type: domain.ResourceTypeBuckets}}}},
+		},
+	}
+}
+
+const testState = `
+orgs:
+  - name: my-org
+    description: new
+    members: [bob]
+    labels:
+      - name: critical
+        color: 0000ff
+      - name: new-label
+    buckets:
+      - name: metrics
+        retention: 30d
+        labels: [critical, new-label]
+      - name: raw
+        retention: 1d
+    tasks:
+      - name: downsample
+        every: 1h
+        flux: |
+          from(bucket: "metrics")
+            |> mean()
+      - name: same
+        cron: "0 * * * *"
+        flux: 'from(bucket: "raw") |> count()' 
+      - name: hourly
+        cron: "0 * * * *"
+        flux: 'from(bucket: "raw")'
+        labels: [new-label]
+    authorizations:
+      - description: telegraf
+        permissions:
+          - action: write
+            resource: buckets
+            name: metrics
+          - action: write
+            resource: buckets
+            name: raw
+      - description: reader
+        status: inactive
+        permissions:
+          - action: read
+            resource: buckets
+  - name: new-org
+    owners: [alice]
+    buckets:
+      - name: data
+`
+
+func TestReconciler(t *testing.T) {
+	state, err := ParseState([]byte(testState))
+	require.NoError(t, err)
+	client := newFakeClient()
+	reconciler := NewReconciler(client)
+
+	plan, err := reconciler.Plan(context.Background(), state, PlanWithPrune(true))
+	require.NoError(t, err)
+	assert.Equal(t, `~ update organization 'my-org'
+    description: 'old' -> 'new'
+~ update label 'my-org/critical'
+    color: 'ff0000' -> '0000ff'
++ create label 'my-org/new-label'
+~ update bucket 'my-org/metrics'
+    retention: '1w' -> '30d'
+    add label 'new-label'
++ create bucket 'my-org/raw'
+    retention: 1d
+~ update task 'my-org/downsample'
+    flux script changed
++ create task 'my-org/hourly'
+    cron: 0 * * * *
+    status: active
+    add label 'new-label'
+-/+ replace authorization 'my-org/telegraf'
+    permissions: write buckets 'metrics' -> write buckets 'metrics', write buckets 'raw'
+    token is recreated
+~ update authorization 'my-org/reader'
+    status: 'active' -> 'inactive'
++ create member 'my-org/bob'
+- delete member 'my-org/alice'
+- delete bucket 'my-org/old-bucket'
+- delete label 'my-org/obsolete'
++ create organization 'new-org'
++ create bucket 'new-org/data'
++ create owner 'new-org/alice'`, plan.String())
+	assert.Len(t, client.calls, 0)
+
+	require.NoError(t, reconciler.Apply(context.Background(), plan))
+	assert.Equal(t, []string{
+		"UpdateOrganization o1 new",
+		"UpdateLabel l1 map[color:0000ff]",
+		"CreateLabel o1 new-label map[]",
+		"UpdateBucket b1 2592000 0",
+		"AddBucketLabel b1 new1",
+		"CreateBucket o1 raw 86400",
+		`UpdateTask t1 "option task = {name: \"downsample\", every: 1h}\nfrom(bucket: \"metrics\")\n  |> mean()\n"`,
+		`CreateTask o1 hourly "option task = {name: \"hourly\", cron: \"0 * * * *\"}\nfrom(bucket: \"raw\")"`,
+		"AddTaskLabel new3 new1",
+		"DeleteAuthorization a1",
+		"CreateAuthorization o1 telegraf write:buckets:b1 write:buckets:new2",
+		"UpdateAuthorizationStatus a2 inactive",
+		"AddMember o1 u2",
+		"RemoveMember o1 u1",
+		"DeleteBucket b3",
+		"DeleteLabel l2",
+		"CreateOrganization new-org",
+		"CreateBucket new5 data 0",
+		"AddOwner new5 u1",
+	}, client.calls)
+	for _, c := range plan.Changes {
+		assert.True(t, c.Applied)
+	}
+	auth, ok := plan.Changes[7].Resource.(*domain.Authorization)
+	require.True(t, ok)
+	assert.Equal(t, "token", *auth.Token)
+
+	// applied plan is not applied again
+	client.calls = client.calls[:0]
+	require.NoError(t, reconciler.Apply(context.Background(), plan))
+	assert.Len(t, client.calls, 0)
+
+	// without pruning, resources not in the state are kept
+	plan, err = reconciler.Plan(context.Background(), &State{Orgs: []Organization{{Name: "my-org", Description: "old"}}})
+	require.NoError(t, err)
+	assert.False(t, plan.HasChanges())
+	assert.Equal(t, "No changes", plan.String())
+}
+
+// clientWithToken is fakeClient with HTTP service authorized by a token
+type clientWithToken struct {
+	*fakeClient
+	service http.Service
+}
+
+func (c *clientWithToken) HTTPService() http.Service { return c.service }
+
+func TestReconciler_PruneAuthorizations(t *testing.T) {
+	fake := newFakeClient()
+	fake.auths = []domain.Authorization{
+		{Id: ptr("a1"), Token: ptr("other-token"), AuthorizationUpdateRequest: domain.AuthorizationUpdateRequest{Description: ptr("telegraf")}},
+		{Id: ptr("a2"), Token: ptr("my-token"), AuthorizationUpdateRequest: domain.AuthorizationUpdateRequest{Description: ptr("admin")}},
+		{Id: ptr("a3"), AuthorizationUpdateRequest: domain.AuthorizationUpdateRequest{Description: ptr("hidden")}},
+	}
+	client := &clientWithToken{fakeClient: fake, service: http.NewService("http://localhost:8086/", "Token my-token", http.DefaultOptions())}
+	reconciler := NewReconciler(client)
+	state := &State{Orgs: []Organization{{Name: "my-org", Description: "old"}}}
+
+	// general pruning keeps authorizations
+	plan, err := reconciler.Plan(context.Background(), state, PlanWithPrune(true))
+	require.NoError(t, err)
+	assert.Equal(t, `- delete task 'my-org/downsample'
+- delete task 'my-org/same'
+- delete bucket 'my-org/metrics'
+- delete bucket 'my-org/old-bucket'
+- delete label 'my-org/critical'
+- delete label 'my-org/obsolete'`, plan.String())
+
+	// the authorization of the client's token and authorizations with unknown token are kept
+	plan, err = reconciler.Plan(context.Background(), state, PlanWithPruneAuthorizations(true))
+	require.NoError(t, err)
+	assert.Equal(t, "- delete authorization 'my-org/telegraf'", plan.String())
+	require.NoError(t, reconciler.Apply(context.Background(), plan))
+	assert.Equal(t, []string{"DeleteAuthorization a1"}, fake.calls)
+}
+
+func TestReconciler_Errors(t *testing.T) {
+	reconciler := NewReconciler(newFakeClient())
+	for state, msg := range map[string]string{
+		"orgs:\n  - name: my-org\n    buckets:\n      - name: b\n        labels: [missing]": "organization 'my-org': bucket 'b': label 'missing' not found",
+		"orgs:\n  - name: my-org\n    members: [carol]":                                     "organization 'my-org': user 'carol' not found",
+		"orgs:\n  - name: my-org\n    authorizations:\n      - description: a\n        permissions:\n          - action: read\n            resource: buckets\n            name: missing": "organization 'my-org': authorization 'a': buckets 'missing' not found",
+		"orgs:\n  - name: my-org\n    authorizations:\n      - description: a\n        permissions:\n          - action: read\n            resource: orgs\n            name: my-org":     "organization 'my-org': authorization 'a': permission for orgs cannot reference a resource by name",
+	} {
+		s, err := ParseState([]byte(state))
+		require.NoError(t, err)
+		_, err = reconciler.Plan(context.Background(), s)
+		assert.EqualError(t, err, msg)
+	}
+}
+
+func TestParseState(t *testing.T) {
+	state, err := ParseState([]byte(`{"orgs": [{"name": "o", "buckets": [{"name": "b", "retention": "1w", "shardGroupDuration": "1d"}]}]}`))
+	require.NoError(t, err)
+	require.Len(t, state.Orgs, 1)
+	assert.Equal(t, Bucket{Name: "b", Retention: "1w", ShardGroupDuration: "1d"}, state.Orgs[0].Buckets[0])
+
+	for doc, msg := range map[string]string{
+		"orgs:\n  - name: o\n    unknown: 1":                                                         "invalid state document: yaml: unmarshal errors:\n  line 3: field unknown not found in type reconcile.Organization",
+		"orgs:\n  - description: d":                                                                  "organization name is required",
+		"orgs:\n  - name: o\n  - name: o":                                                            "duplicate organization 'o'",
+		"orgs:\n  - name: o\n    buckets:\n      - name: b\n        retention: 1mo":                  "organization 'o': bucket 'b': invalid retention: duration '1mo' uses calendar unit 'mo', which is not supported",
+		"orgs:\n  - name: o\n    labels:\n      - name: l\n      - name: l":                          "organization 'o': duplicate label 'l'",
+		"orgs:\n  - name: o\n    tasks:\n      - name: t\n        flux: f":                           "organization 'o': task 't': task option requires either every or cron",
+		"orgs:\n  - name: o\n    tasks:\n      - name: t\n        every: 1h\n        status: paused": "organization 'o': task 't': invalid status 'paused'",
+		"orgs:\n  - name: o\n    authorizations:\n      - description: a":                            "organization 'o': authorization 'a': permissions are required",
+		"orgs:\n  - name: o\n    authorizations:\n      - description: a\n        permissions:\n          - action: delete\n            resource: buckets": "organization 'o': authorization 'a': invalid permission action 'delete'",
+	} {
+		_, err := ParseState([]byte(doc))
+		assert.EqualError(t, err, msg, doc)
+	}
+}
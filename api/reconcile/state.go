@@ -0,0 +1,221 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+// Package reconcile provides declarative management of InfluxDB resources.
+// A desired state document describes organizations with their buckets, labels, tasks, authorizations and membership.
+// Reconciler compares the desired state with the server and creates a Plan of changes, which can be printed (dry-run) and applied.
+package reconcile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/influxdata/influxdb-client-go/v2/internal/schedule"
+	"gopkg.in/yaml.v3"
+)
+
+// State is the desired state of InfluxDB resources
+type State struct {
+	// Orgs lists managed organizations. Organizations not listed are left untouched.
+	Orgs []Organization `json:"orgs" yaml:"orgs"`
+}
+
+// Organization is the desired state of an organization and its resources
+type Organization struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Members lists names of users, which are members of the organization.
+	// When nil, members are not managed. Otherwise, users not listed are removed from members.
+	Members []string `json:"members,omitempty" yaml:"members,omitempty"`
+	// Owners lists names of users, which are owners of the organization.
+	// When nil, owners are not managed. Otherwise, users not listed are removed from owners.
+	Owners         []string        `json:"owners,omitempty" yaml:"owners,omitempty"`
+	Labels         []Label         `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Buckets        []Bucket        `json:"buckets,omitempty" yaml:"buckets,omitempty"`
+	Tasks          []Task          `json:"tasks,omitempty" yaml:"tasks,omitempty"`
+	Authorizations []Authorization `json:"authorizations,omitempty" yaml:"authorizations,omitempty"`
+}
+
+// Label is the desired state of a label
+type Label struct {
+	Name        string `json:"name" yaml:"name"`
+	Color       string `json:"color,omitempty" yaml:"color,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Bucket is the desired state of a bucket
+type Bucket struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Retention is a duration, e.g. 30d, after which data expire. Empty means infinite retention.
+	Retention string `json:"retention,omitempty" yaml:"retention,omitempty"`
+	// ShardGroupDuration is a duration of shard groups, e.g. 1d. When empty, the server default is used.
+	ShardGroupDuration string `json:"shardGroupDuration,omitempty" yaml:"shardGroupDuration,omitempty"`
+	// Labels lists names of labels of the bucket. When nil, labels are not managed.
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Task is the desired state of a task
+type Task struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Every is a duration of the task schedule, e.g. 1h. Every and Cron are mutually exclusive.
+	Every string `json:"every,omitempty" yaml:"every,omitempty"`
+	// Cron is a cron expression of the task schedule. Every and Cron are mutually exclusive.
+	Cron   string `json:"cron,omitempty" yaml:"cron,omitempty"`
+	Offset string `json:"offset,omitempty" yaml:"offset,omitempty"`
+	// Status is either active or inactive. Default is active.
+	Status domain.TaskStatusType `json:"status,omitempty" yaml:"status,omitempty"`
+	// Flux is the task script. Its task option is set from Name, Every, Cron and Offset.
+	Flux string `json:"flux" yaml:"flux"`
+	// Labels lists names of labels of the task. When nil, labels are not managed.
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Authorization is the desired state of an authorization
+type Authorization struct {
+	// Description identifies the authorization within the organization
+	Description string `json:"description" yaml:"description"`
+	// User is the name of the user the authorization is created for. When empty, the user of the client is used.
+	User string `json:"user,omitempty" yaml:"user,omitempty"`
+	// Status is either active or inactive. Default is active.
+	Status      domain.AuthorizationUpdateRequestStatus `json:"status,omitempty" yaml:"status,omitempty"`
+	Permissions []Permission                            `json:"permissions" yaml:"permissions"`
+}
+
+// Permission is an action allowed on resources
+type Permission struct {
+	// Action is either read or write
+	Action domain.PermissionAction `json:"action" yaml:"action"`
+	// Resource is the type of resources, e.g. buckets
+	Resource domain.ResourceType `json:"resource" yaml:"resource"`
+	// Name of the resource within the organization. When empty, the permission applies to all resources of the type.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// ParseState parses desired state from a YAML or JSON document and validates it
+func ParseState(data []byte) (*State, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	state := &State{}
+	if err := decoder.Decode(state); err != nil {
+		return nil, fmt.Errorf("invalid state document: %w", err)
+	}
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// ReadStateFile reads desired state from a YAML or JSON file
+func ReadStateFile(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseState(data)
+}
+
+// Validate checks the desired state is complete and consistent
+func (s *State) Validate() error {
+	orgs := make(map[string]bool)
+	for _, org := range s.Orgs {
+		if org.Name == "" {
+			return errors.New("organization name is required")
+		}
+		if orgs[org.Name] {
+			return fmt.Errorf("duplicate organization '%s'", org.Name)
+		}
+		orgs[org.Name] = true
+		if err := org.validate(); err != nil {
+			return fmt.Errorf("organization '%s': %w", org.Name, err)
+		}
+	}
+	return nil
+}
+
+func (o *Organization) validate() error {
+	names := make(map[string]bool)
+	unique := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s name is required", kind)
+		}
+		if names[kind+"/"+name] {
+			return fmt.Errorf("duplicate %s '%s'", kind, name)
+		}
+		names[kind+"/"+name] = true
+		return nil
+	}
+	for _, l := range o.Labels {
+		if err := unique("label", l.Name); err != nil {
+			return err
+		}
+	}
+	for _, b := range o.Buckets {
+		if err := unique("bucket", b.Name); err != nil {
+			return err
+		}
+		if _, err := parseRetention(b.Retention); err != nil {
+			return fmt.Errorf("bucket '%s': invalid retention: %w", b.Name, err)
+		}
+		if _, err := parseRetention(b.ShardGroupDuration); err != nil {
+			return fmt.Errorf("bucket '%s': invalid shard group duration: %w", b.Name, err)
+		}
+	}
+	for _, t := range o.Tasks {
+		if err := unique("task", t.Name); err != nil {
+			return err
+		}
+		if err := t.options().Validate(); err != nil {
+			return fmt.Errorf("task '%s': %w", t.Name, err)
+		}
+		if t.Status != "" && t.Status != domain.TaskStatusTypeActive && t.Status != domain.TaskStatusTypeInactive {
+			return fmt.Errorf("task '%s': invalid status '%s'", t.Name, t.Status)
+		}
+	}
+	for _, a := range o.Authorizations {
+		if a.Description == "" {
+			return errors.New("authorization description is required")
+		}
+		if err := unique("authorization", a.Description); err != nil {
+			return err
+		}
+		if len(a.Permissions) == 0 {
+			return fmt.Errorf("authorization '%s': permissions are required", a.Description)
+		}
+		if a.Status != "" && a.Status != domain.AuthorizationUpdateRequestStatusActive && a.Status != domain.AuthorizationUpdateRequestStatusInactive {
+			return fmt.Errorf("authorization '%s': invalid status '%s'", a.Description, a.Status)
+		}
+		for _, p := range a.Permissions {
+			if p.Action != domain.PermissionActionRead && p.Action != domain.PermissionActionWrite {
+				return fmt.Errorf("authorization '%s': invalid permission action '%s'", a.Description, p.Action)
+			}
+			if p.Resource == "" {
+				return fmt.Errorf("authorization '%s': permission resource is required", a.Description)
+			}
+		}
+	}
+	return nil
+}
+
+// options returns task option of the task
+func (t *Task) options() *api.TaskOptions {
+	return &api.TaskOptions{Name: t.Name, Every: t.Every, Cron: t.Cron, Offset: t.Offset}
+}
+
+// parseRetention parses duration in seconds, empty string or 0 mean no duration
+func parseRetention(d string) (int64, error) {
+	if d == "" || d == "0" {
+		return 0, nil
+	}
+	duration, err := schedule.ParseDuration(d)
+	if err != nil {
+		return 0, err
+	}
+	return int64(duration.Seconds()), nil
+}
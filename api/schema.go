@@ -0,0 +1,48 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+)
+
+// MeasurementSchemaToSchema converts a measurement schema of a bucket into a write.MeasurementSchema.
+// Columns without a data type are considered float fields.
+func MeasurementSchemaToSchema(schema *domain.MeasurementSchema) *write.MeasurementSchema {
+	s := write.NewMeasurementSchema(schema.Name)
+	for _, c := range schema.Columns {
+		switch c.Type {
+		case domain.ColumnSemanticTypeTag:
+			s.AddTag(c.Name)
+		case domain.ColumnSemanticTypeField:
+			fieldType := write.FieldTypeFloat
+			if c.DataType != nil {
+				fieldType = write.FieldType(*c.DataType)
+			}
+			s.AddField(c.Name, fieldType)
+		}
+	}
+	return s
+}
+
+// RegisterBucketSchemas fetches measurement schemas of a bucket with explicit schema type and registers them in registry.
+// Registry should be set to write.Options used for writing to the bucket.
+func RegisterBucketSchemas(ctx context.Context, bucketsAPI BucketsAPI, bucket *domain.Bucket, registry *write.SchemaRegistry) error {
+	if bucket.SchemaType == nil || *bucket.SchemaType != domain.SchemaTypeExplicit {
+		return fmt.Errorf("bucket '%s' doesn't have explicit schema type", bucket.Name)
+	}
+	schemas, err := bucketsAPI.FindMeasurementSchemas(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	for i := range schemas {
+		registry.Register(MeasurementSchemaToSchema(&schemas[i]))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/influxdata/influxdb-client-go/v2/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterBucketSchemas(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/buckets/{id}/schema/measurements", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "b1", r.PathValue("id"))
+		assert.Equal(t, "o1", r.URL.Query().Get("orgID"))
+		writeJSON(w, http.StatusOK, `{"measurementSchemas":[{"id":"s1","name":"cpu","createdAt":"2021-01-01T00:00:00Z","updatedAt":"2021-01-01T00:00:00Z","columns":[
+			{"name":"time","type":"timestamp"},
+			{"name":"host","type":"tag"},
+			{"name":"usage","type":"field","dataType":"float"},
+			{"name":"count","type":"field","dataType":"integer"}]}]}`)
+	})
+	bucketsAPI := NewBucketsAPI(newTestAPIClient(t, mux))
+	ctx := context.Background()
+	bucketID, orgID := "b1", "o1"
+	bucket := &domain.Bucket{Id: &bucketID, OrgID: &orgID, Name: "my-bucket"}
+
+	registry := write.NewSchemaRegistry()
+	err := RegisterBucketSchemas(ctx, bucketsAPI, bucket, registry)
+	assert.EqualError(t, err, "bucket 'my-bucket' doesn't have explicit schema type")
+
+	schemaType := domain.SchemaTypeExplicit
+	bucket.SchemaType = &schemaType
+	require.NoError(t, RegisterBucketSchemas(ctx, bucketsAPI, bucket, registry))
+	schema := registry.Schema("cpu")
+	require.NotNil(t, schema)
+	assert.True(t, schema.Explicit())
+	assert.True(t, schema.HasTag("host"))
+	assert.False(t, schema.HasTag("time"))
+	fieldType, _ := schema.FieldType("count")
+	assert.Equal(t, write.FieldTypeInteger, fieldType)
+
+	// points are validated before writing
+	service := test.NewTestService(t, "http://localhost:8888")
+	opts := write.DefaultOptions().SetSchemaRegistry(registry)
+	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, opts)
+	err = writeAPI.WritePoint(ctx, write.NewPointWithMeasurement("cpu").AddTag("host", "h1").AddField("usage", 1.5).AddField("count", 1.0))
+	var schemaErr *write.SchemaError
+	require.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, "count", schemaErr.Column)
+	assert.Len(t, service.Lines(), 0)
+
+	opts.SetCoerceToSchema(true)
+	err = writeAPI.WritePoint(ctx, write.NewPointWithMeasurement("cpu").AddTag("host", "h1").AddField("usage", 1.5).AddField("count", 1.0))
+	require.NoError(t, err)
+	require.Len(t, service.Lines(), 1)
+	assert.Equal(t, "cpu,host=h1 usage=1.5,count=1i", service.Lines()[0])
+}
+
+func TestWriteAPIImpl_SchemaErrorsNotRead(t *testing.T) {
+	registry := write.NewSchemaRegistry().Register(write.NewMeasurementSchema("cpu").AddField("usage", write.FieldTypeFloat))
+	service := test.NewTestService(t, "http://localhost:8888")
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetSchemaRegistry(registry))
+	// errors channel is obtained, but not read
+	_ = writeAPI.Errors()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			writeAPI.WritePoint(write.NewPointWithMeasurement("cpu").AddField("usage", "high"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "invalid points block writing")
+	}
+	writeAPI.Close()
+	assert.Len(t, service.Lines(), 0)
+}
@@ -0,0 +1,535 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/influxdb-client-go/v2/internal/schedule"
+)
+
+// TaskOptions holds properties of the task option record of a Flux task script,
+// e.g. option task = {name: "downsample", every: 1h, offset: 5m}
+type TaskOptions struct {
+	// Name of the task
+	Name string
+	// Every is a Flux duration literal defining the interval of task runs. Every and Cron are mutually exclusive.
+	Every string
+	// Cron is a cron expression defining the schedule of task runs. Every and Cron are mutually exclusive.
+	Cron string
+	// Offset is a Flux duration literal delaying the execution of task runs
+	Offset string
+	// Concurrency is the maximum number of task runs executed at the same time. Nil if not set.
+	Concurrency *int
+	// Retry is the number of times a failed task run is retried. Nil if not set.
+	Retry *int
+}
+
+// Validate checks the task options are complete and consistent, the way the server does when a task is created.
+func (o *TaskOptions) Validate() error {
+	if o.Name == "" {
+		return errors.New("task option name is required")
+	}
+	if o.Every != "" && o.Cron != "" {
+		return fmt.Errorf("task option every '%s' conflicts with cron '%s'", o.Every, o.Cron)
+	}
+	if o.Every == "" && o.Cron == "" {
+		return errors.New("task option requires either every or cron")
+	}
+	if o.Every != "" {
+		if strings.HasPrefix(o.Every, "-") {
+			return fmt.Errorf("task option every '%s' must be positive", o.Every)
+		}
+		if err := schedule.ValidateDuration(o.Every); err != nil {
+			return fmt.Errorf("invalid task option every: %w", err)
+		}
+	}
+	if o.Offset != "" {
+		if err := schedule.ValidateDuration(o.Offset); err != nil {
+			return fmt.Errorf("invalid task option offset: %w", err)
+		}
+	}
+	if o.Concurrency != nil && *o.Concurrency < 1 {
+		return fmt.Errorf("task option concurrency must be positive, got %d", *o.Concurrency)
+	}
+	if o.Retry != nil && *o.Retry < 0 {
+		return fmt.Errorf("task option retry must not be negative, got %d", *o.Retry)
+	}
+	return nil
+}
+
+// ParseTaskOptions extracts the task option record from a Flux task script.
+// An error is returned when the script has no task option or the option cannot be parsed.
+func ParseTaskOptions(flux string) (*TaskOptions, error) {
+	block, err := findTaskOption(flux)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("flux script has no task option")
+	}
+	return block.options()
+}
+
+// SetTaskOptions returns the Flux task script with the task option record replaced by options.
+// Properties of the record unknown to TaskOptions are kept. When the script has no task option,
+// the option is inserted after the package clause and imports.
+func SetTaskOptions(flux string, options *TaskOptions) (string, error) {
+	block, err := findTaskOption(flux)
+	if err != nil {
+		return "", err
+	}
+	if block == nil {
+		stmt := formatTaskOption(options.properties(), "")
+		end := importsEnd(flux)
+		if end == 0 {
+			return stmt + "\n" + flux, nil
+		}
+		return flux[:end] + "\n\n" + stmt + flux[end:], nil
+	}
+	properties := options.properties()
+	for _, p := range block.properties {
+		if !isTaskOptionKey(p.key) {
+			properties = append(properties, p)
+		}
+	}
+	return flux[:block.start] + formatTaskOption(properties, block.indent) + flux[block.end:], nil
+}
+
+// taskOptionKeys lists properties of task option record known to TaskOptions, in the order they are written
+var taskOptionKeys = []string{"name", "every", "cron", "offset", "concurrency", "retry"}
+
+func isTaskOptionKey(key string) bool {
+	for _, k := range taskOptionKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// taskOptionProperty is a property of the task option record with the raw Flux value
+type taskOptionProperty struct {
+	key   string
+	value string
+}
+
+// properties returns set options as task option record properties
+func (o *TaskOptions) properties() []taskOptionProperty {
+	var properties []taskOptionProperty
+	if o.Name != "" {
+		properties = append(properties, taskOptionProperty{"name", quoteFluxString(o.Name)})
+	}
+	if o.Every != "" {
+		properties = append(properties, taskOptionProperty{"every", o.Every})
+	}
+	if o.Cron != "" {
+		properties = append(properties, taskOptionProperty{"cron", quoteFluxString(o.Cron)})
+	}
+	if o.Offset != "" {
+		properties = append(properties, taskOptionProperty{"offset", o.Offset})
+	}
+	if o.Concurrency != nil {
+		properties = append(properties, taskOptionProperty{"concurrency", strconv.Itoa(*o.Concurrency)})
+	}
+	if o.Retry != nil {
+		properties = append(properties, taskOptionProperty{"retry", strconv.Itoa(*o.Retry)})
+	}
+	return properties
+}
+
+// formatTaskOption formats the task option statement. Non-empty indent formats the record on multiple lines.
+func formatTaskOption(properties []taskOptionProperty, indent string) string {
+	var sb strings.Builder
+	sb.WriteString("option task = {")
+	for i, p := range properties {
+		if indent != "" {
+			sb.WriteString("\n" + indent)
+		} else if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(p.key + ": " + p.value)
+		if indent != "" {
+			sb.WriteString(",")
+		}
+	}
+	if indent != "" {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// taskOptionBlock is the location and content of the task option statement in a Flux script
+type taskOptionBlock struct {
+	// start and end positions of the whole statement
+	start, end int
+	properties []taskOptionProperty
+	// indent of properties of a record written on multiple lines, empty for a single line record
+	indent string
+}
+
+// options decodes properties of the task option record
+func (b *taskOptionBlock) options() (*TaskOptions, error) {
+	options := &TaskOptions{}
+	for _, p := range b.properties {
+		var err error
+		switch p.key {
+		case "name":
+			options.Name, err = unquoteFluxString(p.value)
+		case "cron":
+			options.Cron, err = unquoteFluxString(p.value)
+		case "every":
+			options.Every, err = p.value, schedule.ValidateDuration(p.value)
+		case "offset":
+			options.Offset, err = p.value, schedule.ValidateDuration(p.value)
+		case "concurrency":
+			options.Concurrency, err = parseFluxInt(p.value)
+		case "retry":
+			options.Retry, err = parseFluxInt(p.value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid task option %s: %w", p.key, err)
+		}
+	}
+	return options, nil
+}
+
+// fluxScanner walks through a Flux script token by token, skipping comments, string and regex literals
+type fluxScanner struct {
+	src string
+	pos int
+	// last significant character, used to distinguish regex literal from division
+	prev byte
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// skipSpace skips white space and comments
+func (s *fluxScanner) skipSpace() {
+	for s.pos < len(s.src) {
+		switch c := s.src[s.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			s.pos++
+		case strings.HasPrefix(s.src[s.pos:], "//"):
+			if i := strings.IndexByte(s.src[s.pos:], '\n'); i >= 0 {
+				s.pos += i + 1
+			} else {
+				s.pos = len(s.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// ident reads an identifier at the current position, it returns empty string if there is none
+func (s *fluxScanner) ident() string {
+	start := s.pos
+	if s.pos < len(s.src) && isIdentStart(s.src[s.pos]) {
+		for s.pos < len(s.src) && isIdentChar(s.src[s.pos]) {
+			s.pos++
+		}
+		s.prev = s.src[s.pos-1]
+	}
+	return s.src[start:s.pos]
+}
+
+// next skips white space and the next token. It returns the single character token or 0 for other tokens.
+func (s *fluxScanner) next() (byte, error) {
+	s.skipSpace()
+	if s.pos >= len(s.src) {
+		return 0, nil
+	}
+	c := s.src[s.pos]
+	switch {
+	case isIdentChar(c):
+		for s.pos < len(s.src) && isIdentChar(s.src[s.pos]) {
+			s.pos++
+		}
+		s.prev = c
+		return 0, nil
+	case c == '"':
+		if err := s.skipString(); err != nil {
+			return 0, err
+		}
+		s.prev = c
+		return 0, nil
+	case c == '/' && !isIdentChar(s.prev) && s.prev != ')' && s.prev != ']':
+		if err := s.skipRegex(); err != nil {
+			return 0, err
+		}
+		s.prev = c
+		return 0, nil
+	}
+	s.pos++
+	s.prev = c
+	return c, nil
+}
+
+// skipString skips a string literal including interpolated expressions
+func (s *fluxScanner) skipString() error {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.src) {
+		switch {
+		case s.src[s.pos] == '\\':
+			s.pos += 2
+		case s.src[s.pos] == '"':
+			s.pos++
+			return nil
+		case strings.HasPrefix(s.src[s.pos:], "${"):
+			s.pos += 2
+			if _, err := s.skipBlock('}'); err != nil {
+				return err
+			}
+		default:
+			s.pos++
+		}
+	}
+	return fmt.Errorf("unterminated string literal at position %d", start)
+}
+
+// skipRegex skips a regex literal
+func (s *fluxScanner) skipRegex() error {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+		switch s.src[s.pos] {
+		case '\\':
+			s.pos += 2
+		case '/':
+			s.pos++
+			return nil
+		default:
+			s.pos++
+		}
+	}
+	return fmt.Errorf("unterminated regex literal at position %d", start)
+}
+
+// skipBlock skips tokens up to and including the closing character matching an already read opening bracket.
+// It returns positions of commas, which are directly inside the block.
+func (s *fluxScanner) skipBlock(closing byte) ([]int, error) {
+	start := s.pos
+	var commas []int
+	stack := []byte{closing}
+	for len(stack) > 0 {
+		c, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		if s.pos >= len(s.src) && c == 0 {
+			return nil, fmt.Errorf("unterminated block at position %d", start)
+		}
+		switch c {
+		case '(':
+			stack = append(stack, ')')
+		case '[':
+			stack = append(stack, ']')
+		case '{':
+			stack = append(stack, '}')
+		case ')', ']', '}':
+			if c != stack[len(stack)-1] {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", c, s.pos-1)
+			}
+			stack = stack[:len(stack)-1]
+		case ',':
+			if len(stack) == 1 {
+				commas = append(commas, s.pos-1)
+			}
+		}
+	}
+	return commas, nil
+}
+
+// findTaskOption locates the task option statement in a Flux script. It returns nil, if there is none.
+func findTaskOption(flux string) (*taskOptionBlock, error) {
+	s := &fluxScanner{src: flux}
+	var block *taskOptionBlock
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.src) {
+			return block, nil
+		}
+		start := s.pos
+		if s.ident() == "option" {
+			s.skipSpace()
+			if s.ident() == "task" {
+				s.skipSpace()
+				if strings.HasPrefix(s.src[s.pos:], "=") && !strings.HasPrefix(s.src[s.pos:], "==") {
+					if block != nil {
+						return nil, errors.New("flux script has multiple task options")
+					}
+					s.pos++
+					var err error
+					if block, err = parseTaskOptionRecord(s, start); err != nil {
+						return nil, err
+					}
+				}
+			}
+			continue
+		}
+		if s.pos > start {
+			continue
+		}
+		c, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '(':
+			_, err = s.skipBlock(')')
+		case '[':
+			_, err = s.skipBlock(']')
+		case '{':
+			_, err = s.skipBlock('}')
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseTaskOptionRecord parses the record assigned to the task option, the scanner is positioned after '='
+func parseTaskOptionRecord(s *fluxScanner, start int) (*taskOptionBlock, error) {
+	s.skipSpace()
+	if !strings.HasPrefix(s.src[s.pos:], "{") {
+		return nil, fmt.Errorf("task option at position %d must be a record", start)
+	}
+	s.pos++
+	recordStart := s.pos
+	commas, err := s.skipBlock('}')
+	if err != nil {
+		return nil, err
+	}
+	block := &taskOptionBlock{start: start, end: s.pos}
+	record := s.src[recordStart : s.pos-1]
+	if i := strings.IndexByte(record, '\n'); i >= 0 && strings.TrimSpace(record) != "" {
+		line := record[i+1:]
+		block.indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if block.indent == "" {
+			block.indent = "    "
+		}
+	}
+	from := recordStart
+	for _, to := range append(commas, s.pos-1) {
+		property := strings.TrimSpace(stripFluxComments(s.src[from:to]))
+		from = to + 1
+		if property == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(property, ":")
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		if !ok || key == "" || strings.ContainsAny(key, " \t\n") {
+			return nil, fmt.Errorf("invalid task option property '%s'", property)
+		}
+		block.properties = append(block.properties, taskOptionProperty{key: key, value: strings.TrimSpace(value)})
+	}
+	return block, nil
+}
+
+// stripFluxComments removes line comments, which are outside of string literals
+func stripFluxComments(src string) string {
+	var sb strings.Builder
+	s := &fluxScanner{src: src}
+	for s.pos < len(s.src) {
+		start := s.pos
+		switch {
+		case strings.HasPrefix(s.src[s.pos:], "//"):
+			s.skipSpace()
+			sb.WriteString("\n")
+			continue
+		case s.src[s.pos] == '"':
+			if err := s.skipString(); err != nil {
+				s.pos = len(s.src)
+			}
+		default:
+			s.pos++
+		}
+		sb.WriteString(s.src[start:s.pos])
+	}
+	return sb.String()
+}
+
+// importsEnd returns position after the package clause and import statements of a Flux script
+func importsEnd(flux string) int {
+	s := &fluxScanner{src: flux}
+	end := 0
+	for {
+		s.skipSpace()
+		switch s.ident() {
+		case "package":
+			s.skipSpace()
+			s.ident()
+		case "import":
+			s.skipSpace()
+			s.ident()
+			s.skipSpace()
+			if s.pos >= len(s.src) || s.src[s.pos] != '"' || s.skipString() != nil {
+				return end
+			}
+		default:
+			return end
+		}
+		end = s.pos
+	}
+}
+
+// quoteFluxString returns Flux string literal with value s
+func quoteFluxString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// unquoteFluxString returns value of a Flux string literal without interpolation
+func unquoteFluxString(literal string) (string, error) {
+	if len(literal) < 2 || literal[0] != '"' || literal[len(literal)-1] != '"' {
+		return "", fmt.Errorf("'%s' is not a string literal", literal)
+	}
+	var sb strings.Builder
+	for i := 1; i < len(literal)-1; i++ {
+		c := literal[i]
+		switch {
+		case c == '\\' && i+1 < len(literal)-1:
+			i++
+			switch literal[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(literal[i])
+			}
+		case c == '"' || c == '\\':
+			return "", fmt.Errorf("'%s' is not a string literal", literal)
+		case strings.HasPrefix(literal[i:], "${"):
+			return "", fmt.Errorf("'%s' uses string interpolation, which is not supported", literal)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// parseFluxInt parses Flux integer literal
+func parseFluxInt(literal string) (*int, error) {
+	n, err := strconv.Atoi(literal)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not an integer literal", literal)
+	}
+	return &n, nil
+}
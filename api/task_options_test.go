@@ -0,0 +1,195 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTaskFlux = `import "strings"
+import r "regexp"
+
+// downsampling task
+option task = {
+    name: "down \"sample\"", // task name
+    every: 1h,
+    offset: 5m,
+    concurrency: 2,
+    retry: 3,
+    custom: {a: 1, b: [1, 2]},
+}
+
+option other = {every: 2h}
+
+from(bucket: "my-bucket ${strings.toUpper(v: "}")}")
+    |> range(start: -task.every)
+    |> filter(fn: (r) => r._measurement =~ /^mem{1}/ and r.value / 2 > 1)
+`
+
+func TestParseTaskOptions(t *testing.T) {
+	options, err := ParseTaskOptions(testTaskFlux)
+	require.NoError(t, err)
+	assert.Equal(t, `down "sample"`, options.Name)
+	assert.Equal(t, "1h", options.Every)
+	assert.Equal(t, "", options.Cron)
+	assert.Equal(t, "5m", options.Offset)
+	require.NotNil(t, options.Concurrency)
+	assert.Equal(t, 2, *options.Concurrency)
+	require.NotNil(t, options.Retry)
+	assert.Equal(t, 3, *options.Retry)
+	assert.NoError(t, options.Validate())
+
+	options, err = ParseTaskOptions(`option task = {name: "cron task", cron: "0 * * * *"} from(bucket: "b")`)
+	require.NoError(t, err)
+	assert.Equal(t, &TaskOptions{Name: "cron task", Cron: "0 * * * *"}, options)
+
+	for flux, msg := range map[string]string{
+		`from(bucket: "b")`: "flux script has no task option",
+		`f = () => { option task = {name: "n", every: 1h} }`:                        "flux script has no task option",
+		`option task = {name: "a", every: 1h} option task = {name: "b", every: 1h}`: "flux script has multiple task options",
+		`option task = {name: "n", every: 1x}`:                                      "invalid task option every: invalid duration '1x': unknown unit 'x'",
+		`option task = {name: n, every: 1h}`:                                        "invalid task option name: 'n' is not a string literal",
+		`option task = {name: "${n}", every: 1h}`:                                   `invalid task option name: '"${n}"' uses string interpolation, which is not supported`,
+		`option task = {name: "n", every: 1h, retry: many}`:                         "invalid task option retry: 'many' is not an integer literal",
+		`option task = {name: "n", every: 1h`:                                       "unterminated block at position 15",
+		`option task = {name: "n, every: 1h}`:                                       "unterminated string literal at position 21",
+		`option task = getOptions()`:                                                "task option at position 0 must be a record",
+	} {
+		_, err := ParseTaskOptions(flux)
+		assert.EqualError(t, err, msg, flux)
+	}
+}
+
+func TestTaskOptions_Validate(t *testing.T) {
+	zero, one := 0, 1
+	for _, c := range []struct {
+		options TaskOptions
+		err     string
+	}{
+		{TaskOptions{Name: "t", Every: "1h"}, ""},
+		{TaskOptions{Name: "t", Cron: "0 * * * *", Offset: "-5m", Concurrency: &one, Retry: &zero}, ""},
+		{TaskOptions{Every: "1h"}, "task option name is required"},
+		{TaskOptions{Name: "t"}, "task option requires either every or cron"},
+		{TaskOptions{Name: "t", Every: "1h", Cron: "0 * * * *"}, "task option every '1h' conflicts with cron '0 * * * *'"},
+		{TaskOptions{Name: "t", Every: "-1h"}, "task option every '-1h' must be positive"},
+		{TaskOptions{Name: "t", Every: "1h", Offset: "5"}, "invalid task option offset: invalid duration '5': unknown unit ''"},
+		{TaskOptions{Name: "t", Every: "1h", Concurrency: &zero}, "task option concurrency must be positive, got 0"},
+	} {
+		err := c.options.Validate()
+		if c.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, c.err)
+		}
+	}
+}
+
+func TestSetTaskOptions(t *testing.T) {
+	options, err := ParseTaskOptions(testTaskFlux)
+	require.NoError(t, err)
+	options.Every, options.Cron, options.Offset, options.Retry = "", "*/5 * * * *", "", nil
+	flux, err := SetTaskOptions(testTaskFlux, options)
+	require.NoError(t, err)
+	assert.Equal(t, `import "strings"
+import r "regexp"
+
+// downsampling task
+option task = {
+    name: "down \"sample\"",
+    cron: "*/5 * * * *",
+    concurrency: 2,
+    custom: {a: 1, b: [1, 2]},
+}
+
+option other = {every: 2h}
+
+from(bucket: "my-bucket ${strings.toUpper(v: "}")}")
+    |> range(start: -task.every)
+    |> filter(fn: (r) => r._measurement =~ /^mem{1}/ and r.value / 2 > 1)
+`, flux)
+	updated, err := ParseTaskOptions(flux)
+	require.NoError(t, err)
+	assert.Equal(t, options, updated)
+
+	flux, err = SetTaskOptions(`option task = {name: "a", every: 1h} from(bucket: "b")`, &TaskOptions{Name: "${b}", Every: "2h"})
+	require.NoError(t, err)
+	assert.Equal(t, `option task = {name: "\${b}", every: 2h} from(bucket: "b")`, flux)
+
+	// task option is added
+	flux, err = SetTaskOptions(`from(bucket: "b")`, &TaskOptions{Name: "a", Every: "1h"})
+	require.NoError(t, err)
+	assert.Equal(t, "option task = {name: \"a\", every: 1h}\nfrom(bucket: \"b\")", flux)
+
+	flux, err = SetTaskOptions("package main\n\nimport \"array\"\n\narray.from(rows: [])", &TaskOptions{Name: "a", Every: "1h"})
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nimport \"array\"\n\noption task = {name: \"a\", every: 1h}\n\narray.from(rows: [])", flux)
+
+	_, err = SetTaskOptions(`from(bucket: "b)`, &TaskOptions{Name: "a", Every: "1h"})
+	assert.Error(t, err)
+}
+
+func TestTasksAPI_TaskOptions(t *testing.T) {
+	var fluxes []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/tasks", func(w http.ResponseWriter, r *http.Request) {
+		var body domain.TaskCreateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fluxes = append(fluxes, body.Flux)
+		writeJSON(w, http.StatusCreated, `{"id":"t1","name":"task","orgID":"o1","flux":""}`)
+	})
+	mux.HandleFunc("PATCH /api/v2/tasks/t1", func(w http.ResponseWriter, r *http.Request) {
+		var body domain.TaskUpdateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fluxes = append(fluxes, *body.Flux)
+		writeJSON(w, http.StatusOK, `{"id":"t1","name":"task","orgID":"o1","flux":""}`)
+	})
+	tasksAPI := NewTasksAPI(newTestAPIClient(t, mux))
+	ctx := context.Background()
+
+	_, err := tasksAPI.CreateTaskWithEvery(ctx, "task", `import "array" array.from(rows: [])`, "1h", "o1")
+	require.NoError(t, err)
+	_, err = tasksAPI.CreateTaskWithEvery(ctx, "task", `option task = {name: "old", every: 1h, offset: 1m} from(bucket: "b")`, "1h", "o1")
+	require.NoError(t, err)
+	_, err = tasksAPI.CreateTaskWithCron(ctx, "task", `option task = {name: "old", offset: 1m} from(bucket: "b")`, "0 * * * *", "o1")
+	require.NoError(t, err)
+	_, err = tasksAPI.CreateTaskByFlux(ctx, `option task = {name: "task", cron: "0 * * * *"} from(bucket: "b")`, "o1")
+	require.NoError(t, err)
+	_, err = tasksAPI.UpdateTask(ctx, &domain.Task{Id: "t1", Name: "task", Every: &[]string{"2h"}[0], Offset: &[]string{"0s"}[0],
+		Cron: &[]string{"0 * * * *"}[0], Flux: `option task = {name: "task", cron: "0 * * * *"} from(bucket: "b")`})
+	require.NoError(t, err)
+	_, err = tasksAPI.UpdateTask(ctx, &domain.Task{Id: "t1", Name: "task", Flux: `from(bucket: "b")`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"import \"array\"\n\noption task = {name: \"task\", every: 1h} array.from(rows: [])",
+		`option task = {name: "task", every: 1h, offset: 1m} from(bucket: "b")`,
+		`option task = {name: "task", cron: "0 * * * *", offset: 1m} from(bucket: "b")`,
+		`option task = {name: "task", cron: "0 * * * *"} from(bucket: "b")`,
+		`option task = {name: "task", every: 2h} from(bucket: "b")`,
+		`from(bucket: "b")`,
+	}, fluxes)
+
+	// conflicts are detected before a request is sent
+	fluxes = fluxes[:0]
+	_, err = tasksAPI.CreateTaskWithEvery(ctx, "task", `option task = {name: "task", cron: "0 * * * *"} from(bucket: "b")`, "1h", "o1")
+	assert.EqualError(t, err, "every '1h' conflicts with the task option of the flux script: every '', cron '0 * * * *'")
+	_, err = tasksAPI.CreateTaskWithEvery(ctx, "task", `option task = {name: "task", every: 2h} from(bucket: "b")`, "1h", "o1")
+	assert.EqualError(t, err, "every '1h' conflicts with the task option of the flux script: every '2h', cron ''")
+	_, err = tasksAPI.CreateTaskWithCron(ctx, "task", `option task = {name: "task", every: 2h} from(bucket: "b")`, "0 * * * *", "o1")
+	assert.EqualError(t, err, "cron '0 * * * *' conflicts with the task option of the flux script: every '2h', cron ''")
+	_, err = tasksAPI.CreateTaskByFlux(ctx, `option task = {name: "task", every: 2h, cron: "0 * * * *"} from(bucket: "b")`, "o1")
+	assert.EqualError(t, err, "task option every '2h' conflicts with cron '0 * * * *'")
+	_, err = tasksAPI.CreateTaskByFlux(ctx, `from(bucket: "b")`, "o1")
+	assert.EqualError(t, err, "flux script has no task option")
+	_, err = tasksAPI.CreateTask(ctx, &domain.Task{Name: "task", Flux: `from(bucket: "b")`, OrgID: "o1"})
+	assert.EqualError(t, err, "task option requires either every or cron")
+	assert.Len(t, fluxes, 0)
+}
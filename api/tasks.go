@@ -7,9 +7,11 @@ package api
 import (
 	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/influxdata/influxdb-client-go/v2/internal/schedule"
 )
 
 // TaskFilter defines filtering options for FindTasks functions.
@@ -48,13 +50,18 @@ type RunFilter struct {
 type TasksAPI interface {
 	// FindTasks retrieves tasks according to the filter. More fields can be applied. Filter can be nil.
 	FindTasks(ctx context.Context, filter *TaskFilter) ([]domain.Task, error)
+	// IterateTasks returns an iterator over all tasks matching the filter, which are fetched page by page on demand.
+	// Filter Limit sets the page size, default is 100. Filter can be nil.
+	// Iteration ends with the first error.
+	IterateTasks(ctx context.Context, filter *TaskFilter) iter.Seq2[domain.Task, error]
 	// GetTask retrieves a refreshed instance of task.
 	GetTask(ctx context.Context, task *domain.Task) (*domain.Task, error)
 	// GetTaskByID retrieves a task found using taskID.
 	GetTaskByID(ctx context.Context, taskID string) (*domain.Task, error)
 	// CreateTask creates a new task according the task object.
 	// It copies OrgId, Name, Description, Flux, Status and Every or Cron properties. Every and Cron are mutually exclusive.
-	// Every has higher priority.
+	// Every has higher priority. Name and Every or Cron are set to the task option of the flux script, which is added when missing.
+	// An error is returned when the task option of the script already defines a different schedule.
 	CreateTask(ctx context.Context, task *domain.Task) (*domain.Task, error)
 	// CreateTaskWithEvery creates a new task with the name, flux script and every repetition setting, in the org orgID.
 	// Every means duration values.
@@ -63,10 +70,11 @@ type TasksAPI interface {
 	// Cron holds cron-like setting, e.g. once an hour at beginning of the hour "0 * * * *".
 	CreateTaskWithCron(ctx context.Context, name, flux, cron, orgID string) (*domain.Task, error)
 	// CreateTaskByFlux creates a new task with complete definition in flux script, in the org orgID
+	// The task option of the script is validated before the task is created.
 	CreateTaskByFlux(ctx context.Context, flux, orgID string) (*domain.Task, error)
 	// UpdateTask updates a task.
 	// It copies Description, Flux, Status, Offset and Every or Cron properties. Every and Cron are mutually exclusive.
-	// Every has higher priority.
+	// Every has higher priority. When the flux script contains the task option, Name, Every or Cron and Offset are set to it.
 	UpdateTask(ctx context.Context, task *domain.Task) (*domain.Task, error)
 	// DeleteTask deletes a task.
 	DeleteTask(ctx context.Context, task *domain.Task) error
@@ -100,6 +108,14 @@ type TasksAPI interface {
 	FindRuns(ctx context.Context, task *domain.Task, filter *RunFilter) ([]domain.Run, error)
 	// FindRunsWithID retrieves runs of a task with taskID according the filter. More fields can be applied. Filter can be nil.
 	FindRunsWithID(ctx context.Context, taskID string, filter *RunFilter) ([]domain.Run, error)
+	// IterateRuns returns an iterator over all runs of a task matching the filter, which are fetched page by page on demand.
+	// Filter Limit sets the page size, default is 100. Filter can be nil.
+	// Iteration ends with the first error.
+	IterateRuns(ctx context.Context, task *domain.Task, filter *RunFilter) iter.Seq2[domain.Run, error]
+	// IterateRunsWithID returns an iterator over all runs of a task with taskID matching the filter, which are fetched page by page on demand.
+	// Filter Limit sets the page size, default is 100. Filter can be nil.
+	// Iteration ends with the first error.
+	IterateRunsWithID(ctx context.Context, taskID string, filter *RunFilter) iter.Seq2[domain.Run, error]
 	// GetRun retrieves a refreshed instance if a task run.
 	GetRun(ctx context.Context, run *domain.Run) (*domain.Run, error)
 	// GetRunByID retrieves a specific task run by taskID and runID
@@ -112,6 +128,23 @@ type TasksAPI interface {
 	RunManually(ctx context.Context, task *domain.Task) (*domain.Run, error)
 	// RunManuallyWithID manually start a run of a task with taskID now, overriding the current schedule.
 	RunManuallyWithID(ctx context.Context, taskID string) (*domain.Run, error)
+	// RunManuallyAt manually start a run of the task now, with the run's `now` option set to scheduledFor.
+	RunManuallyAt(ctx context.Context, task *domain.Task, scheduledFor time.Time) (*domain.Run, error)
+	// RunManuallyAtWithID manually start a run of a task with taskID now, with the run's `now` option set to scheduledFor.
+	RunManuallyAtWithID(ctx context.Context, taskID string, scheduledFor time.Time) (*domain.Run, error)
+	// Backfill runs the task for all times within [start, stop] it would be scheduled for according to its every or cron option.
+	// Runs are submitted with bounded concurrency and, by default, each run is waited for to finish.
+	// The returned results, one per scheduled time in chronological order, report success or failure of each run.
+	// An error is returned when the task schedule cannot be computed.
+	Backfill(ctx context.Context, task *domain.Task, start, stop time.Time, options ...BackfillOption) ([]BackfillResult, error)
+	// WaitForRun blocks until the run reaches a terminal status (success, failed or canceled), checking the run every pollInterval.
+	// It returns the finished run along with all its log events.
+	// Waiting can be cancelled via ctx. pollInterval must be positive.
+	WaitForRun(ctx context.Context, run *domain.Run, pollInterval time.Duration) (*domain.Run, []domain.LogEvent, error)
+	// StreamRunLogs checks the run every pollInterval and sends its new log events to the returned channel as they appear.
+	// Channels are closed when the run reaches a terminal status and all its log events are sent, after an error, or when ctx is done.
+	// An error is sent to the error channel, which is buffered, and it ends the streaming. pollInterval must be positive.
+	StreamRunLogs(ctx context.Context, run *domain.Run, pollInterval time.Duration) (<-chan domain.LogEvent, <-chan error)
 	// RetryRun retry a task run.
 	RetryRun(ctx context.Context, run *domain.Run) (*domain.Run, error)
 	// RetryRunWithID retry a run with runID of a task with taskID.
@@ -151,6 +184,39 @@ func NewTasksAPI(apiClient *domain.Client) TasksAPI {
 }
 
 func (t *tasksAPI) FindTasks(ctx context.Context, filter *TaskFilter) ([]domain.Task, error) {
+	return t.findTasks(ctx, tasksParams(filter))
+}
+
+func (t *tasksAPI) findTasks(ctx context.Context, params *domain.GetTasksParams) ([]domain.Task, error) {
+	response, err := t.apiClient.GetTasks(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return *response.Tasks, nil
+}
+
+func (t *tasksAPI) IterateTasks(ctx context.Context, filter *TaskFilter) iter.Seq2[domain.Task, error] {
+	paging := defaultPaging()
+	if filter != nil {
+		paging.limit = domain.Limit(filter.Limit)
+		paging.after = domain.After(filter.After)
+	}
+	return iteratePages(paging, afterCursor, func(p *Paging) ([]domain.Task, error) {
+		params := tasksParams(filter)
+		limit := int(p.limit)
+		params.Limit = &limit
+		if p.after != "" {
+			after := string(p.after)
+			params.After = &after
+		}
+		return t.findTasks(ctx, params)
+	}, func(task *domain.Task) *string {
+		return &task.Id
+	})
+}
+
+// tasksParams creates GetTasks params from the filter
+func tasksParams(filter *TaskFilter) *domain.GetTasksParams {
 	params := &domain.GetTasksParams{}
 	if filter != nil {
 		if filter.Name != "" {
@@ -176,12 +242,7 @@ func (t *tasksAPI) FindTasks(ctx context.Context, filter *TaskFilter) ([]domain.
 			params.After = &filter.After
 		}
 	}
-
-	response, err := t.apiClient.GetTasks(ctx, params)
-	if err != nil {
-		return nil, err
-	}
-	return *response.Tasks, nil
+	return params
 }
 
 func (t *tasksAPI) GetTask(ctx context.Context, task *domain.Task) (*domain.Task, error) {
@@ -202,16 +263,43 @@ func (t *tasksAPI) createTask(ctx context.Context, taskReq *domain.TaskCreateReq
 	return t.apiClient.PostTasks(ctx, params)
 }
 
-func createTaskReqDetailed(name, flux string, every, cron *string, orgID string) *domain.TaskCreateRequest {
-	repetition := ""
+// createTaskReqDetailed creates task request with the task option of the flux script set to name and every or cron.
+// Every has higher priority.
+func createTaskReqDetailed(name, flux string, every, cron *string, orgID string) (*domain.TaskCreateRequest, error) {
+	block, err := findTaskOption(flux)
+	if err != nil {
+		return nil, err
+	}
+	options := &TaskOptions{}
+	if block != nil {
+		if options, err = block.options(); err != nil {
+			return nil, err
+		}
+	}
 	if every != nil {
-		repetition = fmt.Sprintf("every: %s", *every)
+		if options.Cron != "" || (options.Every != "" && options.Every != *every) {
+			return nil, fmt.Errorf("every '%s' conflicts with the task option of the flux script: every '%s', cron '%s'", *every, options.Every, options.Cron)
+		}
+		options.Every = *every
 	} else if cron != nil {
-		repetition = fmt.Sprintf(`cron: "%s"`, *cron)
+		if options.Every != "" || (options.Cron != "" && options.Cron != *cron) {
+			return nil, fmt.Errorf("cron '%s' conflicts with the task option of the flux script: every '%s', cron '%s'", *cron, options.Every, options.Cron)
+		}
+		options.Cron = *cron
+	}
+	if name != "" {
+		options.Name = name
 	}
-	fullFlux := fmt.Sprintf(`option task = { name: "%s", %s } %s`, name, repetition, flux)
-	return createTaskReq(fullFlux, orgID)
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	fullFlux, err := SetTaskOptions(flux, options)
+	if err != nil {
+		return nil, err
+	}
+	return createTaskReq(fullFlux, orgID), nil
 }
+
 func createTaskReq(flux string, orgID string) *domain.TaskCreateRequest {
 
 	status := domain.TaskStatusTypeActive
@@ -224,23 +312,39 @@ func createTaskReq(flux string, orgID string) *domain.TaskCreateRequest {
 }
 
 func (t *tasksAPI) CreateTask(ctx context.Context, task *domain.Task) (*domain.Task, error) {
-	taskReq := createTaskReqDetailed(task.Name, task.Flux, task.Every, task.Cron, task.OrgID)
+	taskReq, err := createTaskReqDetailed(task.Name, task.Flux, task.Every, task.Cron, task.OrgID)
+	if err != nil {
+		return nil, err
+	}
 	taskReq.Description = task.Description
 	taskReq.Status = task.Status
 	return t.createTask(ctx, taskReq)
 }
 
 func (t *tasksAPI) CreateTaskWithEvery(ctx context.Context, name, flux, every, orgID string) (*domain.Task, error) {
-	taskReq := createTaskReqDetailed(name, flux, &every, nil, orgID)
+	taskReq, err := createTaskReqDetailed(name, flux, &every, nil, orgID)
+	if err != nil {
+		return nil, err
+	}
 	return t.createTask(ctx, taskReq)
 }
 
 func (t *tasksAPI) CreateTaskWithCron(ctx context.Context, name, flux, cron, orgID string) (*domain.Task, error) {
-	taskReq := createTaskReqDetailed(name, flux, nil, &cron, orgID)
+	taskReq, err := createTaskReqDetailed(name, flux, nil, &cron, orgID)
+	if err != nil {
+		return nil, err
+	}
 	return t.createTask(ctx, taskReq)
 }
 
 func (t *tasksAPI) CreateTaskByFlux(ctx context.Context, flux, orgID string) (*domain.Task, error) {
+	options, err := ParseTaskOptions(flux)
+	if err != nil {
+		return nil, err
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
 	taskReq := createTaskReq(flux, orgID)
 	return t.createTask(ctx, taskReq)
 }
@@ -257,10 +361,14 @@ func (t *tasksAPI) DeleteTaskWithID(ctx context.Context, taskID string) error {
 }
 
 func (t *tasksAPI) UpdateTask(ctx context.Context, task *domain.Task) (*domain.Task, error) {
+	flux, err := updateTaskFlux(task)
+	if err != nil {
+		return nil, err
+	}
 	params := &domain.PatchTasksIDAllParams{
 		Body: domain.PatchTasksIDJSONRequestBody(domain.TaskUpdateRequest{
 			Description: task.Description,
-			Flux:        &task.Flux,
+			Flux:        &flux,
 			Name:        &task.Name,
 			Offset:      task.Offset,
 			Status:      task.Status,
@@ -275,6 +383,41 @@ func (t *tasksAPI) UpdateTask(ctx context.Context, task *domain.Task) (*domain.T
 	return t.apiClient.PatchTasksID(ctx, params)
 }
 
+// updateTaskFlux returns the task flux script with the task option updated by the task Name, Every or Cron and Offset properties,
+// so the script is consistent with the properties. A script without the task option is returned unchanged.
+func updateTaskFlux(task *domain.Task) (string, error) {
+	block, err := findTaskOption(task.Flux)
+	if err != nil || block == nil {
+		return task.Flux, err
+	}
+	options, err := block.options()
+	if err != nil {
+		return "", err
+	}
+	if task.Name != "" {
+		options.Name = task.Name
+	}
+	if task.Every != nil && *task.Every != "" {
+		options.Every, options.Cron = *task.Every, ""
+	} else if task.Cron != nil && *task.Cron != "" {
+		options.Every, options.Cron = "", *task.Cron
+	}
+	// server reports zero offset of tasks without offset
+	if task.Offset != nil && *task.Offset != "" && (options.Offset != "" || !isZeroDuration(*task.Offset)) {
+		options.Offset = *task.Offset
+	}
+	if err := options.Validate(); err != nil {
+		return "", err
+	}
+	return SetTaskOptions(task.Flux, options)
+}
+
+// isZeroDuration returns true for a zero Flux duration literal, e.g. 0s
+func isZeroDuration(d string) bool {
+	duration, err := schedule.ParseDuration(d)
+	return err == nil && duration == 0
+}
+
 func (t *tasksAPI) FindMembers(ctx context.Context, task *domain.Task) ([]domain.ResourceMember, error) {
 	return t.FindMembersWithID(ctx, task.Id)
 }
@@ -359,6 +502,43 @@ func (t *tasksAPI) FindRuns(ctx context.Context, task *domain.Task, filter *RunF
 }
 
 func (t *tasksAPI) FindRunsWithID(ctx context.Context, taskID string, filter *RunFilter) ([]domain.Run, error) {
+	return t.findRuns(ctx, runsParams(taskID, filter))
+}
+
+func (t *tasksAPI) findRuns(ctx context.Context, params *domain.GetTasksIDRunsAllParams) ([]domain.Run, error) {
+	response, err := t.apiClient.GetTasksIDRuns(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return *response.Runs, nil
+}
+
+func (t *tasksAPI) IterateRuns(ctx context.Context, task *domain.Task, filter *RunFilter) iter.Seq2[domain.Run, error] {
+	return t.IterateRunsWithID(ctx, task.Id, filter)
+}
+
+func (t *tasksAPI) IterateRunsWithID(ctx context.Context, taskID string, filter *RunFilter) iter.Seq2[domain.Run, error] {
+	paging := defaultPaging()
+	if filter != nil {
+		paging.limit = domain.Limit(filter.Limit)
+		paging.after = domain.After(filter.After)
+	}
+	return iteratePages(paging, afterCursor, func(p *Paging) ([]domain.Run, error) {
+		params := runsParams(taskID, filter)
+		limit := int(p.limit)
+		params.Limit = &limit
+		if p.after != "" {
+			after := string(p.after)
+			params.After = &after
+		}
+		return t.findRuns(ctx, params)
+	}, func(run *domain.Run) *string {
+		return run.Id
+	})
+}
+
+// runsParams creates GetTasksIDRuns params from the filter
+func runsParams(taskID string, filter *RunFilter) *domain.GetTasksIDRunsAllParams {
 	params := &domain.GetTasksIDRunsAllParams{TaskID: taskID}
 	if filter != nil {
 		if !filter.AfterTime.IsZero() {
@@ -374,11 +554,7 @@ func (t *tasksAPI) FindRunsWithID(ctx context.Context, taskID string, filter *Ru
 			params.After = &filter.After
 		}
 	}
-	response, err := t.apiClient.GetTasksIDRuns(ctx, params)
-	if err != nil {
-		return nil, err
-	}
-	return *response.Runs, nil
+	return params
 }
 
 func (t *tasksAPI) GetRun(ctx context.Context, run *domain.Run) (*domain.Run, error) {
@@ -422,6 +598,109 @@ func (t *tasksAPI) RunManuallyWithID(ctx context.Context, taskID string) (*domai
 	return t.apiClient.PostTasksIDRuns(ctx, params)
 }
 
+func (t *tasksAPI) RunManuallyAt(ctx context.Context, task *domain.Task, scheduledFor time.Time) (*domain.Run, error) {
+	return t.RunManuallyAtWithID(ctx, task.Id, scheduledFor)
+}
+
+func (t *tasksAPI) RunManuallyAtWithID(ctx context.Context, taskID string, scheduledFor time.Time) (*domain.Run, error) {
+	params := &domain.PostTasksIDRunsAllParams{
+		TaskID: taskID,
+		Body: domain.PostTasksIDRunsJSONRequestBody{
+			ScheduledFor: &scheduledFor,
+		},
+	}
+	return t.apiClient.PostTasksIDRuns(ctx, params)
+}
+
+func (t *tasksAPI) WaitForRun(ctx context.Context, run *domain.Run, pollInterval time.Duration) (*domain.Run, []domain.LogEvent, error) {
+	if err := checkPollInterval(pollInterval); err != nil {
+		return nil, nil, err
+	}
+	for {
+		r, err := t.GetRun(ctx, run)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isRunFinished(r) {
+			logs, err := t.FindRunLogs(ctx, run)
+			if err != nil {
+				return nil, nil, err
+			}
+			return r, logs, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (t *tasksAPI) StreamRunLogs(ctx context.Context, run *domain.Run, pollInterval time.Duration) (<-chan domain.LogEvent, <-chan error) {
+	logCh := make(chan domain.LogEvent)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		defer close(logCh)
+		if err := checkPollInterval(pollInterval); err != nil {
+			errCh <- err
+			return
+		}
+		sent := 0
+		for {
+			// status must be read before logs, so that all logs of a finished run are sent
+			r, err := t.GetRun(ctx, run)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			logs, err := t.FindRunLogs(ctx, run)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			for ; sent < len(logs); sent++ {
+				select {
+				case logCh <- logs[sent]:
+				case <-ctx.Done():
+					errCh <- ctx.Err()
+					return
+				}
+			}
+			if isRunFinished(r) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			case <-time.After(pollInterval):
+			}
+		}
+	}()
+	return logCh, errCh
+}
+
+// checkPollInterval returns error if pollInterval is not positive, which would poll the server in a tight loop
+func checkPollInterval(pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		return fmt.Errorf("poll interval must be positive, got %v", pollInterval)
+	}
+	return nil
+}
+
+// isRunFinished returns true if run reached a terminal status
+func isRunFinished(run *domain.Run) bool {
+	if run.Status == nil {
+		return false
+	}
+	switch *run.Status {
+	case domain.RunStatusSuccess, domain.RunStatusFailed, domain.RunStatusCanceled:
+		return true
+	}
+	return false
+}
+
 func (t *tasksAPI) RetryRun(ctx context.Context, run *domain.Run) (*domain.Run, error) {
 	return t.RetryRunWithID(ctx, *run.TaskID, *run.Id)
 }
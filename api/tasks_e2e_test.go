@@ -473,6 +473,22 @@ func TestTasksAPI_Runs(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, run)
 
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	finished, runLogs, err := tasksAPI.WaitForRun(waitCtx, runm, 100*time.Millisecond)
+	cancel()
+	require.Nil(t, err, err)
+	require.NotNil(t, finished)
+	assert.Equal(t, domain.RunStatusSuccess, *finished.Status)
+	assert.True(t, len(runLogs) > 0)
+
+	logCh, errCh := tasksAPI.StreamRunLogs(ctx, runm, 100*time.Millisecond)
+	streamed := 0
+	for range logCh {
+		streamed++
+	}
+	require.Nil(t, <-errCh)
+	assert.Equal(t, len(runLogs), streamed)
+
 	run2, err := tasksAPI.RetryRun(ctx, run)
 	require.Nil(t, err)
 	require.NotNil(t, run2)
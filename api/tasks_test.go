@@ -0,0 +1,205 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runServer simulates a task run, which finishes after given number of status checks,
+// and produces a new log event with each status check
+type runServer struct {
+	lock        sync.Mutex
+	checks      int
+	finishAfter int
+	finalStatus domain.RunStatus
+}
+
+func (s *runServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/tasks/t1/runs/r1", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.checks++
+		status := domain.RunStatusStarted
+		if s.checks >= s.finishAfter {
+			status = s.finalStatus
+		}
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"id":"r1","taskID":"t1","status":"%s"}`, status))
+	})
+	mux.HandleFunc("/api/v2/tasks/t1/runs/r1/logs", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		events := make([]string, s.checks)
+		for i := range events {
+			events[i] = fmt.Sprintf(`{"runID":"r1","message":"log %d"}`, i+1)
+		}
+		writeJSON(w, http.StatusOK, `{"events":[`+strings.Join(events, ",")+`]}`)
+	})
+	return mux
+}
+
+func testRun() *domain.Run {
+	return &domain.Run{Id: &[]string{"r1"}[0], TaskID: &[]string{"t1"}[0]}
+}
+
+func TestTasksAPI_WaitForRun(t *testing.T) {
+	server := &runServer{finishAfter: 3, finalStatus: domain.RunStatusSuccess}
+	tasksAPI := NewTasksAPI(newTestAPIClient(t, server.handler()))
+
+	run, logs, err := tasksAPI.WaitForRun(context.Background(), testRun(), time.Millisecond)
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, domain.RunStatusSuccess, *run.Status)
+	assert.Equal(t, 3, server.checks)
+	require.Len(t, logs, 3)
+	assert.Equal(t, "log 3", *logs[2].Message)
+
+	server = &runServer{finishAfter: 100, finalStatus: domain.RunStatusFailed}
+	tasksAPI = NewTasksAPI(newTestAPIClient(t, server.handler()))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	run, logs, err = tasksAPI.WaitForRun(ctx, testRun(), 5*time.Millisecond)
+	// deadline can be exceeded while waiting or during a request
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, run)
+	assert.Nil(t, logs)
+
+	for _, pollInterval := range []time.Duration{0, -time.Second} {
+		server = &runServer{finishAfter: 100, finalStatus: domain.RunStatusFailed}
+		tasksAPI = NewTasksAPI(newTestAPIClient(t, server.handler()))
+		_, _, err = tasksAPI.WaitForRun(context.Background(), testRun(), pollInterval)
+		assert.EqualError(t, err, fmt.Sprintf("poll interval must be positive, got %v", pollInterval))
+		assert.Equal(t, 0, server.checks)
+	}
+}
+
+func TestTasksAPI_StreamRunLogs(t *testing.T) {
+	server := &runServer{finishAfter: 4, finalStatus: domain.RunStatusFailed}
+	tasksAPI := NewTasksAPI(newTestAPIClient(t, server.handler()))
+
+	logCh, errCh := tasksAPI.StreamRunLogs(context.Background(), testRun(), time.Millisecond)
+	var messages []string
+	for event := range logCh {
+		messages = append(messages, *event.Message)
+	}
+	assert.NoError(t, <-errCh)
+	assert.Equal(t, []string{"log 1", "log 2", "log 3", "log 4"}, messages)
+
+	// failing request
+	tasksAPI = NewTasksAPI(newTestAPIClient(t, http.NotFoundHandler()))
+	logCh, errCh = tasksAPI.StreamRunLogs(context.Background(), testRun(), time.Millisecond)
+	for range logCh {
+		assert.Fail(t, "no log expected")
+	}
+	assert.Error(t, <-errCh)
+
+	server = &runServer{finishAfter: 100, finalStatus: domain.RunStatusFailed}
+	tasksAPI = NewTasksAPI(newTestAPIClient(t, server.handler()))
+	logCh, errCh = tasksAPI.StreamRunLogs(context.Background(), testRun(), 0)
+	for range logCh {
+		assert.Fail(t, "no log expected")
+	}
+	assert.EqualError(t, <-errCh, "poll interval must be positive, got 0s")
+	assert.Equal(t, 0, server.checks)
+}
+
+func TestTasksAPI_Backfill(t *testing.T) {
+	var lock sync.Mutex
+	var scheduled []string
+	running, maxRunning := 0, 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/tasks/t1/runs", func(w http.ResponseWriter, r *http.Request) {
+		var body domain.RunManually
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.NotNil(t, body.ScheduledFor)
+		lock.Lock()
+		scheduled = append(scheduled, body.ScheduledFor.UTC().Format(time.RFC3339))
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		id := body.ScheduledFor.UTC().Format("15")
+		writeJSON(w, http.StatusCreated, fmt.Sprintf(`{"id":"%s","taskID":"t1","status":"scheduled"}`, id))
+	})
+	mux.HandleFunc("GET /api/v2/tasks/t1/runs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		running--
+		lock.Unlock()
+		// let other workers submit their runs
+		time.Sleep(5 * time.Millisecond)
+		status := domain.RunStatusSuccess
+		if r.PathValue("id") == "02" {
+			status = domain.RunStatusFailed
+		}
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"id":"%s","taskID":"t1","status":"%s"}`, r.PathValue("id"), status))
+	})
+	mux.HandleFunc("GET /api/v2/tasks/t1/runs/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"events":[{"runID":"`+r.PathValue("id")+`","message":"done"}]}`)
+	})
+	tasksAPI := NewTasksAPI(newTestAPIClient(t, mux))
+	start, _ := time.Parse(time.RFC3339, "2022-01-01T00:00:00Z")
+	stop := start.Add(5 * time.Hour)
+
+	task := &domain.Task{Id: "t1", Name: "task", Every: &[]string{"1h"}[0]}
+	results, err := tasksAPI.Backfill(context.Background(), task, start, stop, BackfillWithConcurrency(2), BackfillWithPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+	assert.Len(t, scheduled, 6)
+	assert.LessOrEqual(t, maxRunning, 2)
+	for i, res := range results {
+		assert.Equal(t, start.Add(time.Duration(i)*time.Hour), res.ScheduledFor)
+		require.NotNil(t, res.Run)
+		require.Len(t, res.Logs, 1)
+		if i == 2 {
+			assert.False(t, res.Succeeded())
+			assert.EqualError(t, res.Err, "run '02' scheduled for 2022-01-01T02:00:00Z finished with status 'failed'")
+		} else {
+			assert.True(t, res.Succeeded(), res.Err)
+			assert.Equal(t, domain.RunStatusSuccess, *res.Run.Status)
+		}
+	}
+
+	// cron schedule without waiting
+	scheduled = scheduled[:0]
+	task = &domain.Task{Id: "t1", Name: "task", Cron: &[]string{"0 */2 * * *"}[0]}
+	results, err = tasksAPI.Backfill(context.Background(), task, start, stop, BackfillWithWait(false))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.ElementsMatch(t, []string{"2022-01-01T00:00:00Z", "2022-01-01T02:00:00Z", "2022-01-01T04:00:00Z"}, scheduled)
+	for _, res := range results {
+		assert.True(t, res.Succeeded())
+		assert.Equal(t, domain.RunStatusScheduled, *res.Run.Status)
+		assert.Nil(t, res.Logs)
+	}
+
+	// failing submission
+	tasksAPI = NewTasksAPI(newTestAPIClient(t, http.NotFoundHandler()))
+	results, err = tasksAPI.Backfill(context.Background(), task, start, stop)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, res := range results {
+		assert.Error(t, res.Err)
+		assert.Nil(t, res.Run)
+	}
+
+	// invalid schedule
+	_, err = tasksAPI.Backfill(context.Background(), &domain.Task{Id: "t1", Name: "task"}, start, stop)
+	assert.EqualError(t, err, "task 'task' has neither every nor cron set")
+	_, err = tasksAPI.Backfill(context.Background(), &domain.Task{Id: "t1", Name: "task", Every: &[]string{"1mo"}[0]}, start, stop)
+	assert.EqualError(t, err, "task 'task': duration '1mo' uses calendar unit 'mo', which is not supported")
+}
@@ -0,0 +1,260 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplatesAPI provides methods for applying InfluxDB templates, which describe resources such as buckets, tasks or dashboards.
+// See https://docs.influxdata.com/influxdb/latest/influxdb-templates/.
+type TemplatesAPI interface {
+	// ApplyTemplate applies templates of params into organization org and returns summary of resources and diff of changes.
+	// If the server rejects the templates, the returned error is *domain.TemplateApplyError holding validation errors.
+	ApplyTemplate(ctx context.Context, org *domain.Organization, params *TemplateApplyParams) (*TemplateApplyResult, error)
+	// ApplyTemplateWithOrgID applies templates of params into organization with orgID and returns summary of resources and diff of changes.
+	// If the server rejects the templates, the returned error is *domain.TemplateApplyError holding validation errors.
+	ApplyTemplateWithOrgID(ctx context.Context, orgID string, params *TemplateApplyParams) (*TemplateApplyResult, error)
+}
+
+// TemplateApplyParams holds templates to apply and parameters of applying them
+type TemplateApplyParams struct {
+	// Templates are contents of templates, see ParseTemplate
+	Templates []domain.Template
+	// RemoteURLs are URLs of template files, which are fetched by the server
+	RemoteURLs []string
+	// DryRun only validates templates and computes the summary and diff, no resources are changed
+	DryRun bool
+	// EnvRefs are values of environment references of templates by their keys
+	EnvRefs map[string]interface{}
+	// Secrets are stored in the organization by their keys, they are referenced by queries of templates
+	Secrets map[string]string
+	// StackID is ID of the stack to update. Without stack ID, a new stack is created with resources of templates.
+	StackID string
+	// SkipKinds are kinds of resources, which are not created or updated
+	SkipKinds []domain.TemplateKind
+	// SkipResources are resources, which are not created or updated
+	SkipResources []TemplateResource
+}
+
+// TemplateResource identifies a resource of a template
+type TemplateResource struct {
+	// Kind of the resource, e.g. Bucket
+	Kind domain.TemplateKind
+	// TemplateMetaName is metadata.name of the resource in the template
+	TemplateMetaName string
+}
+
+// TemplateApplyResult is result of applying templates
+type TemplateApplyResult struct {
+	// StackID is ID of the stack with resources of templates, it can be empty for a dry run
+	StackID string
+	// Changes are changes of resources in the diff
+	Changes []TemplateChange
+	// Summary holds resources of templates, the diff and missing environment references and secrets
+	Summary *domain.TemplateSummary
+}
+
+// TemplateChangeStatus is state of a resource in the diff of applied templates
+type TemplateChangeStatus string
+
+const (
+	// TemplateChangeNew is a resource created by templates
+	TemplateChangeNew TemplateChangeStatus = "new"
+	// TemplateChangeExisting is an existing resource updated by templates, or unchanged
+	TemplateChangeExisting TemplateChangeStatus = "existing"
+	// TemplateChangeRemove is a resource of the stack removed by templates
+	TemplateChangeRemove TemplateChangeStatus = "remove"
+)
+
+// TemplateChange is a change of a resource in the diff of applied templates
+type TemplateChange struct {
+	TemplateResource
+	// ID of the resource, it is empty for a new resource in a dry run
+	ID string
+	// Status of the resource
+	Status TemplateChangeStatus
+	// Modified is true if properties of the resource differ in the old and new state
+	Modified bool
+}
+
+// templatesAPI implements TemplatesAPI
+type templatesAPI struct {
+	apiClient *domain.Client
+}
+
+// NewTemplatesAPI creates new instance of TemplatesAPI
+func NewTemplatesAPI(apiClient *domain.Client) TemplatesAPI {
+	return &templatesAPI{
+		apiClient: apiClient,
+	}
+}
+
+func (t *templatesAPI) ApplyTemplate(ctx context.Context, org *domain.Organization, params *TemplateApplyParams) (*TemplateApplyResult, error) {
+	return t.ApplyTemplateWithOrgID(ctx, *org.Id, params)
+}
+
+func (t *templatesAPI) ApplyTemplateWithOrgID(ctx context.Context, orgID string, params *TemplateApplyParams) (*TemplateApplyResult, error) {
+	if len(params.Templates) == 0 && len(params.RemoteURLs) == 0 {
+		return nil, errors.New("no template to apply")
+	}
+	body := domain.ApplyTemplateJSONRequestBody{
+		OrgID:  &orgID,
+		DryRun: &params.DryRun,
+	}
+	if params.StackID != "" {
+		body.StackID = &params.StackID
+	}
+	if len(params.Templates) > 0 {
+		templates := make([]struct {
+			ContentType *string          `json:"contentType,omitempty"`
+			Contents    *domain.Template `json:"contents,omitempty"`
+			Sources     *[]string        `json:"sources,omitempty"`
+		}, len(params.Templates))
+		for i := range params.Templates {
+			templates[i].Contents = &params.Templates[i]
+		}
+		body.Templates = &templates
+	}
+	if len(params.RemoteURLs) > 0 {
+		remotes := make([]struct {
+			ContentType *string `json:"contentType,omitempty"`
+			Url         string  `json:"url"`
+		}, len(params.RemoteURLs))
+		for i, u := range params.RemoteURLs {
+			remotes[i].Url = u
+		}
+		body.Remotes = &remotes
+	}
+	if len(params.EnvRefs) > 0 {
+		body.EnvRefs = &domain.TemplateApply_EnvRefs{AdditionalProperties: params.EnvRefs}
+	}
+	if len(params.Secrets) > 0 {
+		body.Secrets = &domain.TemplateApply_Secrets{AdditionalProperties: params.Secrets}
+	}
+	if len(params.SkipKinds) > 0 || len(params.SkipResources) > 0 {
+		actions := make([]interface{}, 0, len(params.SkipKinds)+len(params.SkipResources))
+		for _, kind := range params.SkipKinds {
+			actions = append(actions, map[string]interface{}{
+				"action":     "skipKind",
+				"properties": map[string]interface{}{"kind": kind},
+			})
+		}
+		for _, r := range params.SkipResources {
+			actions = append(actions, map[string]interface{}{
+				"action":     "skipResource",
+				"properties": map[string]interface{}{"kind": r.Kind, "resourceTemplateName": r.TemplateMetaName},
+			})
+		}
+		body.Actions = &actions
+	}
+	summary, err := t.apiClient.ApplyTemplate(ctx, &domain.ApplyTemplateAllParams{Body: body})
+	if err != nil {
+		return nil, err
+	}
+	changes, err := templateChanges(summary)
+	if err != nil {
+		return nil, err
+	}
+	result := &TemplateApplyResult{Changes: changes, Summary: summary}
+	if summary.StackID != nil {
+		result.StackID = *summary.StackID
+	}
+	return result, nil
+}
+
+// templateChanges returns changes of resources of all kinds in the diff of summary, label mappings are not included
+func templateChanges(summary *domain.TemplateSummary) ([]TemplateChange, error) {
+	if summary.Diff == nil {
+		return nil, nil
+	}
+	// diff of each kind has the same structure, except for old and new state
+	data, err := json.Marshal(summary.Diff)
+	if err != nil {
+		return nil, err
+	}
+	var diff struct {
+		Buckets               []resourceDiff `json:"buckets"`
+		Checks                []resourceDiff `json:"checks"`
+		Dashboards            []resourceDiff `json:"dashboards"`
+		Labels                []resourceDiff `json:"labels"`
+		NotificationEndpoints []resourceDiff `json:"notificationEndpoints"`
+		NotificationRules     []resourceDiff `json:"notificationRules"`
+		Tasks                 []resourceDiff `json:"tasks"`
+		TelegrafConfigs       []resourceDiff `json:"telegrafConfigs"`
+		Variables             []resourceDiff `json:"variables"`
+	}
+	if err := json.Unmarshal(data, &diff); err != nil {
+		return nil, err
+	}
+	var changes []TemplateChange
+	for _, kind := range [][]resourceDiff{diff.Buckets, diff.Checks, diff.Dashboards, diff.Labels, diff.NotificationEndpoints,
+		diff.NotificationRules, diff.Tasks, diff.TelegrafConfigs, diff.Variables} {
+		for _, d := range kind {
+			changes = append(changes, TemplateChange{
+				TemplateResource: TemplateResource{Kind: d.Kind, TemplateMetaName: d.TemplateMetaName},
+				ID:               d.ID,
+				Status:           d.StateStatus,
+				Modified:         !reflect.DeepEqual(d.Old, d.New),
+			})
+		}
+	}
+	return changes, nil
+}
+
+// resourceDiff is diff of a resource of any kind
+type resourceDiff struct {
+	ID               string               `json:"id"`
+	Kind             domain.TemplateKind  `json:"kind"`
+	StateStatus      TemplateChangeStatus `json:"stateStatus"`
+	TemplateMetaName string               `json:"templateMetaName"`
+	Old              interface{}          `json:"old"`
+	New              interface{}          `json:"new"`
+}
+
+// ParseTemplate parses contents of a template file in YAML or JSON, as exported by influx CLI.
+// The file contains a list of resources, or resources as multiple YAML documents.
+func ParseTemplate(data []byte) (domain.Template, error) {
+	var resources []interface{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		switch d := doc.(type) {
+		case []interface{}:
+			resources = append(resources, d...)
+		case map[string]interface{}:
+			resources = append(resources, d)
+		case nil:
+		default:
+			return nil, fmt.Errorf("invalid template: unexpected %T", doc)
+		}
+	}
+	// convert into the template structure via JSON
+	js, err := json.Marshal(resources)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	var template domain.Template
+	if err := json.Unmarshal(js, &template); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return template, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTemplate = `apiVersion: influxdata.com/v2alpha1
+kind: Bucket
+metadata:
+  name: my-bucket
+spec:
+  name:
+    envRef:
+      key: bucket-name
+  retentionRules:
+    - everySeconds: 3600
+      type: expire
+---
+apiVersion: influxdata.com/v2alpha1
+kind: Task
+metadata:
+  name: my-task
+spec:
+  every: 1h
+  query: 'from(bucket: "my-bucket") |> range(start: -1h)'
+`
+
+func TestParseTemplate(t *testing.T) {
+	template, err := ParseTemplate([]byte(testTemplate))
+	require.NoError(t, err)
+	require.Len(t, template, 2)
+	assert.Equal(t, domain.TemplateKindBucket, *template[0].Kind)
+	assert.Equal(t, "my-bucket", *template[0].Metadata.Name)
+	assert.Equal(t, map[string]interface{}{"envRef": map[string]interface{}{"key": "bucket-name"}}, (*template[0].Spec)["name"])
+	assert.Equal(t, domain.TemplateKindTask, *template[1].Kind)
+
+	// JSON list of resources, as exported by influx CLI
+	js, err := json.Marshal(template)
+	require.NoError(t, err)
+	parsed, err := ParseTemplate(js)
+	require.NoError(t, err)
+	assert.Equal(t, template, parsed)
+
+	_, err = ParseTemplate([]byte("- a\n- b"))
+	require.Error(t, err)
+	_, err = ParseTemplate([]byte("a: [b"))
+	require.Error(t, err)
+}
+
+func TestTemplatesAPI_ApplyTemplate(t *testing.T) {
+	var request map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/templates/apply", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		body, _ := io.ReadAll(r.Body)
+		request = nil
+		require.NoError(t, json.Unmarshal(body, &request))
+		writeJSON(w, http.StatusOK, `{"stackID":"s1","sources":["byte stream"],
+			"diff":{
+				"buckets":[{"kind":"Bucket","stateStatus":"existing","id":"b1","templateMetaName":"my-bucket",
+					"old":{"name":"prod","retentionRules":[{"everySeconds":60,"type":"expire"}]},
+					"new":{"name":"prod","retentionRules":[{"everySeconds":3600,"type":"expire"}]}}],
+				"tasks":[{"kind":"Task","stateStatus":"new","templateMetaName":"my-task","new":{"name":"my-task","every":"1h"}}],
+				"labels":[{"kind":"Label","stateStatus":"existing","id":"l1","templateMetaName":"my-label","old":{"name":"l"},"new":{"name":"l"}}],
+				"labelMappings":[{"status":"new","labelName":"l","resourceName":"prod","resourceType":"buckets"}]
+			},
+			"summary":{"buckets":[{"kind":"Bucket","id":"b1","name":"prod","templateMetaName":"my-bucket"}],"missingSecrets":["TOKEN"]}}`)
+	})
+	templatesAPI := NewTemplatesAPI(newTestAPIClient(t, mux))
+	template, err := ParseTemplate([]byte(testTemplate))
+	require.NoError(t, err)
+	orgID := "o1"
+
+	res, err := templatesAPI.ApplyTemplate(context.Background(), &domain.Organization{Id: &orgID}, &TemplateApplyParams{
+		Templates:     []domain.Template{template},
+		RemoteURLs:    []string{"https://example.com/template.yml"},
+		DryRun:        true,
+		EnvRefs:       map[string]interface{}{"bucket-name": "prod"},
+		Secrets:       map[string]string{"TOKEN": "secret"},
+		StackID:       "s1",
+		SkipKinds:     []domain.TemplateKind{domain.TemplateKindDashboard},
+		SkipResources: []TemplateResource{{Kind: domain.TemplateKindLabel, TemplateMetaName: "my-label"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "o1", request["orgID"])
+	assert.Equal(t, true, request["dryRun"])
+	assert.Equal(t, "s1", request["stackID"])
+	assert.Equal(t, map[string]interface{}{"bucket-name": "prod"}, request["envRefs"])
+	assert.Equal(t, map[string]interface{}{"TOKEN": "secret"}, request["secrets"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "https://example.com/template.yml"}}, request["remotes"])
+	require.Len(t, request["templates"], 1)
+	assert.Len(t, request["templates"].([]interface{})[0].(map[string]interface{})["contents"], 2)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"action": "skipKind", "properties": map[string]interface{}{"kind": "Dashboard"}},
+		map[string]interface{}{"action": "skipResource", "properties": map[string]interface{}{"kind": "Label", "resourceTemplateName": "my-label"}},
+	}, request["actions"])
+
+	assert.Equal(t, "s1", res.StackID)
+	assert.Equal(t, []TemplateChange{
+		{TemplateResource: TemplateResource{Kind: domain.TemplateKindBucket, TemplateMetaName: "my-bucket"}, ID: "b1", Status: TemplateChangeExisting, Modified: true},
+		{TemplateResource: TemplateResource{Kind: domain.TemplateKindLabel, TemplateMetaName: "my-label"}, ID: "l1", Status: TemplateChangeExisting},
+		{TemplateResource: TemplateResource{Kind: domain.TemplateKindTask, TemplateMetaName: "my-task"}, Status: TemplateChangeNew, Modified: true},
+	}, res.Changes)
+	require.NotNil(t, res.Summary.Summary)
+	assert.Equal(t, "prod", *(*res.Summary.Summary.Buckets)[0].Name)
+	assert.Equal(t, []string{"TOKEN"}, *res.Summary.Summary.MissingSecrets)
+	assert.Len(t, *res.Summary.Diff.LabelMappings, 1)
+
+	// minimal request
+	_, err = templatesAPI.ApplyTemplateWithOrgID(context.Background(), "o1", &TemplateApplyParams{RemoteURLs: []string{"https://example.com/t.json"}})
+	require.NoError(t, err)
+	assert.Equal(t, false, request["dryRun"])
+	assert.NotContains(t, request, "templates")
+	assert.NotContains(t, request, "stackID")
+	assert.NotContains(t, request, "actions")
+
+	_, err = templatesAPI.ApplyTemplateWithOrgID(context.Background(), "o1", &TemplateApplyParams{})
+	assert.EqualError(t, err, "no template to apply")
+}
+
+func TestTemplatesAPI_ApplyTemplateError(t *testing.T) {
+	fail := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/templates/apply", func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			writeJSON(w, http.StatusInternalServerError, `{"code":"internal error","message":"oops"}`)
+			return
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, `{"code":"unprocessable entity","message":"template failed validation",
+			"errors":[{"kind":"Bucket","reason":"invalid retention","fields":["spec","retentionRules"],"indexes":[0]}]}`)
+	})
+	templatesAPI := NewTemplatesAPI(newTestAPIClient(t, mux))
+
+	_, err := templatesAPI.ApplyTemplateWithOrgID(context.Background(), "o1", &TemplateApplyParams{RemoteURLs: []string{"https://example.com/t.yml"}})
+	var applyErr *domain.TemplateApplyError
+	require.ErrorAs(t, err, &applyErr)
+	assert.EqualError(t, err, "unprocessable entity: template failed validation")
+	require.NotNil(t, applyErr.Errors)
+	assert.Equal(t, "invalid retention", *(*applyErr.Errors)[0].Reason)
+	assert.Equal(t, []string{"spec", "retentionRules"}, *(*applyErr.Errors)[0].Fields)
+
+	fail = true
+	_, err = templatesAPI.ApplyTemplateWithOrgID(context.Background(), "o1", &TemplateApplyParams{RemoteURLs: []string{"https://example.com/t.yml"}})
+	assert.EqualError(t, err, "internal error: oops")
+	assert.False(t, errors.As(err, &applyErr))
+}
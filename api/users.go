@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"iter"
 	nethttp "net/http"
 	"net/http/cookiejar"
 	"sync"
@@ -21,6 +22,11 @@ import (
 type UsersAPI interface {
 	// GetUsers returns all users
 	GetUsers(ctx context.Context) (*[]domain.User, error)
+	// IterateUsers returns an iterator over all users, which are fetched page by page on demand.
+	// IterateUsers supports PagingOptions: Offset, Limit, After. Limit sets the page size, default is 100.
+	// Pages are followed using After, if it is set, otherwise using Offset.
+	// Iteration ends with the first error.
+	IterateUsers(ctx context.Context, pagingOptions ...PagingOption) iter.Seq2[domain.User, error]
 	// FindUserByID returns user with userID
 	FindUserByID(ctx context.Context, userID string) (*domain.User, error)
 	// FindUserByName returns user with name userName
@@ -76,6 +82,33 @@ func (u *usersAPI) GetUsers(ctx context.Context) (*[]domain.User, error) {
 	return userResponsesToUsers(response.Users), nil
 }
 
+func (u *usersAPI) IterateUsers(ctx context.Context, pagingOptions ...PagingOption) iter.Seq2[domain.User, error] {
+	paging := newPaging(pagingOptions...)
+	cursor := offsetCursor
+	if paging.after != "" {
+		cursor = afterCursor
+	}
+	return iteratePages(paging, cursor, func(p *Paging) ([]domain.User, error) {
+		params := &domain.GetUsersParams{Limit: &p.limit}
+		if p.after != "" {
+			params.After = &p.after
+		} else {
+			params.Offset = &p.offset
+		}
+		response, err := u.apiClient.GetUsers(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		users := userResponsesToUsers(response.Users)
+		if users == nil {
+			return nil, nil
+		}
+		return *users, nil
+	}, func(user *domain.User) *string {
+		return user.Id
+	})
+}
+
 func (u *usersAPI) FindUserByID(ctx context.Context, userID string) (*domain.User, error) {
 	params := &domain.GetUsersIDAllParams{
 		UserID: userID,
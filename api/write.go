@@ -6,6 +6,8 @@ package api
 
 import (
 	"context"
+	"hash/fnv"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -29,15 +31,30 @@ type WriteFailedCallback func(batch string, error http2.Error, retryAttempts uin
 type WriteAPI interface {
 	// WriteRecord writes asynchronously line protocol record into bucket.
 	// WriteRecord adds record into the buffer which is sent on the background when it reaches the batch size.
+	// If records validation is enabled in write.Options, invalid lines are skipped and reported via Errors() as *write.ParseError.
+	// When the memory budget set in write.Options is exceeded, the record can be discarded or WriteRecord blocks, according to the overflow policy.
 	// Blocking alternative is available in the WriteAPIBlocking interface
 	WriteRecord(line string)
 	// WritePoint writes asynchronously Point into bucket.
 	// WritePoint adds Point into the buffer which is sent on the background when it reaches the batch size.
+	// When the memory budget set in write.Options is exceeded, the point can be discarded or WritePoint blocks, according to the overflow policy.
 	// Blocking alternative is available in the WriteAPIBlocking interface
 	WritePoint(point *write.Point)
+	// TryWriteRecord adds line protocol record into the buffer without blocking.
+	// It returns false if the record was not added, because the buffer is full or the memory budget set in write.Options is exceeded.
+	// Invalid records, when records validation is enabled, are reported via Errors().
+	TryWriteRecord(line string) bool
+	// TryWritePoint adds Point into the buffer without blocking.
+	// It returns false if the point was not added, because the buffer is full or the memory budget set in write.Options is exceeded.
+	// Encoding errors are reported via Errors().
+	TryWritePoint(point *write.Point) bool
+	// WritePointCtx adds Point into the buffer, it waits until there is space in the buffer or ctx is done.
+	// Errors of adding the point, such as encoding errors or ctx errors, are returned, not reported via Errors().
+	WritePointCtx(ctx context.Context, point *write.Point) error
 	// Flush forces all pending writes from the buffer to be sent
 	Flush()
 	// Errors returns a channel for reading errors which occurs during async writes.
+	// When the server rejects some lines of a batch, *write.PartialWriteError lists the rejected records and points.
 	// Must be called before performing any writes for errors to be collected.
 	// The chan is unbuffered and must be drained or the writer will block.
 	Errors() <-chan error
@@ -48,18 +65,20 @@ type WriteAPI interface {
 
 // WriteAPIImpl provides main implementation for WriteAPI
 type WriteAPIImpl struct {
-	service     *iwrite.Service
-	writeBuffer []string
+	// service of the first writer, it encodes points and holds the memory budget
+	service *iwrite.Service
+	// writers send batches concurrently, there is a single writer unless concurrency is set in write options
+	writers []*batchWriter
+	// buffers of batches, each buffer for a writer in case of ordered writes, otherwise a single buffer shared by writers
+	buffers []*lineBuffer
 
 	errCh        chan error
-	writeCh      chan *iwrite.Batch
-	bufferCh     chan string
+	bufferCh     chan bufferedLine
 	writeStop    chan struct{}
 	bufferStop   chan struct{}
 	bufferFlush  chan struct{}
 	doneCh       chan struct{}
 	bufferInfoCh chan writeBuffInfoReq
-	writeInfoCh  chan writeBuffInfoReq
 	writeOptions *write.Options
 	closingMu    *sync.Mutex
 	// more appropriate Bool type from sync/atomic cannot be used because it is available since go 1.19
@@ -70,26 +89,102 @@ type writeBuffInfoReq struct {
 	writeBuffLen int
 }
 
+// batchWriter writes batches received from writeCh
+type batchWriter struct {
+	service *iwrite.Service
+	writeCh chan *iwrite.Batch
+	infoCh  chan writeBuffInfoReq
+	// flushCh receives requests to flush the retry queue of service, the received channel is closed when it is flushed
+	flushCh chan chan struct{}
+}
+
+// lineBuffer collects lines of a batch
+type lineBuffer struct {
+	lines []string
+	// points holds points of lines, nil for lines of records
+	points []*write.Point
+	// bytes is the size of lines
+	bytes int
+	// writeCh receives batches of the buffer
+	writeCh chan *iwrite.Batch
+}
+
+// add adds line to the buffer, flush is called when the buffer reaches the batch size or the maximum batch bytes of options
+func (b *lineBuffer) add(l bufferedLine, options *write.Options, flush func(b *lineBuffer)) {
+	maxBytes := int(options.MaxBatchBytes())
+	if maxBytes > 0 && b.bytes+len(l.line) > maxBytes {
+		flush(b)
+	}
+	b.lines = append(b.lines, l.line)
+	b.bytes += len(l.line)
+	for range strings.Count(l.line, "\n") {
+		b.points = append(b.points, l.point)
+	}
+	if len(b.points) >= int(options.BatchSize()) || (maxBytes > 0 && b.bytes >= maxBytes) {
+		flush(b)
+	}
+}
+
+// take returns lines of the buffer as a batch and empties the buffer, it returns nil if the buffer is empty
+func (b *lineBuffer) take(options *write.Options) *iwrite.Batch {
+	if len(b.lines) == 0 {
+		return nil
+	}
+	batch := iwrite.NewBatch(buffer(b.lines), options.MaxRetryTime())
+	if slices.ContainsFunc(b.points, func(p *write.Point) bool { return p != nil }) {
+		batch.Points = slices.Clone(b.points)
+	}
+	b.lines = b.lines[:0]
+	b.points = b.points[:0]
+	b.bytes = 0
+	return batch
+}
+
+// bufferedLine is a record or an encoded point sent to the buffer, line ends with new line
+type bufferedLine struct {
+	line  string
+	point *write.Point
+}
+
 // NewWriteAPI returns new non-blocking write client for writing data to  bucket belonging to org
 func NewWriteAPI(org string, bucket string, service http2.Service, writeOptions *write.Options) *WriteAPIImpl {
+	return newWriteAPI(iwrite.NewWorkers(org, bucket, service, writeOptions, int(writeOptions.Concurrency())), writeOptions)
+}
+
+// NewWriteAPIV1 returns new non-blocking write client for writing data to database and retention policy of target
+// using the /write endpoint of InfluxDB 1.x
+func NewWriteAPIV1(target write.V1Target, service http2.Service, writeOptions *write.Options) *WriteAPIImpl {
+	return newWriteAPI(iwrite.NewV1Workers(target, service, writeOptions, int(writeOptions.Concurrency())), writeOptions)
+}
+
+// newWriteAPI returns new non-blocking write client writing batches by services
+func newWriteAPI(services []*iwrite.Service, writeOptions *write.Options) *WriteAPIImpl {
 	w := &WriteAPIImpl{
-		service:      iwrite.NewService(org, bucket, service, writeOptions),
 		errCh:        make(chan error, 1),
-		writeBuffer:  make([]string, 0, writeOptions.BatchSize()+1),
-		writeCh:      make(chan *iwrite.Batch),
-		bufferCh:     make(chan string),
+		bufferCh:     make(chan bufferedLine, writeOptions.BufferCapacity()),
 		bufferStop:   make(chan struct{}),
 		writeStop:    make(chan struct{}),
 		bufferFlush:  make(chan struct{}),
 		doneCh:       make(chan struct{}),
 		bufferInfoCh: make(chan writeBuffInfoReq),
-		writeInfoCh:  make(chan writeBuffInfoReq),
 		writeOptions: writeOptions,
 		closingMu:    &sync.Mutex{},
 	}
+	w.service = services[0]
+	ordered := writeOptions.OrderedWrites()
+	var writeCh chan *iwrite.Batch
+	for _, s := range services {
+		if ordered || writeCh == nil {
+			writeCh = make(chan *iwrite.Batch)
+			w.buffers = append(w.buffers, &lineBuffer{lines: make([]string, 0, writeOptions.BatchSize()+1), writeCh: writeCh})
+		}
+		w.writers = append(w.writers, &batchWriter{service: s, writeCh: writeCh, infoCh: make(chan writeBuffInfoReq), flushCh: make(chan chan struct{})})
+	}
 
 	go w.bufferProc()
-	go w.writeProc()
+	for _, bw := range w.writers {
+		go w.writeProc(bw)
+	}
 
 	return w
 }
@@ -97,9 +192,11 @@ func NewWriteAPI(org string, bucket string, service http2.Service, writeOptions
 // SetWriteFailedCallback sets callback allowing custom handling of failed writes.
 // If callback returns true, failed batch will be retried, otherwise discarded.
 func (w *WriteAPIImpl) SetWriteFailedCallback(cb WriteFailedCallback) {
-	w.service.SetBatchErrorCallback(func(batch *iwrite.Batch, error2 http2.Error) bool {
-		return cb(batch.Batch, error2, batch.RetryAttempts)
-	})
+	for _, bw := range w.writers {
+		bw.service.SetBatchErrorCallback(func(batch *iwrite.Batch, error2 http2.Error) bool {
+			return cb(batch.Batch, error2, batch.RetryAttempts)
+		})
+	}
 }
 
 // Errors returns a channel for reading errors which occurs during async writes.
@@ -115,7 +212,12 @@ func (w *WriteAPIImpl) Errors() <-chan error {
 func (w *WriteAPIImpl) Flush() {
 	w.bufferFlush <- struct{}{}
 	w.waitForFlushing()
-	w.service.Flush()
+	// retry queues are flushed by write procs, which also retry them
+	for _, bw := range w.writers {
+		done := make(chan struct{})
+		bw.flushCh <- done
+		<-done
+	}
 }
 
 func (w *WriteAPIImpl) waitForFlushing() {
@@ -128,14 +230,16 @@ func (w *WriteAPIImpl) waitForFlushing() {
 		log.Info("Waiting buffer is flushed")
 		<-time.After(time.Millisecond)
 	}
-	for {
-		w.writeInfoCh <- writeBuffInfoReq{}
-		writeBuffInfo := <-w.writeInfoCh
-		if writeBuffInfo.writeBuffLen == 0 {
-			break
+	for _, bw := range w.writers {
+		for {
+			bw.infoCh <- writeBuffInfoReq{}
+			writeBuffInfo := <-bw.infoCh
+			if writeBuffInfo.writeBuffLen == 0 {
+				break
+			}
+			log.Info("Waiting buffer is flushed")
+			<-time.After(time.Millisecond)
 		}
-		log.Info("Waiting buffer is flushed")
-		<-time.After(time.Millisecond)
 	}
 }
 
@@ -145,17 +249,16 @@ func (w *WriteAPIImpl) bufferProc() {
 x:
 	for {
 		select {
-		case line := <-w.bufferCh:
-			w.writeBuffer = append(w.writeBuffer, line)
-			if len(w.writeBuffer) == int(w.writeOptions.BatchSize()) {
-				w.flushBuffer()
-			}
+		case l := <-w.bufferCh:
+			w.addToBuffer(l)
 		case <-ticker.C:
 			w.flushBuffer()
 		case <-w.bufferFlush:
+			w.drainBufferCh()
 			w.flushBuffer()
 		case <-w.bufferStop:
 			ticker.Stop()
+			w.drainBufferCh()
 			w.flushBuffer()
 			break x
 		case buffInfo := <-w.bufferInfoCh:
@@ -167,12 +270,47 @@ x:
 	w.doneCh <- struct{}{}
 }
 
+// addToBuffer adds line to a write buffer, in case of ordered writes lines are distributed to buffers by series
+func (w *WriteAPIImpl) addToBuffer(l bufferedLine) {
+	if len(w.buffers) == 1 {
+		w.addToLineBuffer(w.buffers[0], l)
+		return
+	}
+	for line := range strings.SplitAfterSeq(l.line, "\n") {
+		if line != "" {
+			w.addToLineBuffer(w.buffers[seriesIndex(line, len(w.buffers))], bufferedLine{line: line, point: l.point})
+		}
+	}
+}
+
+// addToLineBuffer adds line to buffer b, b is flushed when it reaches the batch size or the maximum batch bytes
+func (w *WriteAPIImpl) addToLineBuffer(b *lineBuffer, l bufferedLine) {
+	b.add(l, w.writeOptions, w.flushLineBuffer)
+}
+
+// drainBufferCh adds lines waiting in the buffered channel to the write buffer
+func (w *WriteAPIImpl) drainBufferCh() {
+	for {
+		select {
+		case l := <-w.bufferCh:
+			w.addToBuffer(l)
+		default:
+			return
+		}
+	}
+}
+
 func (w *WriteAPIImpl) flushBuffer() {
-	if len(w.writeBuffer) > 0 {
+	for _, b := range w.buffers {
+		w.flushLineBuffer(b)
+	}
+}
+
+// flushLineBuffer sends lines of buffer b as a batch to writers
+func (w *WriteAPIImpl) flushLineBuffer(b *lineBuffer) {
+	if batch := b.take(w.writeOptions); batch != nil {
 		log.Info("sending batch")
-		batch := iwrite.NewBatch(buffer(w.writeBuffer), w.writeOptions.MaxRetryTime())
-		w.writeCh <- batch
-		w.writeBuffer = w.writeBuffer[:0]
+		b.writeCh <- batch
 	}
 }
 func (w *WriteAPIImpl) isErrChanRead() bool {
@@ -183,26 +321,33 @@ func (w *WriteAPIImpl) setErrChanRead() {
 	atomic.StoreInt32(&w.isErrChReader, 1)
 }
 
-func (w *WriteAPIImpl) writeProc() {
+// writeProc writes batches of bw, batches in the retry queue are retried also when no new batch comes, in flush intervals
+func (w *WriteAPIImpl) writeProc(bw *batchWriter) {
 	log.Info("Write proc started")
+	ticker := time.NewTicker(time.Duration(w.writeOptions.FlushInterval()) * time.Millisecond)
 x:
 	for {
 		select {
-		case batch := <-w.writeCh:
-			err := w.service.HandleWrite(context.Background(), batch)
-			if err != nil && w.isErrChanRead() {
-				select {
-				case w.errCh <- err:
-				default:
-					log.Warn("Cannot write error to error channel, it is not read")
+		case batch := <-bw.writeCh:
+			if err := bw.service.HandleWrite(context.Background(), batch); err != nil {
+				w.reportError(err)
+			}
+		case <-ticker.C:
+			if bw.service.HasRetries() {
+				if err := bw.service.HandleWrite(context.Background(), nil); err != nil {
+					w.reportError(err)
 				}
 			}
 		case <-w.writeStop:
 			log.Info("Write proc: received stop")
+			ticker.Stop()
 			break x
-		case buffInfo := <-w.writeInfoCh:
-			buffInfo.writeBuffLen = len(w.writeCh)
-			w.writeInfoCh <- buffInfo
+		case buffInfo := <-bw.infoCh:
+			buffInfo.writeBuffLen = len(bw.writeCh)
+			bw.infoCh <- buffInfo
+		case done := <-bw.flushCh:
+			bw.service.Flush()
+			close(done)
 		}
 	}
 	log.Info("Write proc finished")
@@ -214,7 +359,7 @@ x:
 func (w *WriteAPIImpl) Close() {
 	w.closingMu.Lock()
 	defer w.closingMu.Unlock()
-	if w.writeCh != nil {
+	if w.writers != nil {
 		// Flush outstanding metrics
 		w.Flush()
 
@@ -225,14 +370,20 @@ func (w *WriteAPIImpl) Close() {
 		close(w.bufferFlush)
 		close(w.bufferCh)
 
-		// stop and wait for write proc
+		// stop and wait for write procs
 		close(w.writeStop)
-		<-w.doneCh
-
-		close(w.writeCh)
-		close(w.writeInfoCh)
+		for range w.writers {
+			<-w.doneCh
+		}
+		for _, bw := range w.writers {
+			close(bw.infoCh)
+			close(bw.flushCh)
+		}
+		for _, b := range w.buffers {
+			close(b.writeCh)
+		}
 		close(w.bufferInfoCh)
-		w.writeCh = nil
+		w.writers = nil
 
 		close(w.errCh)
 		w.errCh = nil
@@ -243,9 +394,44 @@ func (w *WriteAPIImpl) Close() {
 // WriteRecord adds record into the buffer which is sent on the background when it reaches the batch size.
 // Blocking alternative is available in the WriteAPIBlocking interface
 func (w *WriteAPIImpl) WriteRecord(line string) {
-	b := []byte(line)
+	record, ok := w.prepareRecord(line)
+	if !ok {
+		return
+	}
+	if err := w.service.AcquireBuffer(context.Background(), record, nil); err != nil {
+		w.reportError(err)
+		return
+	}
+	w.bufferCh <- bufferedLine{line: record}
+}
+
+// TryWriteRecord adds line protocol record into the buffer without blocking.
+// It returns false if the buffer is full or the memory budget is exceeded.
+func (w *WriteAPIImpl) TryWriteRecord(line string) bool {
+	record, ok := w.prepareRecord(line)
+	if !ok {
+		return true
+	}
+	return w.tryAddLine(bufferedLine{line: record})
+}
+
+// prepareRecord validates record, if enabled, freezes missing timestamps of idempotent writes and appends new line.
+// It returns false if there is nothing to write.
+func (w *WriteAPIImpl) prepareRecord(line string) (string, bool) {
+	if w.writeOptions.ValidateRecords() {
+		var errs []error
+		line, errs = validRecords(line, w.writeOptions.Precision())
+		for _, err := range errs {
+			log.Errorf("invalid record: %s\n", err.Error())
+			w.reportError(err)
+		}
+		if line == "" {
+			return "", false
+		}
+	}
+	b := []byte(w.service.FreezeTimestamps(line))
 	b = append(b, 0xa)
-	w.bufferCh <- string(b)
+	return string(b), true
 }
 
 // WritePoint writes asynchronously Point into bucket.
@@ -255,14 +441,101 @@ func (w *WriteAPIImpl) WritePoint(point *write.Point) {
 	line, err := w.service.EncodePoints(point)
 	if err != nil {
 		log.Errorf("point encoding error: %s\n", err.Error())
-		if w.errCh != nil {
-			w.errCh <- err
-		}
+		w.reportError(err)
+	} else if err := w.service.AcquireBuffer(context.Background(), line, point); err != nil {
+		w.reportError(err)
 	} else {
-		w.bufferCh <- line
+		w.bufferCh <- bufferedLine{line: line, point: point}
+	}
+}
+
+// TryWritePoint adds Point into the buffer without blocking.
+// It returns false if the buffer is full or the memory budget is exceeded.
+func (w *WriteAPIImpl) TryWritePoint(point *write.Point) bool {
+	line, err := w.service.EncodePoints(point)
+	if err != nil {
+		log.Errorf("point encoding error: %s\n", err.Error())
+		w.reportError(err)
+		return true
 	}
+	return w.tryAddLine(bufferedLine{line: line, point: point})
+}
+
+// WritePointCtx adds Point into the buffer, it waits until there is space in the buffer or ctx is done
+func (w *WriteAPIImpl) WritePointCtx(ctx context.Context, point *write.Point) error {
+	line, err := w.service.EncodePoints(point)
+	if err != nil {
+		return err
+	}
+	if err := w.service.AcquireBuffer(ctx, line, point); err != nil {
+		return err
+	}
+	select {
+	case w.bufferCh <- bufferedLine{line: line, point: point}:
+		return nil
+	case <-ctx.Done():
+		w.service.ReleaseBuffer(line)
+		return ctx.Err()
+	}
+}
+
+// tryAddLine sends l to the buffer without blocking, it returns false if the buffer is full or the memory budget is exceeded
+func (w *WriteAPIImpl) tryAddLine(l bufferedLine) bool {
+	if !w.service.TryAcquireBuffer(l.line) {
+		return false
+	}
+	select {
+	case w.bufferCh <- l:
+		return true
+	default:
+		w.service.ReleaseBuffer(l.line)
+		return false
+	}
+}
+
+// reportError sends err to the errors channel without blocking, if it is read
+func (w *WriteAPIImpl) reportError(err error) {
+	if !w.isErrChanRead() {
+		return
+	}
+	select {
+	case w.errCh <- err:
+	default:
+		log.Warn("Cannot write error to error channel, it is not read")
+	}
+}
+
+// seriesIndex returns index of the buffer for series of line, from 0 to n-1.
+// Series is identified by the measurement and tags, which end at the first unescaped space.
+func seriesIndex(line string, n int) int {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+		} else if line[i] == ' ' {
+			end = i
+			break
+		}
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(line[:end]))
+	return int(h.Sum32() % uint32(n))
 }
 
 func buffer(lines []string) string {
 	return strings.Join(lines, "")
 }
+
+// validRecords returns valid lines of line protocol record joined by new line, and errors of invalid lines
+func validRecords(record string, precision time.Duration) (string, []error) {
+	var lines []string
+	var errs []error
+	for line, err := range write.ParseLines(record, precision) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lines = append(lines, line.Text)
+	}
+	return strings.Join(lines, "\n"), errs
+}
@@ -0,0 +1,130 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeadLetterReason is the reason why a batch was discarded
+type DeadLetterReason string
+
+const (
+	// DeadLetterRetryBufferFull means the batch was the oldest one in the full retry buffer
+	DeadLetterRetryBufferFull DeadLetterReason = "retry buffer full"
+	// DeadLetterMaxRetries means writing of the batch failed after the maximum number of retries
+	DeadLetterMaxRetries DeadLetterReason = "max retries"
+	// DeadLetterExpired means the batch was not written within the maximum retry time
+	DeadLetterExpired DeadLetterReason = "expired"
+	// DeadLetterCallbackRejected means the write failed callback rejected retrying of the batch
+	DeadLetterCallbackRejected DeadLetterReason = "callback rejected"
+	// DeadLetterIgnoredError means the server returned an error which is not retried, such as "points beyond retention policy"
+	DeadLetterIgnoredError DeadLetterReason = "ignored error"
+	// DeadLetterWriteFailed means writing failed with an error which is not retried
+	DeadLetterWriteFailed DeadLetterReason = "write failed"
+	// DeadLetterPartialWrite means the server rejected lines of the batch, only the rejected lines are in the dead letter
+	DeadLetterPartialWrite DeadLetterReason = "partial write"
+	// DeadLetterMemoryBudget means the batch was discarded because the memory budget was exceeded
+	DeadLetterMemoryBudget DeadLetterReason = "memory budget"
+)
+
+// DeadLetter is a batch discarded by the write API
+type DeadLetter struct {
+	// ID is the ID of the batch when writes are idempotent, empty otherwise
+	ID     string
+	Reason DeadLetterReason
+	// Batch is the discarded line protocol
+	Batch string
+	// Points are the discarded points indexed by line of Batch, nil for lines written as records
+	Points []*Point
+	// Err is the last write error, nil if the batch was discarded before it failed, e.g. when the retry buffer is full
+	Err error
+	// RetryAttempts is the number of retries of the batch
+	RetryAttempts uint
+	// Time is the time when the batch was discarded
+	Time time.Time
+}
+
+// DeadLetterSink receives batches discarded by the write API.
+// Send is called synchronously from the write goroutine, it should not block for long.
+// Errors returned by Send are logged.
+type DeadLetterSink interface {
+	Send(letter *DeadLetter) error
+}
+
+// ChannelDeadLetterSink delivers dead letters to a channel
+type ChannelDeadLetterSink struct {
+	ch chan *DeadLetter
+}
+
+// NewChannelDeadLetterSink creates ChannelDeadLetterSink with a channel of capacity.
+// When the channel is full, dead letters are not delivered and Send returns an error.
+func NewChannelDeadLetterSink(capacity int) *ChannelDeadLetterSink {
+	return &ChannelDeadLetterSink{ch: make(chan *DeadLetter, capacity)}
+}
+
+// Send delivers letter to the channel
+func (s *ChannelDeadLetterSink) Send(letter *DeadLetter) error {
+	select {
+	case s.ch <- letter:
+		return nil
+	default:
+		return errors.New("dead letter channel is full")
+	}
+}
+
+// Letters returns the channel for reading dead letters
+func (s *ChannelDeadLetterSink) Letters() <-chan *DeadLetter {
+	return s.ch
+}
+
+// FileDeadLetterSink appends dead letters to a file.
+// Each dead letter is written as a comment line with time, reason and error, followed by the line protocol of the batch.
+// The file can be written back to InfluxDB, comments are skipped.
+// FileDeadLetterSink can be used concurrently.
+type FileDeadLetterSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileDeadLetterSink creates FileDeadLetterSink appending to the file at path, the file is created if it doesn't exist
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open dead letter file: %w", err)
+	}
+	return &FileDeadLetterSink{file: f}, nil
+}
+
+// Send appends letter to the file
+func (s *FileDeadLetterSink) Send(letter *DeadLetter) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s %s", letter.Time.UTC().Format(time.RFC3339Nano), letter.Reason)
+	if letter.Err != nil {
+		sb.WriteString(": ")
+		sb.WriteString(strings.ReplaceAll(letter.Err.Error(), "\n", " "))
+	}
+	sb.WriteByte('\n')
+	sb.WriteString(letter.Batch)
+	if !strings.HasSuffix(letter.Batch, "\n") {
+		sb.WriteByte('\n')
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.file.WriteString(sb.String())
+	return err
+}
+
+// Close closes the file
+func (s *FileDeadLetterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,56 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelDeadLetterSink(t *testing.T) {
+	sink := write.NewChannelDeadLetterSink(1)
+	letter := &write.DeadLetter{Reason: write.DeadLetterMaxRetries, Batch: "m v=1\n"}
+	require.NoError(t, sink.Send(letter))
+	assert.EqualError(t, sink.Send(letter), "dead letter channel is full")
+	assert.Equal(t, letter, <-sink.Letters())
+	assert.NoError(t, sink.Send(letter))
+}
+
+func TestFileDeadLetterSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.lp")
+	ts := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	sink, err := write.NewFileDeadLetterSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(&write.DeadLetter{Reason: write.DeadLetterRetryBufferFull, Batch: "m v=1\nm v=2\n", Time: ts}))
+	require.NoError(t, sink.Send(&write.DeadLetter{Reason: write.DeadLetterWriteFailed, Batch: "m v=3", Err: errors.New("bad\nrequest"), Time: ts}))
+	require.NoError(t, sink.Close())
+
+	// appends to existing file
+	sink, err = write.NewFileDeadLetterSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(&write.DeadLetter{Reason: write.DeadLetterExpired, Batch: "m v=4\n", Time: ts}))
+	require.NoError(t, sink.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# 2021-03-04T05:06:07Z retry buffer full\nm v=1\nm v=2\n"+
+		"# 2021-03-04T05:06:07Z write failed: bad request\nm v=3\n"+
+		"# 2021-03-04T05:06:07Z expired\nm v=4\n", string(content))
+
+	// file is valid line protocol
+	points, err := write.ParseLineProtocol(string(content), time.Nanosecond)
+	require.NoError(t, err)
+	assert.Len(t, points, 4)
+
+	_, err = write.NewFileDeadLetterSink(filepath.Join(t.TempDir(), "missing", "dead.lp"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	lp "github.com/influxdata/line-protocol"
+)
+
+// errInvalidFieldKey is returned for field keys which cannot be encoded
+var errInvalidFieldKey = errors.New("invalid field key")
+
+// Encoder encodes points into InfluxDB line protocol.
+// Encoding doesn't allocate, once the internal buffers of Encoder and the destination slice are large enough.
+// Encoder is not safe for concurrent use.
+//
+// Tags with an empty key or value are skipped. Fields with float NaN or Inf value cause an error,
+// as well as points without fields.
+type Encoder struct {
+	precision   time.Duration
+	defaultTags map[string]string
+	// tags is a buffer for merging point tags with default tags
+	tags []lp.Tag
+	// defaultTime is the timestamp of points without timestamp, zero if they are encoded without timestamp
+	defaultTime time.Time
+}
+
+// NewEncoder creates an Encoder with nanosecond precision
+func NewEncoder() *Encoder {
+	return &Encoder{precision: time.Nanosecond}
+}
+
+// SetPrecision sets precision of timestamps. In unit of duration: time.Nanosecond, time.Microsecond, time.Millisecond, time.Second
+func (e *Encoder) SetPrecision(precision time.Duration) *Encoder {
+	e.precision = precision
+	return e
+}
+
+// SetDefaultTags sets tags added to each encoded point. If a point already has a tag with the same key, it is left unchanged.
+// When there are default tags, tags of the encoded line are sorted by key.
+func (e *Encoder) SetDefaultTags(tags map[string]string) *Encoder {
+	e.defaultTags = tags
+	return e
+}
+
+// SetDefaultTime sets timestamp of encoded points, which don't have timestamp.
+// Zero time means points without timestamp are encoded without it and the server assigns the time of writing.
+func (e *Encoder) SetDefaultTime(t time.Time) *Encoder {
+	e.defaultTime = t
+	return e
+}
+
+// Append appends line protocol line of point, including the trailing new line, to dst and returns the extended slice.
+// In case of an error, dst is returned unchanged.
+func (e *Encoder) Append(dst []byte, point *Point) ([]byte, error) {
+	start := len(dst)
+	if point.measurement == "" {
+		return dst, lp.ErrInvalidName
+	}
+	dst = appendEscaped(dst, point.measurement, nameEscapes)
+	if len(e.defaultTags) > 0 {
+		for _, t := range e.mergeDefaultTags(point.tags) {
+			dst = appendTag(dst, t.Key, t.Value)
+		}
+	} else {
+		for _, t := range point.tags {
+			dst = appendTag(dst, t.Key, t.Value)
+		}
+	}
+	dst = append(dst, ' ')
+	if len(point.fields) == 0 {
+		return dst[:start], lp.ErrNoFields
+	}
+	for i, f := range point.fields {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		var err error
+		if dst, err = appendField(dst, f.Key, f.Value); err != nil {
+			return dst[:start], err
+		}
+	}
+	if !point.timestamp.IsZero() {
+		dst = appendTimestamp(append(dst, ' '), point.timestamp, e.precision)
+	} else if !e.defaultTime.IsZero() {
+		dst = appendTimestamp(append(dst, ' '), e.defaultTime, e.precision)
+	}
+	return append(dst, '\n'), nil
+}
+
+// appendTimestamp appends timestamp t in units of precision to dst
+func appendTimestamp(dst []byte, t time.Time, precision time.Duration) []byte {
+	switch precision {
+	case time.Microsecond:
+		return strconv.AppendInt(dst, t.UnixNano()/1000, 10)
+	case time.Millisecond:
+		return strconv.AppendInt(dst, t.UnixNano()/1000000, 10)
+	case time.Second:
+		return strconv.AppendInt(dst, t.Unix(), 10)
+	default:
+		return strconv.AppendInt(dst, t.UnixNano(), 10)
+	}
+}
+
+// mergeDefaultTags returns tags with default tags, which are not overridden by tags, sorted by key
+func (e *Encoder) mergeDefaultTags(tags []*lp.Tag) []lp.Tag {
+	e.tags = e.tags[:0]
+	for _, t := range tags {
+		e.tags = append(e.tags, *t)
+	}
+x:
+	for k, v := range e.defaultTags {
+		for _, t := range tags {
+			if t.Key == k {
+				continue x
+			}
+		}
+		e.tags = append(e.tags, lp.Tag{Key: k, Value: v})
+	}
+	slices.SortFunc(e.tags, func(a, b lp.Tag) int { return strings.Compare(a.Key, b.Key) })
+	return e.tags
+}
+
+// appendTag appends ,key=value to dst, tags with empty key or value are skipped
+func appendTag(dst []byte, key, value string) []byte {
+	if key == "" || value == "" {
+		return dst
+	}
+	dst = append(dst, ',')
+	dst = appendEscaped(dst, key, keyEscapes)
+	dst = append(dst, '=')
+	return appendEscaped(dst, value, keyEscapes)
+}
+
+// appendField appends key=value to dst
+func appendField(dst []byte, key string, value interface{}) ([]byte, error) {
+	start := len(dst)
+	dst = appendEscaped(dst, key, keyEscapes)
+	// keys consisting of an escaped character only are rejected, as by the line-protocol encoder
+	if len(dst) == start || (len(dst)-start == 2 && dst[start] == '\\') {
+		return dst[:start], errInvalidFieldKey
+	}
+	dst = append(dst, '=')
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) {
+			return dst[:start], lp.ErrIsNaN
+		}
+		if math.IsInf(v, 0) {
+			return dst[:start], lp.ErrIsInf
+		}
+		dst = strconv.AppendFloat(dst, v, 'f', -1, 64)
+	case int64:
+		dst = append(strconv.AppendInt(dst, v, 10), 'i')
+	case uint64:
+		dst = append(strconv.AppendUint(dst, v, 10), 'u')
+	case string:
+		dst = append(dst, '"')
+		dst = appendEscaped(dst, v, stringEscapes)
+		dst = append(dst, '"')
+	case bool:
+		dst = strconv.AppendBool(dst, v)
+	// types below are not produced by Point.AddField, but they can be set by re-adding a field
+	case int:
+		dst = append(strconv.AppendInt(dst, int64(v), 10), 'i')
+	case float32:
+		return appendField(dst[:start], key, float64(v))
+	case []byte:
+		dst = append(dst, '"')
+		dst = appendEscaped(dst, string(v), stringEscapes)
+		dst = append(dst, '"')
+	default:
+		return dst[:start], fmt.Errorf("invalid value type: %T", v)
+	}
+	return dst, nil
+}
+
+// escapes is a table of characters escaped in a part of line protocol line
+type escapes [256]string
+
+var (
+	// nameEscapes are escapes of measurement names
+	nameEscapes = newEscapes(',', ' ')
+	// keyEscapes are escapes of tag keys, tag values and field keys
+	keyEscapes = newEscapes(',', ' ', '=')
+	// stringEscapes are escapes of string field values
+	stringEscapes = newEscapes('"', '\\')
+)
+
+// newEscapes creates escapes of control characters and characters chars, which are prefixed with backslash
+func newEscapes(chars ...byte) *escapes {
+	e := &escapes{'\t': `\t`, '\n': `\n`, '\f': `\f`, '\r': `\r`}
+	for _, c := range chars {
+		e[c] = `\` + string(c)
+	}
+	return e
+}
+
+// appendEscaped appends s with escaped characters to dst
+func appendEscaped(dst []byte, s string, e *escapes) []byte {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		if esc := e[s[i]]; esc != "" {
+			dst = append(dst, s[last:i]...)
+			dst = append(dst, esc...)
+			last = i + 1
+		}
+	}
+	return append(dst, s[last:]...)
+}
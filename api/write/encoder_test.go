@@ -0,0 +1,193 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	lp "github.com/influxdata/line-protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pointWithDefaultTags is a metric with default tags, as encoded by the line-protocol encoder before
+type pointWithDefaultTags struct {
+	*Point
+	defaultTags map[string]string
+}
+
+func (p *pointWithDefaultTags) TagList() []*lp.Tag {
+	tags := append([]*lp.Tag{}, p.Point.TagList()...)
+x:
+	for k, v := range p.defaultTags {
+		for _, t := range p.Point.TagList() {
+			if t.Key == k {
+				continue x
+			}
+		}
+		tags = append(tags, &lp.Tag{Key: k, Value: v})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags
+}
+
+// lpEncode encodes point by the line-protocol encoder
+func lpEncode(p *Point, precision time.Duration, defaultTags map[string]string) (string, error) {
+	var buffer bytes.Buffer
+	e := lp.NewEncoder(&buffer)
+	e.SetFieldTypeSupport(lp.UintSupport)
+	e.FailOnFieldErr(true)
+	e.SetPrecision(precision)
+	var m lp.Metric = p
+	if len(defaultTags) > 0 {
+		m = &pointWithDefaultTags{Point: p, defaultTags: defaultTags}
+	}
+	if _, err := e.Encode(m); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// assertEncodedIdentically checks Encoder output is the same as of the line-protocol encoder
+func assertEncodedIdentically(t *testing.T, p *Point, precision time.Duration, defaultTags map[string]string) {
+	t.Helper()
+	expected, expectedErr := lpEncode(p, precision, defaultTags)
+	e := NewEncoder().SetPrecision(precision).SetDefaultTags(defaultTags)
+	line, err := e.Append([]byte("prefix\n"), p)
+	if expectedErr != nil {
+		require.Error(t, err)
+		assert.Equal(t, expectedErr.Error(), err.Error())
+		assert.Equal(t, "prefix\n", string(line))
+		return
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "prefix\n"+expected, string(line))
+}
+
+func TestEncoder(t *testing.T) {
+	ts := time.Unix(60, 123456789)
+	tests := []struct {
+		name  string
+		point *Point
+	}{
+		{"simple", NewPoint("cpu", map[string]string{"host": "h1"}, map[string]interface{}{"usage": 1.5}, ts)},
+		{"no tags", NewPoint("cpu", nil, map[string]interface{}{"usage": 1.5}, ts)},
+		{"no time", NewPoint("cpu", nil, map[string]interface{}{"usage": 1.5}, time.Time{})},
+		{"types", NewPoint("m", nil, map[string]interface{}{"f": 1.0, "f2": -0.000001, "f3": 1e21, "i": -3, "u": uint64(math.MaxUint64), "b": true, "s": "text", "e": ""}, ts)},
+		{"escaped name", NewPointWithMeasurement("m ,=\t\n\f\r\\\"x").AddField("v", 1)},
+		{"escaped tags", NewPointWithMeasurement("m").AddTag("k ,=\t\n\f\r\\\"", "v ,=\t\n\f\r\\\"").AddTag("é", "ü").AddField("v", 1)},
+		{"escaped fields", NewPointWithMeasurement("m").AddField("k ,=\t\n\f\r\\\"", "v ,=\t\n\f\r\\\"")},
+		{"empty tag", NewPointWithMeasurement("m").AddTag("a", "").AddTag("", "b").AddTag("c", "d").AddField("v", 1)},
+		{"unsorted", NewPointWithMeasurement("m").AddTag("z", "1").AddTag("a", "2").AddField("z", 1).AddField("a", 2)},
+		{"re-set types", NewPointWithMeasurement("m").AddField("i", 1).AddField("i", 2).AddField("f", 1).AddField("f", float32(2.5)).AddField("s", 1).AddField("s", []byte("abc"))},
+		{"invalid type", NewPointWithMeasurement("m").AddField("v", 1).AddField("v", int32(2))},
+		{"NaN", NewPointWithMeasurement("m").AddField("a", 1).AddField("v", math.NaN())},
+		{"Inf", NewPointWithMeasurement("m").AddField("v", math.Inf(-1))},
+		{"float32 Inf", NewPointWithMeasurement("m").AddField("v", 1).AddField("v", float32(math.Inf(1)))},
+		{"no fields", NewPointWithMeasurement("m").AddTag("a", "b")},
+		{"no name", NewPointWithMeasurement("").AddField("v", 1)},
+		{"empty field key", NewPointWithMeasurement("m").AddField("", 1)},
+		{"field key with escaped char", NewPointWithMeasurement("m").AddField(" ", 1)},
+		{"field key with backslash", NewPointWithMeasurement("m").AddField(`\a`, 1).AddField(`a\`, 2).AddField(`\`, 3)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, precision := range []time.Duration{time.Nanosecond, time.Microsecond, time.Millisecond, time.Second, time.Minute} {
+				assertEncodedIdentically(t, test.point, precision, nil)
+			}
+			assertEncodedIdentically(t, test.point, time.Nanosecond, map[string]string{"dc": "eu", "a": "x", "z ": "y=", "empty": ""})
+		})
+	}
+}
+
+func TestEncoder_DefaultTime(t *testing.T) {
+	e := NewEncoder().SetPrecision(time.Microsecond).SetDefaultTime(time.Unix(60, 123456789))
+	b, err := e.Append(nil, NewPointWithMeasurement("m").AddField("v", 1))
+	require.NoError(t, err)
+	b, err = e.Append(b, NewPointWithMeasurement("m").AddField("v", 2).SetTime(time.Unix(1, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, "m v=1i 60123456\nm v=2i 1000000\n", string(b))
+
+	b, err = e.SetDefaultTime(time.Time{}).Append(nil, NewPointWithMeasurement("m").AddField("v", 1))
+	require.NoError(t, err)
+	assert.Equal(t, "m v=1i\n", string(b))
+}
+
+func TestEncoder_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const chars = "ab ,=\"\\\t\n\f\r\x00é😀"
+	str := func() string {
+		runes := []rune(chars)
+		s := make([]rune, r.Intn(5))
+		for i := range s {
+			s[i] = runes[r.Intn(len(runes))]
+		}
+		return string(s)
+	}
+	value := func() interface{} {
+		switch r.Intn(5) {
+		case 0:
+			return r.NormFloat64() * math.Pow10(r.Intn(40)-20)
+		case 1:
+			return r.Int63() - r.Int63()
+		case 2:
+			return r.Uint64()
+		case 3:
+			return r.Intn(2) == 0
+		default:
+			return str()
+		}
+	}
+	defaultTags := map[string]string{str(): str(), str(): str()}
+	for i := 0; i < 2000; i++ {
+		p := NewPointWithMeasurement(str())
+		for j := r.Intn(4); j > 0; j-- {
+			p.AddTag(str(), str())
+		}
+		for j := r.Intn(4); j > 0; j-- {
+			p.AddField(str(), value())
+		}
+		p.SetTime(time.Unix(0, r.Int63()))
+		assertEncodedIdentically(t, p, time.Nanosecond, nil)
+		assertEncodedIdentically(t, p, time.Millisecond, defaultTags)
+	}
+}
+
+func TestEncoder_Allocations(t *testing.T) {
+	p := NewPointWithMeasurement("cpu a").
+		AddTag("host", "h,1").
+		AddTag("region", "eu").
+		AddField("usage", 1.5).
+		AddField("count", 3).
+		AddField("free", uint64(1)).
+		AddField("up", true).
+		AddField("msg", `a "b"`).
+		SetTime(time.Now())
+	e := NewEncoder().SetDefaultTags(map[string]string{"dc": "x", "host": "y"})
+	buf := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		var err error
+		buf, err = e.Append(buf[:0], p)
+		require.NoError(t, err)
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	e := NewEncoder()
+	buf := make([]byte, 0, 1024*1024)
+	for n := 0; n < b.N; n++ {
+		buf = buf[:0]
+		for _, p := range points {
+			buf, _ = e.Append(buf, p)
+		}
+		s = string(buf)
+	}
+}
@@ -5,7 +5,11 @@
 package write
 
 import (
+	"compress/gzip"
+	"errors"
 	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
 )
 
 // Options holds write configuration properties
@@ -17,8 +21,10 @@ type Options struct {
 	// Precision to use in writes for timestamp. In unit of duration: time.Nanosecond, time.Microsecond, time.Millisecond, time.Second
 	// Default time.Nanosecond
 	precision time.Duration
-	// Whether to use GZip compression in requests. Default false
-	useGZip bool
+	// Codec compressing write requests. Default nil, requests are not compressed.
+	compression http.Codec
+	// Minimum size of batch in bytes, which is compressed. Default 0, all batches are compressed.
+	compressionThreshold uint
 	// Tags added to each point during writing. If a point already has a tag with the same key, it is left unchanged.
 	defaultTags map[string]string
 	// Default retry interval in ms, if not sent by server. Default 5,000.
@@ -35,6 +41,36 @@ type Options struct {
 	exponentialBase uint
 	// InfluxDB Enterprise write consistency as explained in https://docs.influxdata.com/enterprise_influxdb/v1.9/concepts/clustering/#write-consistency
 	consistency Consistency
+	// Schemas used to validate points before they are written. Default nil, points are not validated.
+	schemaRegistry *SchemaRegistry
+	// Whether to convert field values to types required by schema. Default false, points with a wrong field type are rejected.
+	coerceToSchema bool
+	// Whether to parse records written by WriteRecord and reject invalid lines individually. Default false.
+	validateRecords bool
+	// Sink receiving discarded batches. Default nil, discarded batches are only logged.
+	deadLetterSink DeadLetterSink
+	// Maximum size of batch in bytes. Default 0, size is not limited.
+	maxBatchBytes uint
+	// Maximum bytes of lines held in write buffer and retry queue. Default 0, memory is not limited.
+	memoryBudget uint
+	// What to do when memory budget is exceeded. Default OverflowDropOldest.
+	overflowPolicy OverflowPolicy
+	// Capacity of the channel of lines written by WriteAPI before they are added to a batch. Default 0, unbuffered.
+	bufferCapacity uint
+	// Number of batches written concurrently by WriteAPI. Default 1.
+	concurrency uint
+	// Whether lines of a series are always written by the same concurrent writer. Default false.
+	orderedWrites bool
+	// Time in ms after which RoutingWriteAPI removes a destination without writes. Default 300,000.
+	destinationIdleTimeout uint
+	// Policy deciding retrying of failed writes. Default nil, errors with status code 429 or higher and connection errors are retried.
+	retryPolicy RetryPolicy
+	// Circuit breaker stopping writes after consecutive failures. Default nil.
+	circuitBreaker *CircuitBreaker
+	// Whether batches are identified by a content hash and points get timestamps when they are written. Default false.
+	idempotentWrites bool
+	// Time in ms for which IDs of written batches are remembered to skip writing them again. Default 180,000.
+	dedupWindow uint
 }
 
 const (
@@ -51,6 +87,23 @@ const (
 	ConsistencyAny Consistency = "any"
 )
 
+// OverflowPolicy defines behaviour of WriteAPI when the memory budget is exceeded
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest discards the oldest batches from the retry queue
+	OverflowDropOldest OverflowPolicy = "drop oldest"
+
+	// OverflowDropNewest discards newly written lines, WriteAPI reports ErrMemoryBudgetExceeded
+	OverflowDropNewest OverflowPolicy = "drop newest"
+
+	// OverflowBlock blocks writing until buffered lines are written or discarded
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// ErrMemoryBudgetExceeded is reported by WriteAPI when a line is discarded by OverflowDropNewest policy
+var ErrMemoryBudgetExceeded = errors.New("memory budget exceeded")
+
 // Consistency defines enum for allows consistency values for InfluxDB Enterprise, as explained  https://docs.influxdata.com/enterprise_influxdb/v1.9/concepts/clustering/#write-consistency
 type Consistency string
 
@@ -156,12 +209,41 @@ func (o *Options) SetPrecision(precision time.Duration) *Options {
 
 // UseGZip returns true if write request are gzip`ed
 func (o *Options) UseGZip() bool {
-	return o.useGZip
+	return o.compression != nil && o.compression.Encoding() == "gzip"
 }
 
 // SetUseGZip specifies whether to use GZip compression in write requests.
+// It sets compression to gzip with the default level, or turns compression off.
 func (o *Options) SetUseGZip(useGZip bool) *Options {
-	o.useGZip = useGZip
+	if useGZip {
+		o.compression = http.GzipCodec(gzip.DefaultCompression)
+	} else {
+		o.compression = nil
+	}
+	return o
+}
+
+// Compression returns codec compressing write requests, nil if requests are not compressed
+func (o *Options) Compression() http.Codec {
+	return o.compression
+}
+
+// SetCompression sets codec compressing write requests, e.g. http.GzipCodec(gzip.BestSpeed).
+// Nil turns compression off.
+func (o *Options) SetCompression(codec http.Codec) *Options {
+	o.compression = codec
+	return o
+}
+
+// CompressionThreshold returns minimum size of batch in bytes, which is compressed
+func (o *Options) CompressionThreshold() uint {
+	return o.compressionThreshold
+}
+
+// SetCompressionThreshold sets minimum size of batch in bytes, which is compressed.
+// Smaller batches are sent uncompressed, as compressing them costs more than it saves.
+func (o *Options) SetCompressionThreshold(threshold uint) *Options {
+	o.compressionThreshold = threshold
 	return o
 }
 
@@ -192,8 +274,195 @@ func (o *Options) SetConsistency(consistency Consistency) *Options {
 	return o
 }
 
+// SchemaRegistry returns schemas used to validate written points
+func (o *Options) SchemaRegistry() *SchemaRegistry {
+	return o.schemaRegistry
+}
+
+// SetSchemaRegistry sets schemas used to validate points before they are written.
+// Points violating a schema are rejected with *SchemaError. Default tags are not validated.
+func (o *Options) SetSchemaRegistry(registry *SchemaRegistry) *Options {
+	o.schemaRegistry = registry
+	return o
+}
+
+// CoerceToSchema returns true if field values are converted to types required by schema
+func (o *Options) CoerceToSchema() bool {
+	return o.coerceToSchema
+}
+
+// SetCoerceToSchema specifies whether to convert field values to types required by schema, instead of rejecting the point
+func (o *Options) SetCoerceToSchema(coerce bool) *Options {
+	o.coerceToSchema = coerce
+	return o
+}
+
+// ValidateRecords returns true if records are validated before writing
+func (o *Options) ValidateRecords() bool {
+	return o.validateRecords
+}
+
+// SetValidateRecords specifies whether to parse line protocol records written by WriteRecord before they are batched.
+// Invalid lines are rejected individually with *ParseError, instead of failing the whole batch on the server.
+func (o *Options) SetValidateRecords(validate bool) *Options {
+	o.validateRecords = validate
+	return o
+}
+
+// DeadLetterSink returns sink receiving discarded batches
+func (o *Options) DeadLetterSink() DeadLetterSink {
+	return o.deadLetterSink
+}
+
+// SetDeadLetterSink sets sink receiving batches discarded by WriteAPI, e.g. after the maximum number of retries,
+// and lines rejected by the server in partial writes
+func (o *Options) SetDeadLetterSink(sink DeadLetterSink) *Options {
+	o.deadLetterSink = sink
+	return o
+}
+
+// MaxBatchBytes returns maximum size of batch in bytes, 0 means no limit
+func (o *Options) MaxBatchBytes() uint {
+	return o.maxBatchBytes
+}
+
+// SetMaxBatchBytes sets maximum size of batch in bytes, which should not exceed the server request body limit.
+// Batch is sent when adding a line would exceed the size. A line larger than the size is sent in its own batch.
+// 0 means no limit.
+func (o *Options) SetMaxBatchBytes(maxBatchBytes uint) *Options {
+	o.maxBatchBytes = maxBatchBytes
+	return o
+}
+
+// MemoryBudget returns maximum bytes of lines held by WriteAPI, 0 means no limit
+func (o *Options) MemoryBudget() uint {
+	return o.memoryBudget
+}
+
+// SetMemoryBudget sets maximum bytes of lines held by WriteAPI in write buffer and retry queue.
+// When the budget is exceeded, OverflowPolicy applies. 0 means no limit.
+func (o *Options) SetMemoryBudget(memoryBudget uint) *Options {
+	o.memoryBudget = memoryBudget
+	return o
+}
+
+// OverflowPolicy returns behaviour when the memory budget is exceeded
+func (o *Options) OverflowPolicy() OverflowPolicy {
+	return o.overflowPolicy
+}
+
+// SetOverflowPolicy sets behaviour when the memory budget is exceeded
+func (o *Options) SetOverflowPolicy(policy OverflowPolicy) *Options {
+	o.overflowPolicy = policy
+	return o
+}
+
+// BufferCapacity returns capacity of the channel of lines written by WriteAPI
+func (o *Options) BufferCapacity() uint {
+	return o.bufferCapacity
+}
+
+// SetBufferCapacity sets capacity of the channel of lines written by WriteAPI before they are added to a batch.
+// With a buffered channel, writes don't block while a batch is being sent, until the channel is full.
+// TryWriteRecord and TryWritePoint of WriteAPI return false when the channel is full.
+func (o *Options) SetBufferCapacity(capacity uint) *Options {
+	o.bufferCapacity = capacity
+	return o
+}
+
+// Concurrency returns number of batches written concurrently by WriteAPI
+func (o *Options) Concurrency() uint {
+	return o.concurrency
+}
+
+// SetConcurrency sets number of batches written concurrently by WriteAPI, each batch is sent by a separate writer.
+// Writers share retry backoff, a retryable error of one writer pauses writes of all writers.
+// Retry buffer limit is divided among writers. Values less than 1 mean 1.
+func (o *Options) SetConcurrency(concurrency uint) *Options {
+	o.concurrency = concurrency
+	return o
+}
+
+// OrderedWrites returns whether lines of a series are always written by the same concurrent writer
+func (o *Options) OrderedWrites() bool {
+	return o.orderedWrites
+}
+
+// SetOrderedWrites sets whether lines of a series are always written by the same concurrent writer,
+// so that they are written in the order they were added, including retries.
+// Each writer then builds its own batches and batch size and maximum batch bytes apply to each of them.
+// When not set, batches are written by any idle writer and lines of a series can be written out of order.
+// It has no effect when concurrency is 1.
+func (o *Options) SetOrderedWrites(ordered bool) *Options {
+	o.orderedWrites = ordered
+	return o
+}
+
+// DestinationIdleTimeout returns time in ms after which RoutingWriteAPI removes a destination without writes
+func (o *Options) DestinationIdleTimeout() uint {
+	return o.destinationIdleTimeout
+}
+
+// SetDestinationIdleTimeout sets time in ms after which RoutingWriteAPI removes a destination without writes,
+// batches waiting for retry are written immediately when it is removed. 0 means destinations are not removed.
+func (o *Options) SetDestinationIdleTimeout(timeout uint) *Options {
+	o.destinationIdleTimeout = timeout
+	return o
+}
+
+// RetryPolicy returns policy deciding retrying of failed writes, nil means the default policy
+func (o *Options) RetryPolicy() RetryPolicy {
+	return o.retryPolicy
+}
+
+// SetRetryPolicy sets policy deciding whether a failed write is retried and the delay before the next write.
+// Maximum retries and maximum retry time apply to retried batches regardless of the policy.
+func (o *Options) SetRetryPolicy(policy RetryPolicy) *Options {
+	o.retryPolicy = policy
+	return o
+}
+
+// CircuitBreaker returns circuit breaker stopping writes after consecutive failures
+func (o *Options) CircuitBreaker() *CircuitBreaker {
+	return o.circuitBreaker
+}
+
+// SetCircuitBreaker sets circuit breaker stopping writes after consecutive failures.
+// While the circuit is open, batches are kept in the retry queue.
+func (o *Options) SetCircuitBreaker(breaker *CircuitBreaker) *Options {
+	o.circuitBreaker = breaker
+	return o
+}
+
+// IdempotentWrites returns whether writes are idempotent
+func (o *Options) IdempotentWrites() bool {
+	return o.idempotentWrites
+}
+
+// SetIdempotentWrites sets whether writes are idempotent, so that retried batches don't create different data.
+// Points and records without timestamp get the current time when they are written, instead of the time the server receives them.
+// Each batch is identified by a hash of its content, which is sent in the Idempotency-Key header,
+// and batches already written within the dedup window are not written again.
+func (o *Options) SetIdempotentWrites(idempotentWrites bool) *Options {
+	o.idempotentWrites = idempotentWrites
+	return o
+}
+
+// DedupWindow returns time in ms for which IDs of written batches are remembered
+func (o *Options) DedupWindow() uint {
+	return o.dedupWindow
+}
+
+// SetDedupWindow sets time in ms for which IDs of written batches are remembered to skip writing them again, when writes are idempotent.
+// Zero disables deduplication.
+func (o *Options) SetDedupWindow(window uint) *Options {
+	o.dedupWindow = window
+	return o
+}
+
 // DefaultOptions returns Options object with default values
 func DefaultOptions() *Options {
-	return &Options{batchSize: 5_000, flushInterval: 1_000, precision: time.Nanosecond, useGZip: false, retryBufferLimit: 50_000, defaultTags: make(map[string]string),
-		maxRetries: 5, retryInterval: 5_000, maxRetryInterval: 125_000, maxRetryTime: 180_000, exponentialBase: 2}
+	return &Options{batchSize: 5_000, flushInterval: 1_000, precision: time.Nanosecond, retryBufferLimit: 50_000, defaultTags: make(map[string]string),
+		maxRetries: 5, retryInterval: 5_000, maxRetryInterval: 125_000, maxRetryTime: 180_000, exponentialBase: 2, overflowPolicy: OverflowDropOldest, concurrency: 1,
+		destinationIdleTimeout: 300_000, dedupWindow: 180_000}
 }
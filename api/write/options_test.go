@@ -5,10 +5,12 @@
 package write_test
 
 import (
+	"compress/gzip"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
 )
 
@@ -26,6 +28,23 @@ func TestDefaultOptions(t *testing.T) {
 	assert.EqualValues(t, 2, opts.ExponentialBase())
 	assert.EqualValues(t, "", opts.Consistency())
 	assert.Len(t, opts.DefaultTags(), 0)
+	assert.Nil(t, opts.SchemaRegistry())
+	assert.False(t, opts.CoerceToSchema())
+	assert.False(t, opts.ValidateRecords())
+	assert.Nil(t, opts.DeadLetterSink())
+	assert.EqualValues(t, 0, opts.MaxBatchBytes())
+	assert.EqualValues(t, 0, opts.MemoryBudget())
+	assert.Equal(t, write.OverflowDropOldest, opts.OverflowPolicy())
+	assert.EqualValues(t, 0, opts.BufferCapacity())
+	assert.EqualValues(t, 1, opts.Concurrency())
+	assert.False(t, opts.OrderedWrites())
+	assert.EqualValues(t, 300_000, opts.DestinationIdleTimeout())
+	assert.Nil(t, opts.RetryPolicy())
+	assert.Nil(t, opts.CircuitBreaker())
+	assert.False(t, opts.IdempotentWrites())
+	assert.EqualValues(t, 180_000, opts.DedupWindow())
+	assert.Nil(t, opts.Compression())
+	assert.EqualValues(t, 0, opts.CompressionThreshold())
 }
 
 func TestSettingsOptions(t *testing.T) {
@@ -42,7 +61,23 @@ func TestSettingsOptions(t *testing.T) {
 		SetMaxRetryTime(200_000).
 		AddDefaultTag("a", "1").
 		AddDefaultTag("b", "2").
-		SetConsistency(write.ConsistencyOne)
+		SetConsistency(write.ConsistencyOne).
+		SetSchemaRegistry(write.NewSchemaRegistry()).
+		SetCoerceToSchema(true).
+		SetValidateRecords(true).
+		SetDeadLetterSink(write.NewChannelDeadLetterSink(1)).
+		SetMaxBatchBytes(1_000_000).
+		SetMemoryBudget(100_000_000).
+		SetOverflowPolicy(write.OverflowBlock).
+		SetBufferCapacity(1_000).
+		SetConcurrency(4).
+		SetOrderedWrites(true).
+		SetDestinationIdleTimeout(60_000).
+		SetRetryPolicy(write.RetryPolicyFunc(func(*http.Error, uint) (bool, time.Duration) { return false, 0 })).
+		SetCircuitBreaker(write.NewCircuitBreaker(5, time.Minute)).
+		SetIdempotentWrites(true).
+		SetDedupWindow(60_000).
+		SetCompressionThreshold(1_024)
 	assert.EqualValues(t, 5, opts.BatchSize())
 	assert.EqualValues(t, true, opts.UseGZip())
 	assert.EqualValues(t, 5000, opts.FlushInterval())
@@ -55,4 +90,28 @@ func TestSettingsOptions(t *testing.T) {
 	assert.EqualValues(t, 3, opts.ExponentialBase())
 	assert.EqualValues(t, "one", opts.Consistency())
 	assert.Len(t, opts.DefaultTags(), 2)
+	assert.NotNil(t, opts.SchemaRegistry())
+	assert.True(t, opts.CoerceToSchema())
+	assert.True(t, opts.ValidateRecords())
+	assert.NotNil(t, opts.DeadLetterSink())
+	assert.EqualValues(t, 1_000_000, opts.MaxBatchBytes())
+	assert.EqualValues(t, 100_000_000, opts.MemoryBudget())
+	assert.Equal(t, write.OverflowBlock, opts.OverflowPolicy())
+	assert.EqualValues(t, 1_000, opts.BufferCapacity())
+	assert.EqualValues(t, 4, opts.Concurrency())
+	assert.True(t, opts.OrderedWrites())
+	assert.EqualValues(t, 60_000, opts.DestinationIdleTimeout())
+	assert.NotNil(t, opts.RetryPolicy())
+	assert.NotNil(t, opts.CircuitBreaker())
+	assert.True(t, opts.IdempotentWrites())
+	assert.EqualValues(t, 60_000, opts.DedupWindow())
+	assert.Equal(t, http.GzipCodec(gzip.DefaultCompression), opts.Compression())
+	assert.EqualValues(t, 1_024, opts.CompressionThreshold())
+
+	opts.SetCompression(http.GzipCodec(gzip.BestSpeed))
+	assert.True(t, opts.UseGZip())
+	assert.Equal(t, http.GzipCodec(gzip.BestSpeed), opts.Compression())
+	opts.SetUseGZip(false)
+	assert.False(t, opts.UseGZip())
+	assert.Nil(t, opts.Compression())
 }
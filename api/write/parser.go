@@ -0,0 +1,358 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError describes invalid line protocol
+type ParseError struct {
+	// Line is the number of the line with the error, starting at 1
+	Line int
+	// Column is the byte offset of the error in the line, starting at 1
+	Column int
+	// Text is the invalid line protocol record, it spans more lines if a string field value contains a new line
+	Text string
+	// Msg describes the error
+	Msg string
+}
+
+// Error implements error interface
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ParsedLine is a line protocol record parsed into a Point
+type ParsedLine struct {
+	// Line is the number of the line where the record starts, starting at 1
+	Line int
+	// Text is the line protocol record, without the new line
+	Text  string
+	Point *Point
+}
+
+// ParseLineProtocol parses line protocol text into points.
+// Timestamps are in units of precision: time.Nanosecond, time.Microsecond, time.Millisecond or time.Second.
+// Points without timestamp have zero time. Empty lines and comments, lines starting with #, are skipped.
+// It returns *ParseError describing the first invalid line.
+func ParseLineProtocol(text string, precision time.Duration) ([]*Point, error) {
+	var points []*Point
+	for line, err := range ParseLines(text, precision) {
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, line.Point)
+	}
+	return points, nil
+}
+
+// ParseLines returns an iterator over line protocol records of text, which yields each record parsed into a point,
+// or *ParseError for an invalid record. Parsing continues on the next line after an error.
+// Timestamps are in units of precision. Empty lines and comments, lines starting with #, are skipped.
+func ParseLines(text string, precision time.Duration) iter.Seq2[*ParsedLine, error] {
+	return func(yield func(*ParsedLine, error) bool) {
+		p := &lpParser{text: text, line: 1, precision: precision}
+		for {
+			p.skipEmptyLines()
+			if p.pos >= len(p.text) {
+				return
+			}
+			start, line := p.pos, p.line
+			point, err := p.parsePoint()
+			if err != nil {
+				err.Text = p.text[start:p.lineEnd()]
+				p.pos = p.lineEnd()
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if !yield(&ParsedLine{Line: line, Text: p.text[start:p.pos], Point: point}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SetMissingTimestamps returns line protocol text with timestamp t in units of precision added to records without timestamp.
+// Empty lines and comments are removed, invalid records are left unchanged.
+func SetMissingTimestamps(text string, t time.Time, precision time.Duration) string {
+	b := make([]byte, 0, len(text)+32)
+	for line, err := range ParseLines(text, precision) {
+		if len(b) > 0 {
+			b = append(b, '\n')
+		}
+		if err != nil {
+			var perr *ParseError
+			if errors.As(err, &perr) {
+				b = append(b, perr.Text...)
+			}
+			continue
+		}
+		b = append(b, line.Text...)
+		if line.Point.Time().IsZero() {
+			b = appendTimestamp(append(b, ' '), t, precision)
+		}
+	}
+	return string(b)
+}
+
+// lpParser holds state of line protocol parsing
+type lpParser struct {
+	text      string
+	pos       int
+	line      int
+	lineStart int
+	precision time.Duration
+}
+
+// errorf creates *ParseError at current position
+func (p *lpParser) errorf(format string, args ...interface{}) *ParseError {
+	return &ParseError{Line: p.line, Column: p.pos - p.lineStart + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lineEnd returns position of the next new line or end of text
+func (p *lpParser) lineEnd() int {
+	if i := strings.IndexByte(p.text[p.pos:], '\n'); i >= 0 {
+		return p.pos + i
+	}
+	return len(p.text)
+}
+
+// newLine moves after new line at current position
+func (p *lpParser) newLine() {
+	p.pos++
+	p.line++
+	p.lineStart = p.pos
+}
+
+// skipEmptyLines skips whitespace, empty lines and comments
+func (p *lpParser) skipEmptyLines() {
+	for p.pos < len(p.text) {
+		switch p.text[p.pos] {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.newLine()
+		case '#':
+			p.pos = p.lineEnd()
+		default:
+			return
+		}
+	}
+}
+
+// atLineEnd returns true if current position is at a new line or end of text
+func (p *lpParser) atLineEnd() bool {
+	return p.pos >= len(p.text) || p.text[p.pos] == '\n' || strings.HasPrefix(p.text[p.pos:], "\r\n") || p.pos == len(p.text)-1 && p.text[p.pos] == '\r'
+}
+
+// skipSpaces skips spaces and returns true if there were any
+func (p *lpParser) skipSpaces() bool {
+	start := p.pos
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// parsePoint parses a line protocol record, current position is at its start
+func (p *lpParser) parsePoint() (*Point, *ParseError) {
+	measurement, ok := p.scanKey(", ", ", ")
+	if measurement == "" {
+		return nil, p.errorf("missing measurement")
+	}
+	if !ok {
+		return nil, p.errorf("missing fields")
+	}
+	point := NewPointWithMeasurement(measurement)
+	for p.text[p.pos] == ',' {
+		p.pos++
+		key, ok := p.scanKey(",= ", ",= ")
+		if key == "" {
+			return nil, p.errorf("missing tag key")
+		}
+		if !ok || p.text[p.pos] != '=' {
+			return nil, p.errorf("missing tag value")
+		}
+		p.pos++
+		value, ok := p.scanKey(",= ", ", ")
+		if !ok && !p.atLineEnd() {
+			return nil, p.errorf("invalid tag value, '=' must be escaped")
+		}
+		if value == "" {
+			return nil, p.errorf("missing tag value")
+		}
+		point.AddTag(key, value)
+		if !ok {
+			break
+		}
+	}
+	if !p.skipSpaces() || p.atLineEnd() {
+		return nil, p.errorf("missing fields")
+	}
+	for {
+		key, ok := p.scanKey(",= ", ",= ")
+		if key == "" {
+			return nil, p.errorf("missing field key")
+		}
+		if !ok || p.text[p.pos] != '=' {
+			return nil, p.errorf("missing field value")
+		}
+		p.pos++
+		value, err := p.scanFieldValue()
+		if err != nil {
+			return nil, err
+		}
+		point.AddField(key, value)
+		if p.pos < len(p.text) && p.text[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.skipSpaces() && !p.atLineEnd() {
+		start := p.pos
+		token := p.scanToken()
+		ts, err := strconv.ParseInt(token, 10, 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("invalid timestamp '%s'", token)
+		}
+		if p.precision > time.Nanosecond && (ts > math.MaxInt64/int64(p.precision) || ts < math.MinInt64/int64(p.precision)) {
+			p.pos = start
+			return nil, p.errorf("timestamp '%s' out of range", token)
+		}
+		point.SetTime(time.Unix(0, ts*int64(p.precision)))
+		p.skipSpaces()
+	}
+	if !p.atLineEnd() {
+		return nil, p.errorf("unexpected character '%c'", p.text[p.pos])
+	}
+	return point, nil
+}
+
+// scanKey scans a measurement name, tag key, tag value or field key up to an unescaped character of stop.
+// Characters of escaped are escaped by backslash, other backslashes are kept.
+// It returns false if a line end or an unescaped character of escaped, which is not in stop, was reached.
+func (p *lpParser) scanKey(escaped, stop string) (string, bool) {
+	var sb strings.Builder
+	last := p.pos
+	for ; p.pos < len(p.text); p.pos++ {
+		c := p.text[p.pos]
+		if c == '\\' && p.pos+1 < len(p.text) && strings.IndexByte(escaped, p.text[p.pos+1]) >= 0 {
+			sb.WriteString(p.text[last:p.pos])
+			p.pos++
+			last = p.pos
+			continue
+		}
+		if strings.IndexByte(stop, c) >= 0 {
+			break
+		}
+		if strings.IndexByte(escaped, c) >= 0 || p.atLineEnd() {
+			sb.WriteString(p.text[last:p.pos])
+			return sb.String(), false
+		}
+	}
+	sb.WriteString(p.text[last:p.pos])
+	return sb.String(), p.pos < len(p.text)
+}
+
+// scanToken scans text until a comma, space or line end
+func (p *lpParser) scanToken() string {
+	start := p.pos
+	for p.pos < len(p.text) && !p.atLineEnd() && p.text[p.pos] != ',' && p.text[p.pos] != ' ' {
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+// scanFieldValue scans a field value
+func (p *lpParser) scanFieldValue() (interface{}, *ParseError) {
+	if p.pos < len(p.text) && p.text[p.pos] == '"' {
+		return p.scanString()
+	}
+	start := p.pos
+	token := p.scanToken()
+	fail := func(format string) (interface{}, *ParseError) {
+		p.pos = start
+		return nil, p.errorf(format, token)
+	}
+	switch {
+	case token == "":
+		return nil, p.errorf("missing field value")
+	case token == "t" || token == "T" || token == "true" || token == "True" || token == "TRUE":
+		return true, nil
+	case token == "f" || token == "F" || token == "false" || token == "False" || token == "FALSE":
+		return false, nil
+	case strings.HasSuffix(token, "i") && isNumber(token[:len(token)-1]):
+		if i, err := strconv.ParseInt(token[:len(token)-1], 10, 64); err == nil {
+			return i, nil
+		}
+		return fail("invalid integer '%s'")
+	case strings.HasSuffix(token, "u") && isNumber(token[:len(token)-1]):
+		if u, err := strconv.ParseUint(token[:len(token)-1], 10, 64); err == nil {
+			return u, nil
+		}
+		return fail("invalid unsigned integer '%s'")
+	default:
+		if f, err := strconv.ParseFloat(token, 64); err == nil && isNumber(token) {
+			return f, nil
+		}
+		return fail("invalid field value '%s'")
+	}
+}
+
+// isNumber checks s consists of characters of a decimal number.
+// It rejects forms accepted by strconv, which are not valid in line protocol, such as Inf, NaN, hexadecimal or underscores.
+func isNumber(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9', c == '.', c == 'e', c == 'E':
+		case c == '-' && i == 0:
+		case (c == '+' || c == '-') && i > 0 && (s[i-1] == 'e' || s[i-1] == 'E'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// scanString scans a double-quoted string field value, current position is at the opening quote
+func (p *lpParser) scanString() (interface{}, *ParseError) {
+	start := p.pos
+	startLine, startLineStart := p.line, p.lineStart
+	p.pos++
+	var sb strings.Builder
+	last := p.pos
+	for p.pos < len(p.text) {
+		switch p.text[p.pos] {
+		case '\\':
+			if p.pos+1 < len(p.text) && (p.text[p.pos+1] == '"' || p.text[p.pos+1] == '\\') {
+				sb.WriteString(p.text[last:p.pos])
+				p.pos++
+				last = p.pos
+			}
+		case '"':
+			sb.WriteString(p.text[last:p.pos])
+			p.pos++
+			return sb.String(), nil
+		case '\n':
+			p.newLine()
+			continue
+		}
+		p.pos++
+	}
+	p.pos, p.line, p.lineStart = start, startLine, startLineStart
+	return nil, p.errorf("unterminated string")
+}
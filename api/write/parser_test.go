@@ -0,0 +1,196 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write_test
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLineProtocol(t *testing.T) {
+	text := "# comment\n" +
+		"cpu,host=h1,region=eu usage=1.5,count=3i,free=7u,up=t,msg=\"a \\\"b\\\" c\\\\d\\e\" 1600000000\n" +
+		"\n" +
+		"  mem free=.5,total=-1e3,on=FALSE\r\n" +
+		"m\\ e\\,a=s,t\\ a\\,g\\=k=v\\ a\\,l\\=\\x f\\=k\\ =\"multi\nline\"   \n" +
+		"m=x\\ y v=1 -5"
+	points, err := write.ParseLineProtocol(text, time.Second)
+	require.NoError(t, err)
+	require.Len(t, points, 4)
+
+	ts := time.Unix(1600000000, 0)
+	assert.Equal(t, write.NewPointWithMeasurement("cpu").
+		AddTag("host", "h1").AddTag("region", "eu").
+		AddField("usage", 1.5).AddField("count", int64(3)).AddField("free", uint64(7)).AddField("up", true).AddField("msg", `a "b" c\d\e`).
+		SetTime(ts), points[0])
+	assert.Equal(t, write.NewPointWithMeasurement("mem").AddField("free", 0.5).AddField("total", -1000.0).AddField("on", false), points[1])
+	assert.Equal(t, write.NewPointWithMeasurement("m e,a=s").AddTag("t a,g=k", `v a,l=\x`).AddField("f=k ", "multi\nline"), points[2])
+	assert.Equal(t, write.NewPointWithMeasurement("m=x y").AddField("v", 1.0).SetTime(time.Unix(-5, 0)), points[3])
+}
+
+func TestParseLineProtocol_Errors(t *testing.T) {
+	tests := []struct {
+		text   string
+		column int
+		msg    string
+	}{
+		{"cpu", 4, "missing fields"},
+		{"cpu ", 5, "missing fields"},
+		{",t=a v=1", 1, "missing measurement"},
+		{"cpu, v=1", 5, "missing tag key"},
+		{"cpu,=a v=1", 5, "missing tag key"},
+		{"cpu,t v=1", 6, "missing tag value"},
+		{"cpu,t= v=1", 7, "missing tag value"},
+		{"cpu,t=a=b v=1", 8, "invalid tag value, '=' must be escaped"},
+		{"cpu,t=a", 8, "missing fields"},
+		{"cpu v", 6, "missing field value"},
+		{"cpu =1", 5, "missing field key"},
+		{"cpu v=", 7, "missing field value"},
+		{"cpu v=1,", 9, "missing field key"},
+		{"cpu v=x", 7, "invalid field value 'x'"},
+		{"cpu v=1x", 7, "invalid field value '1x'"},
+		{"cpu v=NaN", 7, "invalid field value 'NaN'"},
+		{"cpu v=Inf", 7, "invalid field value 'Inf'"},
+		{"cpu v=0x10", 7, "invalid field value '0x10'"},
+		{"cpu v=1_000", 7, "invalid field value '1_000'"},
+		{"cpu v=+1", 7, "invalid field value '+1'"},
+		{"cpu v=1.5i", 7, "invalid integer '1.5i'"},
+		{"cpu v=99999999999999999999i", 7, "invalid integer '99999999999999999999i'"},
+		{"cpu v=-1u", 7, "invalid unsigned integer '-1u'"},
+		{"cpu v=tru", 7, "invalid field value 'tru'"},
+		{"cpu v=\"abc", 7, "unterminated string"},
+		{"cpu v=\"abc\"x", 12, "unexpected character 'x'"},
+		{"cpu v=1 x", 9, "invalid timestamp 'x'"},
+		{"cpu v=1 1.5", 9, "invalid timestamp '1.5'"},
+		{"cpu v=1 1 1", 11, "unexpected character '1'"},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			_, err := write.ParseLineProtocol("m v=1\n"+test.text+"\nm v=2", time.Nanosecond)
+			var parseErr *write.ParseError
+			require.True(t, errors.As(err, &parseErr), err)
+			assert.Equal(t, write.ParseError{Line: 2, Column: test.column, Text: test.text, Msg: test.msg}, *parseErr)
+		})
+	}
+}
+
+func TestParseLineProtocol_TimestampRange(t *testing.T) {
+	_, err := write.ParseLineProtocol("m v=1 9223372036854775807", time.Second)
+	assert.EqualError(t, err, "line 1, column 7: timestamp '9223372036854775807' out of range")
+	points, err := write.ParseLineProtocol("m v=1 9223372036854775807", time.Nanosecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9223372036854775807), points[0].Time().UnixNano())
+	points, err = write.ParseLineProtocol("m v=1 1600000000123", time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1600000000123), points[0].Time())
+}
+
+func TestParseLines(t *testing.T) {
+	text := "m v=1\nm v=x\nm v=\"a\nb\" 1\n\nm,t=a\nm v=4 4"
+	type result struct {
+		line int
+		text string
+		err  string
+	}
+	var results []result
+	for line, err := range write.ParseLines(text, time.Nanosecond) {
+		if err != nil {
+			var parseErr *write.ParseError
+			require.True(t, errors.As(err, &parseErr))
+			results = append(results, result{line: parseErr.Line, text: parseErr.Text, err: err.Error()})
+			continue
+		}
+		results = append(results, result{line: line.Line, text: line.Text})
+	}
+	assert.Equal(t, []result{
+		{line: 1, text: "m v=1"},
+		{line: 2, text: "m v=x", err: "line 2, column 5: invalid field value 'x'"},
+		{line: 3, text: "m v=\"a\nb\" 1"},
+		{line: 6, text: "m,t=a", err: "line 6, column 6: missing fields"},
+		{line: 7, text: "m v=4 4"},
+	}, results)
+
+	// stop iteration
+	count := 0
+	for range write.ParseLines(text, time.Nanosecond) {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestSetMissingTimestamps(t *testing.T) {
+	ts := time.Unix(60, 123456789)
+	text := "m v=1\nm v=2 5\n# comment\n\nm v=x\nm,t=a\\ b v=\"a b\""
+	assert.Equal(t, "m v=1 60123456789\nm v=2 5\nm v=x\nm,t=a\\ b v=\"a b\" 60123456789", write.SetMissingTimestamps(text, ts, time.Nanosecond))
+	assert.Equal(t, "m v=1 60\nm v=2 5", write.SetMissingTimestamps("m v=1\nm v=2 5", ts, time.Second))
+	assert.Equal(t, "m v=1 60123", write.SetMissingTimestamps("m v=1", ts, time.Millisecond))
+	assert.Equal(t, "", write.SetMissingTimestamps("", ts, time.Nanosecond))
+}
+
+func TestParseLineProtocol_RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const chars = "ab ,=\"\\é"
+	str := func() string {
+		runes := []rune(chars)
+		s := make([]rune, r.Intn(5)+1)
+		for i := range s {
+			s[i] = runes[r.Intn(len(runes))]
+		}
+		// a trailing backslash would escape the following separator
+		if s[len(s)-1] == '\\' {
+			s = append(s, 'a')
+		}
+		return string(s)
+	}
+	value := func() interface{} {
+		switch r.Intn(5) {
+		case 0:
+			return r.NormFloat64() * 1e10
+		case 1:
+			return r.Int63() - r.Int63()
+		case 2:
+			return r.Uint64()
+		case 3:
+			return r.Intn(2) == 0
+		default:
+			return str()
+		}
+	}
+	encoder := write.NewEncoder()
+	var buf []byte
+	var points []*write.Point
+	for i := 0; i < 1000; i++ {
+		p := write.NewPointWithMeasurement(str())
+		for j := r.Intn(3); j > 0; j-- {
+			p.AddTag(str(), str())
+		}
+		for j := r.Intn(3) + 1; j > 0; j-- {
+			p.AddField(str(), value())
+		}
+		if r.Intn(2) == 0 {
+			p.SetTime(time.Unix(0, r.Int63()))
+		}
+		var err error
+		buf, err = encoder.Append(buf, p)
+		if err != nil {
+			// field keys rejected by the encoder
+			continue
+		}
+		points = append(points, p)
+	}
+	parsed, err := write.ParseLineProtocol(string(buf), time.Nanosecond)
+	require.NoError(t, err)
+	require.Equal(t, len(points), len(parsed))
+	for i, p := range points {
+		require.Equal(t, p, parsed[i], i)
+	}
+}
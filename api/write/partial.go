@@ -0,0 +1,50 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RejectedLine is a line of a written batch, which was rejected by the server
+type RejectedLine struct {
+	// Line is the number of the line in the batch, starting at 1, 0 if it is unknown
+	Line int
+	// Record is the rejected line protocol record
+	Record string
+	// Point is the written point, nil if the line was written as a record
+	Point *Point
+	// Reason is the error reported by the server
+	Reason string
+}
+
+// PartialWriteError is returned when the server rejected some lines of a batch.
+// Lines not listed in Rejected were written.
+type PartialWriteError struct {
+	Rejected []RejectedLine
+	// Err is the error returned by the server, usually *http.Error
+	Err error
+}
+
+// Error implements error interface
+func (e *PartialWriteError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "partial write, %d rejected lines", len(e.Rejected))
+	for i, r := range e.Rejected {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString("; ")
+		}
+		fmt.Fprintf(&sb, "line %d: %s", r.Line, r.Reason)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error of the server
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
@@ -268,7 +268,8 @@ func TestTagEscapingKeyAndValue(t *testing.T) {
 	p.AddField("level", 2)
 
 	line := PointToLineProtocol(p, time.Nanosecond)
-	assert.Equal(t, "h\\\\n2\\\\no\\\\t_data,new\\\\nline=new\\\\nline,carriage\\\\rreturn=carriage\\\\rreturn,t\\\\tab=t\\\\tab level=2i\n", line)
+	// escaped with a single backslash, the same as lines sent by WriteAPI
+	assert.Equal(t, "h\\n2\\no\\t_data,new\\nline=new\\nline,carriage\\rreturn=carriage\\rreturn,t\\tab=t\\tab level=2i\n", line)
 }
 
 func TestEqualSignEscaping(t *testing.T) {
@@ -0,0 +1,190 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+)
+
+// RetryPolicy decides whether a failed write is retried and how long to wait before the next write.
+// Without a policy, connection errors and errors with status code 429 or higher are retried.
+type RetryPolicy interface {
+	// Retry returns whether the write failed with err is retried and the delay before the next write.
+	// attempts is the number of failed writes since the last successful write, 0 for the first failure.
+	// Zero delay means the default delay, Retry-After of the server or the exponential delay computed from retry options.
+	Retry(err *http.Error, attempts uint) (retry bool, delay time.Duration)
+}
+
+// RetryPolicyFunc is an adapter to use a function as RetryPolicy
+type RetryPolicyFunc func(err *http.Error, attempts uint) (bool, time.Duration)
+
+// Retry calls f(err, attempts)
+func (f RetryPolicyFunc) Retry(err *http.Error, attempts uint) (bool, time.Duration) {
+	return f(err, attempts)
+}
+
+// ErrCircuitOpen is returned by WriteAPIBlocking when a write is not allowed by the circuit breaker of write options
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RetryError is returned by WriteAPIBlocking when a retried write failed, it holds errors of all attempts
+type RetryError struct {
+	// Attempts are errors of write attempts in order
+	Attempts []error
+	// Err is the context error if the context was done while waiting for the next attempt,
+	// or ErrCircuitOpen if the next attempt was not allowed by the circuit breaker
+	Err error
+}
+
+// Error implements error interface
+func (e *RetryError) Error() string {
+	msg := fmt.Sprintf("write failed after %d attempts", len(e.Attempts))
+	if len(e.Attempts) > 0 {
+		msg += ": " + e.Attempts[len(e.Attempts)-1].Error()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns errors of attempts and the context error
+func (e *RetryError) Unwrap() []error {
+	if e.Err != nil {
+		return append(slices.Clone(e.Attempts), e.Err)
+	}
+	return e.Attempts
+}
+
+// CircuitState is state of CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed allows writes
+	CircuitClosed CircuitState = iota
+	// CircuitOpen stops writes after consecutive failures
+	CircuitOpen
+	// CircuitHalfOpen allows a single probe write after cool-down, its result closes or opens the circuit
+	CircuitHalfOpen
+)
+
+// String returns name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops writing to the server after consecutive failed writes.
+// Failed writes are writes failed with a retryable error, any response of the server to other writes is a success.
+// When the number of consecutive failures reaches the threshold, the circuit opens and batches are kept in the retry queue without writing.
+// After the cool-down the circuit is half-open, a single probe write is allowed and its success closes the circuit, its failure opens it again.
+// Other writes are not allowed until the probe completes. A probe without a recorded result is replaced by another one after the cool-down.
+// CircuitBreaker can be used concurrently, also by multiple write APIs.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold uint
+	coolDown  time.Duration
+	state     CircuitState
+	failures  uint
+	openedAt  time.Time
+	// probeAt is start of the probe write in the half-open state, zero if there is no probe
+	probeAt  time.Time
+	onChange func(from, to CircuitState)
+}
+
+// NewCircuitBreaker creates CircuitBreaker, which opens after failureThreshold consecutive failures and stays open for coolDown
+func NewCircuitBreaker(failureThreshold uint, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: max(failureThreshold, 1), coolDown: coolDown}
+}
+
+// SetStateChangeCallback sets callback called synchronously when the state changes
+func (b *CircuitBreaker) SetStateChangeCallback(cb func(from, to CircuitState)) *CircuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onChange = cb
+	return b
+}
+
+// State returns the current state, an open circuit is reported half-open after the cool-down
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.coolDown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Allow returns whether a write is allowed. An open circuit turns half-open after the cool-down.
+// In the half-open state, only the first caller is allowed to write a probe, until its result is recorded.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	switch {
+	case b.state == CircuitClosed:
+		b.mu.Unlock()
+		return true
+	case b.state == CircuitHalfOpen:
+		allow := time.Since(b.probeAt) >= b.coolDown
+		if allow {
+			b.probeAt = time.Now()
+		}
+		b.mu.Unlock()
+		return allow
+	case time.Since(b.openedAt) < b.coolDown:
+		b.mu.Unlock()
+		return false
+	}
+	b.probeAt = time.Now()
+	b.setState(CircuitHalfOpen)
+	return true
+}
+
+// RecordSuccess records a write, to which the server responded without a retryable error
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	b.failures = 0
+	b.probeAt = time.Time{}
+	if b.state == CircuitClosed {
+		b.mu.Unlock()
+		return
+	}
+	b.setState(CircuitClosed)
+}
+
+// RecordFailure records a write failed with a retryable error
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	b.failures++
+	b.probeAt = time.Time{}
+	if b.state == CircuitOpen || (b.state == CircuitClosed && b.failures < b.threshold) {
+		b.mu.Unlock()
+		return
+	}
+	b.openedAt = time.Now()
+	b.setState(CircuitOpen)
+}
+
+// setState changes state and calls the state change callback after unlocking b.mu, which must be locked
+func (b *CircuitBreaker) setState(state CircuitState) {
+	from := b.state
+	b.state = state
+	cb := b.onChange
+	b.mu.Unlock()
+	if cb != nil {
+		cb(from, state)
+	}
+}
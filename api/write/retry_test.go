@@ -0,0 +1,71 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyFunc(t *testing.T) {
+	var policy write.RetryPolicy = write.RetryPolicyFunc(func(err *http.Error, attempts uint) (bool, time.Duration) {
+		return err.StatusCode == 500, time.Duration(attempts) * time.Second
+	})
+	retry, delay := policy.Retry(&http.Error{StatusCode: 500}, 2)
+	assert.True(t, retry)
+	assert.Equal(t, 2*time.Second, delay)
+	retry, _ = policy.Retry(&http.Error{StatusCode: 503}, 0)
+	assert.False(t, retry)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	type change struct{ from, to write.CircuitState }
+	var changes []change
+	b := write.NewCircuitBreaker(2, 20*time.Millisecond).SetStateChangeCallback(func(from, to write.CircuitState) {
+		changes = append(changes, change{from, to})
+	})
+	assert.Equal(t, write.CircuitClosed, b.State())
+	b.RecordFailure()
+	b.RecordSuccess()
+	b.RecordFailure()
+	assert.True(t, b.Allow())
+	assert.Equal(t, write.CircuitClosed, b.State())
+	b.RecordFailure()
+	assert.Equal(t, write.CircuitOpen, b.State())
+	assert.False(t, b.Allow())
+	b.RecordFailure()
+
+	<-time.After(20 * time.Millisecond)
+	assert.Equal(t, write.CircuitHalfOpen, b.State())
+	// single probe is allowed
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	assert.Equal(t, write.CircuitHalfOpen, b.State())
+	b.RecordFailure()
+	assert.Equal(t, write.CircuitOpen, b.State())
+	assert.False(t, b.Allow())
+
+	<-time.After(20 * time.Millisecond)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	// probe without result is replaced after the cool-down
+	<-time.After(20 * time.Millisecond)
+	assert.True(t, b.Allow())
+	b.RecordSuccess()
+	assert.Equal(t, write.CircuitClosed, b.State())
+	assert.Equal(t, []change{
+		{write.CircuitClosed, write.CircuitOpen},
+		{write.CircuitOpen, write.CircuitHalfOpen},
+		{write.CircuitHalfOpen, write.CircuitOpen},
+		{write.CircuitOpen, write.CircuitHalfOpen},
+		{write.CircuitHalfOpen, write.CircuitClosed},
+	}, changes)
+	assert.Equal(t, "half-open", write.CircuitHalfOpen.String())
+	assert.Equal(t, "unknown", write.CircuitState(7).String())
+}
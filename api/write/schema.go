@@ -0,0 +1,327 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	lp "github.com/influxdata/line-protocol"
+)
+
+// FieldType is a data type of a field
+type FieldType string
+
+// Field types of a measurement schema, values match column data types of InfluxDB measurement schemas
+const (
+	FieldTypeFloat    FieldType = "float"
+	FieldTypeInteger  FieldType = "integer"
+	FieldTypeUnsigned FieldType = "unsigned"
+	FieldTypeBoolean  FieldType = "boolean"
+	FieldTypeString   FieldType = "string"
+)
+
+// MeasurementSchema describes tags and fields of a measurement.
+// An explicit schema, created by NewMeasurementSchema, defines all allowed tags and fields.
+// An implicit schema is inferred by SchemaRegistry from written points, it allows new tags and fields, but field types must not change.
+type MeasurementSchema struct {
+	name     string
+	explicit bool
+	tags     map[string]struct{}
+	fields   map[string]FieldType
+}
+
+// NewMeasurementSchema creates an explicit schema of measurement.
+// Use AddTag and AddField to define columns.
+func NewMeasurementSchema(measurement string) *MeasurementSchema {
+	return &MeasurementSchema{name: measurement, explicit: true, tags: make(map[string]struct{}), fields: make(map[string]FieldType)}
+}
+
+// AddTag adds a tag to the schema
+func (s *MeasurementSchema) AddTag(key string) *MeasurementSchema {
+	s.tags[key] = struct{}{}
+	return s
+}
+
+// AddField adds a field with the type to the schema
+func (s *MeasurementSchema) AddField(key string, fieldType FieldType) *MeasurementSchema {
+	s.fields[key] = fieldType
+	return s
+}
+
+// Name returns the measurement name
+func (s *MeasurementSchema) Name() string {
+	return s.name
+}
+
+// Explicit returns true if the schema defines all allowed tags and fields
+func (s *MeasurementSchema) Explicit() bool {
+	return s.explicit
+}
+
+// FieldType returns the type of field key, false if the field is not in the schema
+func (s *MeasurementSchema) FieldType(key string) (FieldType, bool) {
+	t, ok := s.fields[key]
+	return t, ok
+}
+
+// HasTag returns true if tag key is in the schema
+func (s *MeasurementSchema) HasTag(key string) bool {
+	_, ok := s.tags[key]
+	return ok
+}
+
+// SchemaViolation is the kind of SchemaError
+type SchemaViolation string
+
+const (
+	// SchemaViolationUnknownTag means a point has a tag not defined in an explicit schema
+	SchemaViolationUnknownTag SchemaViolation = "unknown tag"
+	// SchemaViolationUnknownField means a point has a field not defined in an explicit schema
+	SchemaViolationUnknownField SchemaViolation = "unknown field"
+	// SchemaViolationFieldType means a field value has a different type than the schema defines and it cannot be coerced
+	SchemaViolationFieldType SchemaViolation = "field type"
+)
+
+// SchemaError is returned when a point violates a measurement schema
+type SchemaError struct {
+	Measurement string
+	// Column is the key of the violating tag or field
+	Column    string
+	Violation SchemaViolation
+	// Expected is the field type defined by the schema, set for SchemaViolationFieldType
+	Expected FieldType
+	// Actual is the type of the field value, set for SchemaViolationFieldType
+	Actual FieldType
+}
+
+// Error implements error interface
+func (e *SchemaError) Error() string {
+	switch e.Violation {
+	case SchemaViolationUnknownTag:
+		return fmt.Sprintf("measurement '%s': tag '%s' is not defined in the schema", e.Measurement, e.Column)
+	case SchemaViolationUnknownField:
+		return fmt.Sprintf("measurement '%s': field '%s' is not defined in the schema", e.Measurement, e.Column)
+	default:
+		return fmt.Sprintf("measurement '%s': field '%s' has type %s, schema requires %s", e.Measurement, e.Column, e.Actual, e.Expected)
+	}
+}
+
+// SchemaRegistry holds measurement schemas used for validation of written points.
+// Points of measurements without a schema are not validated,
+// unless inferring of implicit schemas is enabled by SetInferImplicit.
+// SchemaRegistry can be used concurrently. Schemas must not be modified after they are registered.
+type SchemaRegistry struct {
+	mu            sync.RWMutex
+	schemas       map[string]*MeasurementSchema
+	inferImplicit bool
+}
+
+// NewSchemaRegistry creates an empty SchemaRegistry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]*MeasurementSchema)}
+}
+
+// Register adds schemas to the registry, replacing schemas of the same measurements
+func (r *SchemaRegistry) Register(schemas ...*MeasurementSchema) *SchemaRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range schemas {
+		r.schemas[s.name] = s
+	}
+	return r
+}
+
+// Schema returns the schema of measurement, nil if there is none
+func (r *SchemaRegistry) Schema(measurement string) *MeasurementSchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.schemas[measurement]
+}
+
+// SetInferImplicit enables inferring of implicit schemas, as in buckets with implicit schema type.
+// The first point of a measurement without a schema defines field types, later points must keep them.
+func (r *SchemaRegistry) SetInferImplicit(infer bool) *SchemaRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inferImplicit = infer
+	return r
+}
+
+// InferImplicit returns true if implicit schemas are inferred
+func (r *SchemaRegistry) InferImplicit() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.inferImplicit
+}
+
+// Check validates point against the schema of its measurement and returns *SchemaError on violation.
+// If coerce is true, field values of a different type are converted to the schema type when possible,
+// e.g. integer to float or "1.5" to float. The point itself is never modified, a copy with converted fields is returned.
+func (r *SchemaRegistry) Check(point *Point, coerce bool) (*Point, error) {
+	r.mu.RLock()
+	s := r.schemas[point.measurement]
+	infer := r.inferImplicit
+	r.mu.RUnlock()
+	if s == nil {
+		if !infer {
+			return point, nil
+		}
+		r.mu.Lock()
+		if s = r.schemas[point.measurement]; s == nil {
+			s = &MeasurementSchema{name: point.measurement, tags: make(map[string]struct{}), fields: make(map[string]FieldType)}
+			r.schemas[point.measurement] = s
+		}
+		r.mu.Unlock()
+	}
+	if s.explicit {
+		return s.check(point, coerce)
+	}
+	// implicit schemas are updated by checks
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, err := s.check(point, coerce)
+	if err == nil {
+		s.learn(p)
+	}
+	return p, err
+}
+
+// check validates point, it returns a copy of point if a field was coerced
+func (s *MeasurementSchema) check(point *Point, coerce bool) (*Point, error) {
+	if s.explicit {
+		for _, t := range point.tags {
+			if _, ok := s.tags[t.Key]; !ok {
+				return nil, &SchemaError{Measurement: s.name, Column: t.Key, Violation: SchemaViolationUnknownTag}
+			}
+		}
+	}
+	var fields []*lp.Field
+	for i, f := range point.fields {
+		expected, ok := s.fields[f.Key]
+		if !ok {
+			if s.explicit {
+				return nil, &SchemaError{Measurement: s.name, Column: f.Key, Violation: SchemaViolationUnknownField}
+			}
+			continue
+		}
+		actual := fieldTypeOf(f.Value)
+		if actual == expected {
+			continue
+		}
+		v, ok := coerceField(f.Value, expected)
+		if !coerce || !ok {
+			return nil, &SchemaError{Measurement: s.name, Column: f.Key, Violation: SchemaViolationFieldType, Expected: expected, Actual: actual}
+		}
+		if fields == nil {
+			fields = make([]*lp.Field, len(point.fields))
+			copy(fields, point.fields)
+		}
+		fields[i] = &lp.Field{Key: f.Key, Value: v}
+	}
+	if fields != nil {
+		return &Point{measurement: point.measurement, tags: point.tags, fields: fields, timestamp: point.timestamp}, nil
+	}
+	return point, nil
+}
+
+// learn adds new tags and fields of point to an implicit schema
+func (s *MeasurementSchema) learn(point *Point) {
+	for _, t := range point.tags {
+		s.tags[t.Key] = struct{}{}
+	}
+	for _, f := range point.fields {
+		if _, ok := s.fields[f.Key]; !ok {
+			s.fields[f.Key] = fieldTypeOf(f.Value)
+		}
+	}
+}
+
+// fieldTypeOf returns type of field value converted by convertField
+func fieldTypeOf(v interface{}) FieldType {
+	switch v.(type) {
+	case float64:
+		return FieldTypeFloat
+	case int64:
+		return FieldTypeInteger
+	case uint64:
+		return FieldTypeUnsigned
+	case bool:
+		return FieldTypeBoolean
+	default:
+		return FieldTypeString
+	}
+}
+
+// maxExactFloatInt is the greatest magnitude of integers, which are exactly representable by float64
+const maxExactFloatInt = 1 << 53
+
+// coerceField converts field value to fieldType, it returns false if the value cannot be converted without loss.
+// Integers are converted to float only if their magnitude does not exceed 2^53, greater ones lose precision as float.
+func coerceField(v interface{}, fieldType FieldType) (interface{}, bool) {
+	switch fieldType {
+	case FieldTypeFloat:
+		switch v := v.(type) {
+		case int64:
+			if v >= -maxExactFloatInt && v <= maxExactFloatInt {
+				return float64(v), true
+			}
+		case uint64:
+			if v <= maxExactFloatInt {
+				return float64(v), true
+			}
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			return f, err == nil
+		}
+	case FieldTypeInteger:
+		switch v := v.(type) {
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), true
+			}
+		case uint64:
+			if v <= math.MaxInt64 {
+				return int64(v), true
+			}
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			return i, err == nil
+		}
+	case FieldTypeUnsigned:
+		switch v := v.(type) {
+		case float64:
+			if v == math.Trunc(v) && v >= 0 && v < math.MaxUint64 {
+				return uint64(v), true
+			}
+		case int64:
+			if v >= 0 {
+				return uint64(v), true
+			}
+		case string:
+			u, err := strconv.ParseUint(v, 10, 64)
+			return u, err == nil
+		}
+	case FieldTypeBoolean:
+		if s, ok := v.(string); ok {
+			b, err := strconv.ParseBool(s)
+			return b, err == nil
+		}
+	case FieldTypeString:
+		switch v := v.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case int64:
+			return strconv.FormatInt(v, 10), true
+		case uint64:
+			return strconv.FormatUint(v, 10), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	}
+	return nil, false
+}
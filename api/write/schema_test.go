@@ -0,0 +1,132 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaRegistry_Explicit(t *testing.T) {
+	registry := write.NewSchemaRegistry().Register(
+		write.NewMeasurementSchema("cpu").
+			AddTag("host").
+			AddField("usage", write.FieldTypeFloat).
+			AddField("count", write.FieldTypeInteger).
+			AddField("up", write.FieldTypeBoolean))
+
+	p := write.NewPointWithMeasurement("cpu").AddTag("host", "h1").AddField("usage", 1.5).AddField("count", 3)
+	checked, err := registry.Check(p, false)
+	require.NoError(t, err)
+	assert.Same(t, p, checked)
+
+	// measurement without schema
+	p = write.NewPointWithMeasurement("mem").AddField("free", "a lot")
+	checked, err = registry.Check(p, false)
+	require.NoError(t, err)
+	assert.Same(t, p, checked)
+
+	var schemaErr *write.SchemaError
+	_, err = registry.Check(write.NewPointWithMeasurement("cpu").AddTag("region", "eu").AddField("usage", 1.5), false)
+	require.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, write.SchemaError{Measurement: "cpu", Column: "region", Violation: write.SchemaViolationUnknownTag}, *schemaErr)
+	assert.Equal(t, "measurement 'cpu': tag 'region' is not defined in the schema", err.Error())
+
+	_, err = registry.Check(write.NewPointWithMeasurement("cpu").AddField("idle", 1.5), true)
+	require.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, write.SchemaError{Measurement: "cpu", Column: "idle", Violation: write.SchemaViolationUnknownField}, *schemaErr)
+	assert.Equal(t, "measurement 'cpu': field 'idle' is not defined in the schema", err.Error())
+
+	// unsupported types are converted to strings by AddField
+	_, err = registry.Check(write.NewPointWithMeasurement("cpu").AddField("usage", struct{ v float64 }{1.5}), true)
+	require.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, write.SchemaError{Measurement: "cpu", Column: "usage", Violation: write.SchemaViolationFieldType, Expected: write.FieldTypeFloat, Actual: write.FieldTypeString}, *schemaErr)
+	assert.Equal(t, "measurement 'cpu': field 'usage' has type string, schema requires float", err.Error())
+
+	_, err = registry.Check(write.NewPointWithMeasurement("cpu").AddField("usage", 2), false)
+	require.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, write.FieldTypeInteger, schemaErr.Actual)
+}
+
+func TestSchemaRegistry_Coerce(t *testing.T) {
+	registry := write.NewSchemaRegistry().Register(
+		write.NewMeasurementSchema("m").
+			AddField("f", write.FieldTypeFloat).
+			AddField("i", write.FieldTypeInteger).
+			AddField("u", write.FieldTypeUnsigned).
+			AddField("b", write.FieldTypeBoolean).
+			AddField("s", write.FieldTypeString))
+
+	ts := time.Unix(60, 0)
+	p := write.NewPoint("m", nil, map[string]interface{}{"f": 1 << 53, "i": 2.0, "u": "3", "b": "true", "s": 4.5}, ts)
+	checked, err := registry.Check(p, true)
+	require.NoError(t, err)
+	values := make(map[string]interface{})
+	for _, f := range checked.FieldList() {
+		values[f.Key] = f.Value
+	}
+	assert.Equal(t, map[string]interface{}{"f": float64(1 << 53), "i": int64(2), "u": uint64(3), "b": true, "s": "4.5"}, values)
+	assert.Equal(t, ts, checked.Time())
+	// original point is not modified
+	assert.Equal(t, int64(1<<53), p.FieldList()[1].Value)
+
+	tests := []struct {
+		field string
+		value interface{}
+	}{
+		{"i", 2.5},
+		{"i", uint64(1 << 63)},
+		{"u", -1},
+		{"f", int64(1<<53 + 1)},
+		{"f", int64(-1<<53 - 1)},
+		{"f", uint64(1<<53 + 1)},
+		{"u", 1.5},
+		{"f", true},
+		{"f", "x"},
+		{"b", 1},
+		{"b", "yes"},
+	}
+	for _, test := range tests {
+		_, err := registry.Check(write.NewPointWithMeasurement("m").AddField(test.field, test.value), true)
+		assert.Error(t, err, "%s=%v", test.field, test.value)
+	}
+}
+
+func TestSchemaRegistry_InferImplicit(t *testing.T) {
+	registry := write.NewSchemaRegistry()
+	_, err := registry.Check(write.NewPointWithMeasurement("m").AddField("v", 1), false)
+	require.NoError(t, err)
+	assert.Nil(t, registry.Schema("m"))
+
+	registry.SetInferImplicit(true)
+	assert.True(t, registry.InferImplicit())
+	_, err = registry.Check(write.NewPointWithMeasurement("m").AddTag("t", "a").AddField("v", 1), false)
+	require.NoError(t, err)
+	schema := registry.Schema("m")
+	require.NotNil(t, schema)
+	assert.False(t, schema.Explicit())
+	assert.True(t, schema.HasTag("t"))
+	fieldType, ok := schema.FieldType("v")
+	assert.True(t, ok)
+	assert.Equal(t, write.FieldTypeInteger, fieldType)
+
+	// new columns are allowed
+	_, err = registry.Check(write.NewPointWithMeasurement("m").AddTag("t2", "b").AddField("v", 2).AddField("w", "x"), false)
+	require.NoError(t, err)
+	fieldType, _ = schema.FieldType("w")
+	assert.Equal(t, write.FieldTypeString, fieldType)
+
+	// types must not change
+	_, err = registry.Check(write.NewPointWithMeasurement("m").AddField("v", 2.5), false)
+	assert.EqualError(t, err, "measurement 'm': field 'v' has type float, schema requires integer")
+	checked, err := registry.Check(write.NewPointWithMeasurement("m").AddField("v", 2.0), true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), checked.FieldList()[0].Value)
+}
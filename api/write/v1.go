@@ -0,0 +1,18 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+// V1Target addresses the /write endpoint of InfluxDB 1.x
+type V1Target struct {
+	// Database to write into
+	Database string
+	// RetentionPolicy of the database, empty for the default retention policy
+	RetentionPolicy string
+	// Username for authentication, sent using HTTP basic authentication.
+	// If empty, the authorization of the HTTP service is used, e.g. a token.
+	Username string
+	// Password of the user
+	Password string
+}
@@ -6,12 +6,16 @@ package api
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	http2 "github.com/influxdata/influxdb-client-go/v2/api/http"
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/influxdata/influxdb-client-go/v2/internal/log"
 	iwrite "github.com/influxdata/influxdb-client-go/v2/internal/write"
 )
 
@@ -25,7 +29,11 @@ import (
 // Flush() can be used to trigger sending of batch when it doesn't have the batch-size.
 //
 // Synchronous writing is intended to use for writing less frequent data, such as a weather sensing, or if there is a need to have explicit control of failed batches.
-
+// When the server rejects some lines of a batch, *write.PartialWriteError is returned with the rejected records and points.
+//
+// Failed writes are not retried by default. Retrying is enabled with EnableRetrying(), then writes failed with a retryable error
+// are retried in-line according to retry settings in write.Options, until the context is done.
+// Writes are allowed and recorded by the circuit breaker of write.Options, if set. When the circuit is open, write.ErrCircuitOpen is returned.
 //
 // WriteAPIBlocking can be used concurrently.
 // When using multiple goroutines for writing, use a single WriteAPIBlocking instance in all goroutines.
@@ -34,6 +42,8 @@ type WriteAPIBlocking interface {
 	// WriteRecord writes lines without implicit batching by default, batch is created from given number of records.
 	// Automatic batching can be enabled by EnableBatching()
 	// Individual arguments can also be batches (multiple records separated by newline).
+	// If records validation is enabled in write.Options, invalid lines are skipped, valid lines are written
+	// and errors of invalid lines (*write.ParseError) are returned joined, unless writing fails.
 	// Non-blocking alternative is available in the WriteAPI interface
 	WriteRecord(ctx context.Context, line ...string) error
 	// WritePoint data point into bucket.
@@ -46,6 +56,11 @@ type WriteAPIBlocking interface {
 	EnableBatching()
 	// Flush forces write of buffer if batching is enabled, even buffer doesn't have the batch-size.
 	Flush(ctx context.Context) error
+	// EnableRetrying turns on retrying of writes failed with a retryable error, connection errors and HTTP status codes 429 and higher by default.
+	// Retries are controlled via write.Options: max retries, max retry time, retry interval, exponential base, max retry interval and retry policy.
+	// Retry-After sent by the server is honoured. Retrying stops when the next attempt would be after the context deadline.
+	// When a write was retried and failed, *write.RetryError with errors of all attempts is returned.
+	EnableRetrying()
 }
 
 // writeAPIBlocking implements WriteAPIBlocking interface
@@ -54,8 +69,12 @@ type writeAPIBlocking struct {
 	writeOptions *write.Options
 	// more appropriate Bool type from sync/atomic cannot be used because it is available since go 1.19
 	batching int32
+	// 1 if retrying is enabled
+	retrying int32
 	batch    []string
 	mu       sync.Mutex
+	// batchPoints holds points of lines in batch, nil for lines of records
+	batchPoints []*write.Point
 }
 
 // NewWriteAPIBlocking creates new instance of blocking write client for writing data to bucket belonging to org
@@ -63,6 +82,12 @@ func NewWriteAPIBlocking(org string, bucket string, service http2.Service, write
 	return &writeAPIBlocking{service: iwrite.NewService(org, bucket, service, writeOptions), writeOptions: writeOptions}
 }
 
+// NewWriteAPIBlockingV1 creates new instance of blocking write client for writing data to database and retention policy of target
+// using the /write endpoint of InfluxDB 1.x
+func NewWriteAPIBlockingV1(target write.V1Target, service http2.Service, writeOptions *write.Options) WriteAPIBlocking {
+	return &writeAPIBlocking{service: iwrite.NewV1Service(target, service, writeOptions), writeOptions: writeOptions}
+}
+
 // NewWriteAPIBlockingWithBatching creates new instance of blocking write client for writing data to bucket belonging to org with batching enabled
 func NewWriteAPIBlockingWithBatching(org string, bucket string, service http2.Service, writeOptions *write.Options) WriteAPIBlocking {
 	api := &writeAPIBlocking{service: iwrite.NewService(org, bucket, service, writeOptions), writeOptions: writeOptions}
@@ -79,28 +104,85 @@ func (w *writeAPIBlocking) EnableBatching() {
 	}
 }
 
-func (w *writeAPIBlocking) write(ctx context.Context, line string) error {
+// write writes line or adds it to the batch, points are the points encoded in line, nil for records
+func (w *writeAPIBlocking) write(ctx context.Context, line string, points []*write.Point) error {
 	if atomic.LoadInt32(&w.batching) > 0 {
 		w.mu.Lock()
 		defer w.mu.Unlock()
 		w.batch = append(w.batch, line)
+		// lines of batch are joined by new line
+		w.batchPoints = append(w.batchPoints, points...)
+		for i := len(points); i <= strings.Count(line, "\n"); i++ {
+			w.batchPoints = append(w.batchPoints, nil)
+		}
 		if len(w.batch) == int(w.writeOptions.BatchSize()) {
 			return w.flush(ctx)
 		}
 		return nil
 	}
-	err := w.service.WriteBatch(ctx, iwrite.NewBatch(line, w.writeOptions.MaxRetryTime()))
-	if err != nil {
-		return err
+	b := iwrite.NewBatch(line, w.writeOptions.MaxRetryTime())
+	b.Points = points
+	return w.writeBatch(ctx, b)
+}
+
+// writeBatch writes batch, it returns *write.PartialWriteError if the server rejected some lines.
+// If retrying is enabled, batch is retried and *write.RetryError is returned if it failed after retrying.
+// Attempts are allowed and recorded by the circuit breaker of write options, write.ErrCircuitOpen is returned when the circuit is open.
+func (w *writeAPIBlocking) writeBatch(ctx context.Context, b *iwrite.Batch) error {
+	var attempts []error
+	for {
+		if w.service.Paused() {
+			if len(attempts) == 0 {
+				return write.ErrCircuitOpen
+			}
+			return &write.RetryError{Attempts: attempts, Err: write.ErrCircuitOpen}
+		}
+		err := w.service.WriteBatch(ctx, b)
+		if err == nil {
+			w.service.RecordResult(true)
+			return nil
+		}
+		if pwerr := iwrite.NewPartialWriteError(b, err); pwerr != nil {
+			w.service.RecordResult(true)
+			return pwerr
+		}
+		attempts = append(attempts, err)
+		retry, delay := w.service.RetryDelay(err, uint(len(attempts)-1))
+		w.service.RecordResult(!retry)
+		if atomic.LoadInt32(&w.retrying) == 0 {
+			return err
+		}
+		next := time.Now().Add(delay)
+		deadline, hasDeadline := ctx.Deadline()
+		if !retry || uint(len(attempts)) > w.writeOptions.MaxRetries() || next.After(b.Expires) || (hasDeadline && next.After(deadline)) {
+			if len(attempts) == 1 {
+				return err
+			}
+			return &write.RetryError{Attempts: attempts}
+		}
+		log.Warnf("Write failed (attempt %d), retrying in %s: %s", len(attempts), delay, err.Error())
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return &write.RetryError{Attempts: attempts, Err: ctx.Err()}
+		}
 	}
-	return nil
 }
 
 func (w *writeAPIBlocking) WriteRecord(ctx context.Context, line ...string) error {
 	if len(line) == 0 {
 		return nil
 	}
-	return w.write(ctx, strings.Join(line, "\n"))
+	if w.writeOptions.ValidateRecords() {
+		record, errs := validRecords(strings.Join(line, "\n"), w.writeOptions.Precision())
+		if record != "" {
+			if err := w.write(ctx, w.service.FreezeTimestamps(record), nil); err != nil {
+				return err
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return w.write(ctx, w.service.FreezeTimestamps(strings.Join(line, "\n")), nil)
 }
 
 func (w *writeAPIBlocking) WritePoint(ctx context.Context, point ...*write.Point) error {
@@ -108,7 +190,7 @@ func (w *writeAPIBlocking) WritePoint(ctx context.Context, point ...*write.Point
 	if err != nil {
 		return err
 	}
-	return w.write(ctx, line)
+	return w.write(ctx, line, point)
 }
 
 // flush is unsychronized helper for creating and sending batch
@@ -118,13 +200,21 @@ func (w *writeAPIBlocking) flush(ctx context.Context) error {
 		body := strings.Join(w.batch, "\n")
 		w.batch = w.batch[:0]
 		b := iwrite.NewBatch(body, w.writeOptions.MaxRetryTime())
-		if err:= w.service.WriteBatch(ctx, b); err != nil {
+		if slices.ContainsFunc(w.batchPoints, func(p *write.Point) bool { return p != nil }) {
+			b.Points = slices.Clone(w.batchPoints)
+		}
+		w.batchPoints = w.batchPoints[:0]
+		if err := w.writeBatch(ctx, b); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (w *writeAPIBlocking) EnableRetrying() {
+	atomic.StoreInt32(&w.retrying, 1)
+}
+
 func (w *writeAPIBlocking) Flush(ctx context.Context) error {
 	if atomic.LoadInt32(&w.batching) > 0 {
 		w.mu.Lock()
@@ -6,8 +6,12 @@ package api
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -36,6 +40,63 @@ func TestWritePoint(t *testing.T) {
 	}
 }
 
+func TestWriteRecordValidation(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, write.DefaultOptions().SetValidateRecords(true))
+	err := writeAPI.WriteRecord(context.Background(), "test,a=1 v=1i 1", "test,a=2 v=2x 2\ntest,a=3 v=3i 3", "test,a 4")
+	require.Error(t, err)
+	assert.Equal(t, "line 2, column 12: invalid field value '2x'\nline 4, column 7: missing tag value", err.Error())
+	var parseErr *write.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 2, parseErr.Line)
+	assert.Equal(t, []string{"test,a=1 v=1i 1", "test,a=3 v=3i 3"}, service.Lines())
+	service.Close()
+
+	// nothing to write
+	err = writeAPI.WriteRecord(context.Background(), "test")
+	assert.EqualError(t, err, "line 1, column 5: missing fields")
+	assert.Equal(t, 0, service.Requests())
+
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "test v=1"))
+	assert.Equal(t, []string{"test v=1"}, service.Lines())
+}
+
+func TestWritePartialWriteError(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(5))
+	service.SetReplyError(&http2.Error{StatusCode: 400, Code: "invalid", Message: "errors encountered on line(s):\nline 2: field type conflict"})
+	p1 := write.NewPointWithMeasurement("test").AddField("a", 1)
+	p2 := write.NewPointWithMeasurement("test").AddField("a", "x")
+	err := writeAPI.WritePoint(context.Background(), p1, p2)
+	var pwerr *write.PartialWriteError
+	require.ErrorAs(t, err, &pwerr)
+	assert.Equal(t, []write.RejectedLine{{Line: 2, Record: `test a="x"`, Point: p2, Reason: "field type conflict"}}, pwerr.Rejected)
+
+	err = writeAPI.WriteRecord(context.Background(), "test a=1i", "test a=\"x\"")
+	require.ErrorAs(t, err, &pwerr)
+	assert.Equal(t, []write.RejectedLine{{Line: 2, Record: `test a="x"`, Reason: "field type conflict"}}, pwerr.Rejected)
+
+	// batch of records and points
+	writeAPI.EnableBatching()
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "test a=1i\ntest a=2i"))
+	require.NoError(t, writeAPI.WritePoint(context.Background(), p1, p2))
+	service.SetReplyError(&http2.Error{StatusCode: 400, Code: "invalid", Message: "errors encountered on line(s):\nline 4: field type conflict\nline 2: missing fields"})
+	err = writeAPI.Flush(context.Background())
+	require.ErrorAs(t, err, &pwerr)
+	assert.Equal(t, []write.RejectedLine{
+		{Line: 4, Record: `test a="x"`, Point: p2, Reason: "field type conflict"},
+		{Line: 2, Record: "test a=2i", Reason: "missing fields"},
+	}, pwerr.Rejected)
+
+	// not a partial write
+	service.SetReplyError(&http2.Error{StatusCode: 400, Code: "invalid", Message: "invalid"})
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "test a=1i"))
+	err = writeAPI.Flush(context.Background())
+	var httpErr *http2.Error
+	require.ErrorAs(t, err, &httpErr)
+	assert.False(t, errors.As(err, &pwerr))
+}
+
 func TestWriteRecord(t *testing.T) {
 	service := test.NewTestService(t, "http://localhost:8888")
 	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(5))
@@ -158,3 +219,166 @@ func TestWriteBatchIng(t *testing.T) {
 	assert.Equal(t, 1, service.Requests())
 	require.Len(t, service.Lines(), 4)
 }
+
+func TestWriteRetrying(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	var calls int
+	failures := 2
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		calls++
+		if calls <= failures {
+			return errors.New("connection refused")
+		}
+		return service.DecodeLines(body)
+	})
+	opts := write.DefaultOptions().SetRetryInterval(1).SetMaxRetries(3)
+	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, opts)
+
+	// retrying is disabled by default
+	err := writeAPI.WriteRecord(context.Background(), "test a=1i")
+	var herr *http2.Error
+	require.ErrorAs(t, err, &herr)
+	assert.Equal(t, 1, calls)
+
+	writeAPI.EnableRetrying()
+	calls = 0
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "test a=1i"))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []string{"test a=1i"}, service.Lines())
+
+	// all attempts fail
+	calls, failures = 0, 10
+	err = writeAPI.WriteRecord(context.Background(), "test a=2i")
+	var rerr *write.RetryError
+	require.ErrorAs(t, err, &rerr)
+	assert.Len(t, rerr.Attempts, 4)
+	assert.Nil(t, rerr.Err)
+	assert.Equal(t, 4, calls)
+	require.ErrorAs(t, err, &herr)
+	assert.Equal(t, "write failed after 4 attempts: connection refused", err.Error())
+
+	// non-retryable error
+	calls = 0
+	service.SetReplyError(&http2.Error{StatusCode: 400, Code: "invalid", Message: "bad request"})
+	err = writeAPI.WriteRecord(context.Background(), "test a=3i")
+	require.ErrorAs(t, err, &herr)
+	assert.Equal(t, 400, herr.StatusCode)
+	service.SetReplyError(nil)
+}
+
+func TestWriteRetryingContext(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		return errors.New("connection refused")
+	})
+	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, write.DefaultOptions().SetRetryInterval(50))
+	writeAPI.EnableRetrying()
+
+	// context is cancelled while waiting for retry
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err := writeAPI.WriteRecord(ctx, "test a=1i")
+	var rerr *write.RetryError
+	require.ErrorAs(t, err, &rerr)
+	assert.Len(t, rerr.Attempts, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// retrying stops when the next attempt would be after deadline
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	writeAPI = NewWriteAPIBlocking("my-org", "my-bucket", service, write.DefaultOptions().SetRetryInterval(200).SetExponentialBase(2))
+	writeAPI.EnableRetrying()
+	err = writeAPI.WriteRecord(ctx, "test a=1i")
+	require.ErrorAs(t, err, &rerr)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Nil(t, rerr.Err)
+	assert.True(t, len(rerr.Attempts) >= 2)
+}
+
+func TestWriteRetryingCircuitBreaker(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	var calls int
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		calls++
+		return errors.New("connection refused")
+	})
+	breaker := write.NewCircuitBreaker(2, time.Hour)
+	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, write.DefaultOptions().SetRetryInterval(1).SetCircuitBreaker(breaker))
+	writeAPI.EnableRetrying()
+
+	// retrying stops when the circuit opens
+	err := writeAPI.WriteRecord(context.Background(), "test a=1i")
+	var rerr *write.RetryError
+	require.ErrorAs(t, err, &rerr)
+	assert.Len(t, rerr.Attempts, 2)
+	assert.ErrorIs(t, err, write.ErrCircuitOpen)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, write.CircuitOpen, breaker.State())
+
+	// open circuit fails fast
+	err = writeAPI.WriteRecord(context.Background(), "test a=2i")
+	assert.Equal(t, write.ErrCircuitOpen, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWriteIdempotent(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	var bodies []string
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		b, _ := io.ReadAll(body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	opts := write.DefaultOptions().SetRetryInterval(1).SetIdempotentWrites(true)
+	writeAPI := NewWriteAPIBlocking("my-org", "my-bucket", service, opts)
+	writeAPI.EnableRetrying()
+
+	now := time.Now().UnixNano()
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "test a=1i", "test a=2i 10"))
+	require.Len(t, bodies, 2)
+	// retried batch has the timestamp set when it was written
+	assert.Equal(t, bodies[0], bodies[1])
+	lines := strings.Split(bodies[0], "\n")
+	require.Len(t, lines, 2)
+	ts, err := strconv.ParseInt(strings.TrimPrefix(lines[0], "test a=1i "), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, ts >= now && ts <= time.Now().UnixNano(), ts)
+	assert.Equal(t, "test a=2i 10", lines[1])
+
+	// the same batch is not written again
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "test a=2i 10"))
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "test a=2i 10"))
+	assert.Len(t, bodies, 3)
+}
+
+func TestWriteV1(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		user, pass, _ := r.BasicAuth()
+		mu.Lock()
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery+" "+user+":"+pass+" "+string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	service := http2.NewService(server.URL+"/", "", http2.DefaultOptions())
+	target := write.V1Target{Database: "telegraf", RetentionPolicy: "weekly", Username: "user", Password: "pass"}
+
+	blocking := NewWriteAPIBlockingV1(target, service, write.DefaultOptions())
+	require.NoError(t, blocking.WriteRecord(context.Background(), "cpu v=1i"))
+
+	writeAPI := NewWriteAPIV1(write.V1Target{Database: "telegraf"}, service, write.DefaultOptions().SetPrecision(time.Second))
+	writeAPI.WritePoint(write.NewPointWithMeasurement("cpu").AddField("v", 2).SetTime(time.Unix(10, 0)))
+	writeAPI.Close()
+
+	assert.Equal(t, []string{
+		"/write?db=telegraf&precision=ns&rp=weekly user:pass cpu v=1i",
+		"/write?db=telegraf&precision=s : cpu v=2i 10\n",
+	}, requests)
+}
@@ -0,0 +1,373 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	http2 "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/influxdata/influxdb-client-go/v2/internal/log"
+	iwrite "github.com/influxdata/influxdb-client-go/v2/internal/write"
+	lp "github.com/influxdata/line-protocol"
+)
+
+// RouteFunc selects org and bucket where a point with measurement and tags is written.
+// Points with empty bucket are not written.
+type RouteFunc func(measurement string, tags []*lp.Tag) (org string, bucket string)
+
+// RoutingWriteAPI is Write client interface with non-blocking methods for writing time series data asynchronously into multiple buckets.
+// Lines are batched per destination org and bucket, all destinations share a single buffer goroutine, flush interval,
+// memory budget and retry backoff, a retryable error of one destination pauses writes of all destinations.
+// Destinations without writes for the destination idle timeout of write options are removed.
+// RoutingWriteAPI can be used concurrently.
+type RoutingWriteAPI interface {
+	// WriteRecord writes asynchronously line protocol records, each line is written into the bucket selected by the route function.
+	// Invalid lines are reported via Errors().
+	WriteRecord(line string)
+	// WriteRecordTo writes asynchronously line protocol record into bucket of org
+	WriteRecordTo(org, bucket, line string)
+	// WritePoint writes asynchronously Point into the bucket selected by the route function
+	WritePoint(point *write.Point)
+	// WritePointTo writes asynchronously Point into bucket of org
+	WritePointTo(org, bucket string, point *write.Point)
+	// Flush forces all pending writes of all destinations to be sent
+	Flush()
+	// Errors returns a channel for reading errors which occurs during async writes.
+	// Errors of writing into a destination are *RoutingWriteError.
+	// Must be called before performing any writes for errors to be collected.
+	// New error of writing a batch is skipped when channel is not read.
+	Errors() <-chan error
+	// Close finishes outstanding write operations and stops background routines
+	Close()
+}
+
+// RoutingWriteError is an error of writing into a destination of RoutingWriteAPI
+type RoutingWriteError struct {
+	Org    string
+	Bucket string
+	Err    error
+}
+
+// Error implements error interface
+func (e *RoutingWriteError) Error() string {
+	return fmt.Sprintf("%s/%s: %s", e.Org, e.Bucket, e.Err.Error())
+}
+
+// Unwrap returns the write error
+func (e *RoutingWriteError) Unwrap() error {
+	return e.Err
+}
+
+// destination is org and bucket where lines are written
+type destination struct {
+	org    string
+	bucket string
+}
+
+// routedLine is a line sent to the buffer of destination
+type routedLine struct {
+	dest destination
+	bufferedLine
+}
+
+// routeBuffer holds lines and write service of a destination
+type routeBuffer struct {
+	buffer    lineBuffer
+	service   *iwrite.Service
+	lastWrite time.Time
+}
+
+// routedBatch is a request for the write goroutine.
+// It writes batch using service, retries queued batches of service when retry is set, or flushes retry queue of service when batch is nil,
+// and then closes done, if set.
+type routedBatch struct {
+	dest    destination
+	service *iwrite.Service
+	batch   *iwrite.Batch
+	retry   bool
+	done    chan struct{}
+}
+
+// routingWriteAPI implements RoutingWriteAPI
+type routingWriteAPI struct {
+	// service encodes points and holds memory budget and retry backoff shared by services of destinations
+	service      *iwrite.Service
+	route        RouteFunc
+	writeOptions *write.Options
+	// buffers of destinations, accessed only by the buffer goroutine
+	buffers map[destination]*routeBuffer
+
+	errCh      chan error
+	bufferCh   chan routedLine
+	writeCh    chan routedBatch
+	flushCh    chan chan struct{}
+	bufferStop chan struct{}
+	doneCh     chan struct{}
+	closingMu  sync.Mutex
+	closed     bool
+	// set to 1 when Errors() was called
+	isErrChReader int32
+}
+
+// NewRoutingWriteAPI returns new non-blocking write client for writing data into multiple buckets.
+// route selects destination of points written by WritePoint and lines written by WriteRecord, it can be nil if they are not used.
+func NewRoutingWriteAPI(service http2.Service, writeOptions *write.Options, route RouteFunc) RoutingWriteAPI {
+	w := &routingWriteAPI{
+		service:      iwrite.NewService("", "", service, writeOptions),
+		route:        route,
+		writeOptions: writeOptions,
+		buffers:      make(map[destination]*routeBuffer),
+		errCh:        make(chan error, 1),
+		bufferCh:     make(chan routedLine, writeOptions.BufferCapacity()),
+		writeCh:      make(chan routedBatch),
+		flushCh:      make(chan chan struct{}),
+		bufferStop:   make(chan struct{}),
+		doneCh:       make(chan struct{}),
+	}
+
+	go w.bufferProc()
+	go w.writeProc()
+
+	return w
+}
+
+func (w *routingWriteAPI) bufferProc() {
+	log.Info("Routing buffer proc started")
+	ticker := time.NewTicker(time.Duration(w.writeOptions.FlushInterval()) * time.Millisecond)
+x:
+	for {
+		select {
+		case l := <-w.bufferCh:
+			w.addToBuffer(l)
+		case <-ticker.C:
+			w.flushBuffers()
+			w.retryBuffers()
+			w.removeIdle()
+		case done := <-w.flushCh:
+			w.drainBufferCh()
+			w.flushBuffers()
+			for dest, b := range w.buffers {
+				w.writeCh <- routedBatch{dest: dest, service: b.service}
+			}
+			w.writeCh <- routedBatch{done: done}
+		case <-w.bufferStop:
+			ticker.Stop()
+			w.drainBufferCh()
+			w.flushBuffers()
+			break x
+		}
+	}
+	log.Info("Routing buffer proc finished")
+	w.doneCh <- struct{}{}
+}
+
+// addToBuffer adds line to the buffer of its destination, the destination is created if it doesn't exist
+func (w *routingWriteAPI) addToBuffer(l routedLine) {
+	b, ok := w.buffers[l.dest]
+	if !ok {
+		log.Infof("Adding write destination %s/%s", l.dest.org, l.dest.bucket)
+		b = &routeBuffer{service: w.service.NewSibling(l.dest.org, l.dest.bucket)}
+		w.buffers[l.dest] = b
+	}
+	b.lastWrite = time.Now()
+	b.buffer.add(l.bufferedLine, w.writeOptions, func(*lineBuffer) {
+		w.flushBuffer(l.dest, b)
+	})
+}
+
+// drainBufferCh adds lines waiting in the buffered channel to the buffers
+func (w *routingWriteAPI) drainBufferCh() {
+	for {
+		select {
+		case l := <-w.bufferCh:
+			w.addToBuffer(l)
+		default:
+			return
+		}
+	}
+}
+
+func (w *routingWriteAPI) flushBuffers() {
+	for dest, b := range w.buffers {
+		w.flushBuffer(dest, b)
+	}
+}
+
+// flushBuffer sends lines in buffer of dest as a batch to the write goroutine
+func (w *routingWriteAPI) flushBuffer(dest destination, b *routeBuffer) {
+	if batch := b.buffer.take(w.writeOptions); batch != nil {
+		log.Infof("sending batch to %s/%s", dest.org, dest.bucket)
+		w.writeCh <- routedBatch{dest: dest, service: b.service, batch: batch}
+	}
+}
+
+// retryBuffers lets the write goroutine retry queued batches of destinations, which are otherwise retried only when a new batch comes
+func (w *routingWriteAPI) retryBuffers() {
+	for dest, b := range w.buffers {
+		w.writeCh <- routedBatch{dest: dest, service: b.service, retry: true}
+	}
+}
+
+// removeIdle removes destinations without writes for the destination idle timeout, their retry queues are flushed
+func (w *routingWriteAPI) removeIdle() {
+	timeout := time.Duration(w.writeOptions.DestinationIdleTimeout()) * time.Millisecond
+	if timeout == 0 {
+		return
+	}
+	for dest, b := range w.buffers {
+		if len(b.buffer.lines) == 0 && time.Since(b.lastWrite) >= timeout {
+			log.Infof("Removing idle write destination %s/%s", dest.org, dest.bucket)
+			delete(w.buffers, dest)
+			w.writeCh <- routedBatch{dest: dest, service: b.service}
+		}
+	}
+}
+
+func (w *routingWriteAPI) writeProc() {
+	log.Info("Routing write proc started")
+	for r := range w.writeCh {
+		if r.batch != nil {
+			w.reportWriteError(r.dest, r.service.HandleWrite(context.Background(), r.batch))
+		} else if r.retry {
+			if r.service.HasRetries() {
+				w.reportWriteError(r.dest, r.service.HandleWrite(context.Background(), nil))
+			}
+		} else if r.service != nil {
+			r.service.Flush()
+		}
+		if r.done != nil {
+			close(r.done)
+		}
+	}
+	log.Info("Routing write proc finished")
+	w.doneCh <- struct{}{}
+}
+
+// reportWriteError sends error of writing into dest to the errors channel without blocking, if it is read
+func (w *routingWriteAPI) reportWriteError(dest destination, err error) {
+	if err == nil || !w.isErrChanRead() {
+		return
+	}
+	select {
+	case w.errCh <- &RoutingWriteError{Org: dest.org, Bucket: dest.bucket, Err: err}:
+	default:
+		log.Warn("Cannot write error to error channel, it is not read")
+	}
+}
+
+// WriteRecord writes asynchronously line protocol records, each line is written into the bucket selected by the route function
+func (w *routingWriteAPI) WriteRecord(line string) {
+	for l, err := range write.ParseLines(line, w.writeOptions.Precision()) {
+		if err != nil {
+			log.Errorf("invalid record: %s\n", err.Error())
+			w.reportError(err)
+			continue
+		}
+		if dest, ok := w.routeOf(l.Point); ok {
+			w.add(dest, w.service.FreezeTimestamps(l.Text)+"\n", nil)
+		}
+	}
+}
+
+// WriteRecordTo writes asynchronously line protocol record into bucket of org
+func (w *routingWriteAPI) WriteRecordTo(org, bucket, line string) {
+	w.add(destination{org: org, bucket: bucket}, w.service.FreezeTimestamps(line)+"\n", nil)
+}
+
+// WritePoint writes asynchronously Point into the bucket selected by the route function
+func (w *routingWriteAPI) WritePoint(point *write.Point) {
+	if dest, ok := w.routeOf(point); ok {
+		w.WritePointTo(dest.org, dest.bucket, point)
+	}
+}
+
+// WritePointTo writes asynchronously Point into bucket of org
+func (w *routingWriteAPI) WritePointTo(org, bucket string, point *write.Point) {
+	line, err := w.service.EncodePoints(point)
+	if err != nil {
+		log.Errorf("point encoding error: %s\n", err.Error())
+		w.reportError(err)
+		return
+	}
+	w.add(destination{org: org, bucket: bucket}, line, point)
+}
+
+// routeOf returns destination of point selected by the route function, it returns false and reports an error if there is none
+func (w *routingWriteAPI) routeOf(point *write.Point) (destination, bool) {
+	if w.route == nil {
+		w.reportError(errors.New("route function is not set"))
+		return destination{}, false
+	}
+	org, bucket := w.route(point.Name(), point.TagList())
+	if bucket == "" {
+		w.reportError(fmt.Errorf("no destination for measurement %s", point.Name()))
+		return destination{}, false
+	}
+	return destination{org: org, bucket: bucket}, true
+}
+
+// add reserves memory budget for line and sends it to the buffer of dest
+func (w *routingWriteAPI) add(dest destination, line string, point *write.Point) {
+	if err := w.service.AcquireBuffer(context.Background(), line, point); err != nil {
+		w.reportError(err)
+		return
+	}
+	w.bufferCh <- routedLine{dest: dest, bufferedLine: bufferedLine{line: line, point: point}}
+}
+
+// Flush forces all pending writes of all destinations to be sent.
+// Flush also tries sending batches from retry queues without additional retrying.
+func (w *routingWriteAPI) Flush() {
+	done := make(chan struct{})
+	w.flushCh <- done
+	<-done
+}
+
+// Errors returns a channel for reading errors which occurs during async writes
+func (w *routingWriteAPI) Errors() <-chan error {
+	atomic.StoreInt32(&w.isErrChReader, 1)
+	return w.errCh
+}
+
+func (w *routingWriteAPI) isErrChanRead() bool {
+	return atomic.LoadInt32(&w.isErrChReader) > 0
+}
+
+// reportError sends err to the errors channel without blocking, if it is read
+func (w *routingWriteAPI) reportError(err error) {
+	if !w.isErrChanRead() {
+		return
+	}
+	select {
+	case w.errCh <- err:
+	default:
+		log.Warn("Cannot write error to error channel, it is not read")
+	}
+}
+
+// Close finishes outstanding write operations and stops background routines
+func (w *routingWriteAPI) Close() {
+	w.closingMu.Lock()
+	defer w.closingMu.Unlock()
+	if w.closed {
+		return
+	}
+	w.Flush()
+
+	close(w.bufferStop)
+	<-w.doneCh
+
+	close(w.writeCh)
+	<-w.doneCh
+
+	close(w.errCh)
+	w.closed = true
+}
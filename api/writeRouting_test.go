@@ -0,0 +1,223 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"io"
+	ihttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	iwrite "github.com/influxdata/influxdb-client-go/v2/internal/write"
+	lp "github.com/influxdata/line-protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bucketServer is a test server storing written lines per org and bucket
+type bucketServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	lines map[string][]string
+	// status returns status code of a write into bucket
+	status func(bucket string) int
+}
+
+func newBucketServer(t *testing.T) *bucketServer {
+	s := &bucketServer{lines: make(map[string][]string), status: func(string) int { return ihttp.StatusNoContent }}
+	s.Server = httptest.NewServer(ihttp.HandlerFunc(func(w ihttp.ResponseWriter, r *ihttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bucket := r.URL.Query().Get("bucket")
+		s.mu.Lock()
+		status := s.status(bucket)
+		if status == ihttp.StatusNoContent {
+			key := r.URL.Query().Get("org") + "/" + bucket
+			s.lines[key] = append(s.lines[key], strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")...)
+		}
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *bucketServer) Lines(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lines[key]
+}
+
+func tenantRoute(measurement string, tags []*lp.Tag) (string, string) {
+	if measurement == "skip" {
+		return "", ""
+	}
+	for _, t := range tags {
+		if t.Key == "tenant" {
+			return "my-org", "bucket-" + t.Value
+		}
+	}
+	return "my-org", "default"
+}
+
+func TestRoutingWriteAPI(t *testing.T) {
+	server := newBucketServer(t)
+	svc := http.NewService(server.URL, "my-token", http.DefaultOptions())
+	writeAPI := NewRoutingWriteAPI(svc, write.DefaultOptions().SetBatchSize(2), tenantRoute)
+	errCh := writeAPI.Errors()
+	// errors are not queued, the error of each write is read before the next one
+	nextErr := func() error {
+		select {
+		case err := <-errCh:
+			return err
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	writeAPI.WriteRecord("cpu,tenant=a v=1i\ncpu,tenant=b v=1i")
+	writeAPI.WriteRecord("cpu,tenant=a v=")
+	var perr *write.ParseError
+	assert.ErrorAs(t, nextErr(), &perr)
+	writeAPI.WriteRecord("mem v=1i\nskip v=1i")
+	assert.EqualError(t, nextErr(), "no destination for measurement skip")
+	writeAPI.WriteRecord("cpu,tenant=a v=2i")
+	writeAPI.WritePoint(write.NewPointWithMeasurement("cpu").AddTag("tenant", "b").AddField("v", 2))
+	writeAPI.WritePoint(write.NewPointWithMeasurement("skip").AddField("v", 2))
+	assert.EqualError(t, nextErr(), "no destination for measurement skip")
+	writeAPI.WritePointTo("other-org", "other", write.NewPointWithMeasurement("cpu").AddTag("tenant", "a").AddField("v", 3))
+	writeAPI.WriteRecordTo("my-org", "bucket-a", "cpu,tenant=a v=3i")
+	writeAPI.Flush()
+	assert.Equal(t, []string{"cpu,tenant=a v=1i", "cpu,tenant=a v=2i", "cpu,tenant=a v=3i"}, server.Lines("my-org/bucket-a"))
+	assert.Equal(t, []string{"cpu,tenant=b v=1i", "cpu,tenant=b v=2i"}, server.Lines("my-org/bucket-b"))
+	assert.Equal(t, []string{"mem v=1i"}, server.Lines("my-org/default"))
+	assert.Equal(t, []string{"cpu,tenant=a v=3i"}, server.Lines("other-org/other"))
+
+	writeAPI.Close()
+	assert.Len(t, errCh, 0)
+}
+
+func TestRoutingWriteAPI_SharedBackoff(t *testing.T) {
+	server := newBucketServer(t)
+	server.status = func(bucket string) int {
+		if bucket == "a" {
+			return ihttp.StatusServiceUnavailable
+		}
+		return ihttp.StatusNoContent
+	}
+	svc := http.NewService(server.URL, "my-token", http.DefaultOptions())
+	writeAPI := NewRoutingWriteAPI(svc, write.DefaultOptions().SetBatchSize(1).SetRetryInterval(10_000), nil)
+	errCh := writeAPI.Errors()
+
+	writeAPI.WriteRecordTo("my-org", "a", "cpu v=1i")
+	err := <-errCh
+	var rerr *RoutingWriteError
+	require.ErrorAs(t, err, &rerr)
+	assert.Equal(t, "my-org", rerr.Org)
+	assert.Equal(t, "a", rerr.Bucket)
+	var herr *http.Error
+	require.True(t, errors.As(err, &herr))
+	assert.Equal(t, ihttp.StatusServiceUnavailable, herr.StatusCode)
+
+	// writes into other buckets are paused
+	writeAPI.WriteRecordTo("my-org", "b", "cpu v=2i")
+	<-time.After(50 * time.Millisecond)
+	assert.Empty(t, server.Lines("my-org/b"))
+
+	// retry queues are written by Flush
+	go func() {
+		for range errCh {
+		}
+	}()
+	writeAPI.Close()
+	assert.Equal(t, []string{"cpu v=2i"}, server.Lines("my-org/b"))
+}
+
+func TestRoutingWriteAPI_RetryWithoutNewBatches(t *testing.T) {
+	server := newBucketServer(t)
+	recovered := time.Now().Add(300 * time.Millisecond)
+	server.status = func(string) int {
+		if time.Now().Before(recovered) {
+			return ihttp.StatusServiceUnavailable
+		}
+		return ihttp.StatusNoContent
+	}
+	svc := http.NewService(server.URL, "my-token", http.DefaultOptions())
+	writeAPI := NewRoutingWriteAPI(svc, write.DefaultOptions().SetBatchSize(1).SetFlushInterval(50).SetRetryInterval(50), nil)
+	defer writeAPI.Close()
+
+	writeAPI.WriteRecordTo("my-org", "a", "cpu v=1i")
+	// queued batch is retried in flush intervals, without Flush
+	assert.Eventually(t, func() bool {
+		return len(server.Lines("my-org/a")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRoutingWriteAPI_ErrorsNotRead(t *testing.T) {
+	server := newBucketServer(t)
+	svc := http.NewService(server.URL, "my-token", http.DefaultOptions())
+	writeAPI := NewRoutingWriteAPI(svc, write.DefaultOptions().SetBatchSize(1), nil)
+	// errors channel is obtained, but not read
+	_ = writeAPI.Errors()
+	done := make(chan struct{})
+	go func() {
+		// points without route function fail and fill the errors channel
+		for i := 0; i < 3; i++ {
+			writeAPI.WritePoint(write.NewPointWithMeasurement("cpu").AddField("v", i))
+		}
+		writeAPI.WriteRecordTo("my-org", "a", "cpu v=1i")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "errors block writing")
+	}
+	writeAPI.Close()
+	assert.Equal(t, []string{"cpu v=1i"}, server.Lines("my-org/a"))
+}
+
+func TestRoutingWriteAPI_RemoveIdle(t *testing.T) {
+	opts := write.DefaultOptions().SetDestinationIdleTimeout(1_000)
+	w := &routingWriteAPI{
+		service:      iwrite.NewService("", "", http.NewService("http://localhost:8086", "", http.DefaultOptions()), opts),
+		writeOptions: opts,
+		buffers:      make(map[destination]*routeBuffer),
+		writeCh:      make(chan routedBatch, 10),
+	}
+	idle := destination{org: "my-org", bucket: "idle"}
+	active := destination{org: "my-org", bucket: "active"}
+	pending := destination{org: "my-org", bucket: "pending"}
+	w.addToBuffer(routedLine{dest: idle, bufferedLine: bufferedLine{line: "cpu v=1i\n"}})
+	w.addToBuffer(routedLine{dest: active, bufferedLine: bufferedLine{line: "cpu v=1i\n"}})
+	w.addToBuffer(routedLine{dest: pending, bufferedLine: bufferedLine{line: "cpu v=1i\n"}})
+	w.flushBuffers()
+	require.Len(t, w.writeCh, 3)
+	for range 3 {
+		<-w.writeCh
+	}
+	w.buffers[idle].lastWrite = time.Now().Add(-2 * time.Second)
+	w.buffers[pending].lastWrite = time.Now().Add(-2 * time.Second)
+	w.buffers[pending].buffer.lines = append(w.buffers[pending].buffer.lines, "cpu v=2i\n")
+
+	w.removeIdle()
+	assert.Len(t, w.buffers, 2)
+	assert.NotContains(t, w.buffers, idle)
+	require.Len(t, w.writeCh, 1)
+	r := <-w.writeCh
+	assert.Equal(t, idle, r.dest)
+	assert.Nil(t, r.batch)
+	assert.NotNil(t, r.service)
+
+	opts.SetDestinationIdleTimeout(0)
+	w.buffers[active].lastWrite = time.Now().Add(-time.Hour)
+	w.removeIdle()
+	assert.Len(t, w.buffers, 2)
+}
@@ -5,9 +5,11 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math"
+	"math/rand"
 	ihttp "net/http"
 	"net/http/httptest"
 	"runtime"
@@ -61,6 +63,276 @@ func TestWriteAPIImpl_Write(t *testing.T) {
 	}
 }
 
+func TestWriteAPIImpl_ValidateRecords(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(2).SetValidateRecords(true))
+	errCh := writeAPI.Errors()
+	var errs []error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		for err := range errCh {
+			errs = append(errs, err)
+		}
+		wg.Done()
+	}()
+	writeAPI.WriteRecord("test,a=1 v=1i 1")
+	writeAPI.WriteRecord("test,a=2 v=x 2\ntest,a=3 v=3i 3")
+	writeAPI.WriteRecord("test,a=4")
+	writeAPI.Close()
+	wg.Wait()
+	assert.Equal(t, []string{"test,a=1 v=1i 1", "test,a=3 v=3i 3"}, service.Lines())
+	require.Len(t, errs, 2)
+	var parseErr *write.ParseError
+	require.ErrorAs(t, errs[0], &parseErr)
+	assert.Equal(t, "test,a=2 v=x 2", parseErr.Text)
+	assert.EqualError(t, errs[1], "line 1, column 9: missing fields")
+}
+
+func TestWriteAPIImpl_PartialWriteError(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(4))
+	errCh := writeAPI.Errors()
+	var errs []error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		for err := range errCh {
+			errs = append(errs, err)
+		}
+		wg.Done()
+	}()
+	service.SetReplyError(&http.Error{StatusCode: 400, Code: "invalid", Message: "errors encountered on line(s):\nline 2: missing fields\nline 4: field type conflict"})
+	p := write.NewPointWithMeasurement("test").AddField("a", "x")
+	writeAPI.WriteRecord("test a=1i\ntest")
+	writeAPI.WritePoint(write.NewPointWithMeasurement("test").AddField("a", 2))
+	writeAPI.WritePoint(p)
+	writeAPI.Close()
+	wg.Wait()
+	require.Len(t, errs, 1)
+	var pwerr *write.PartialWriteError
+	require.ErrorAs(t, errs[0], &pwerr)
+	assert.Equal(t, []write.RejectedLine{
+		{Line: 2, Record: "test", Reason: "missing fields"},
+		{Line: 4, Record: `test a="x"`, Point: p, Reason: "field type conflict"},
+	}, pwerr.Rejected)
+}
+
+func TestWriteAPIImpl_MaxBatchBytes(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(100).SetMaxBatchBytes(25).SetFlushInterval(60_000))
+	// 10 bytes each
+	for i := 0; i < 5; i++ {
+		writeAPI.WriteRecord(fmt.Sprintf("test v=%di", i))
+	}
+	// larger than max batch bytes, sent alone
+	writeAPI.WriteRecord("test,a=aaaaaaaaaaaaaaaaaaaaaaaa v=5i")
+	writeAPI.WriteRecord("test v=6i")
+	writeAPI.Close()
+	assert.Equal(t, 5, service.Requests())
+	assert.Len(t, service.Lines(), 7)
+}
+
+func TestWriteAPIImpl_BatchSizeCountsLines(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(3).SetFlushInterval(60_000))
+	writeAPI.WriteRecord("test v=1i\ntest v=2i")
+	writeAPI.WritePoint(write.NewPointWithMeasurement("test").AddField("v", 3))
+	// batch is sent once it has 3 lines
+	assert.Eventually(t, func() bool { return len(service.Lines()) == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, service.Requests())
+	writeAPI.WriteRecord("test v=4i")
+	writeAPI.Close()
+	assert.Equal(t, 2, service.Requests())
+	assert.Len(t, service.Lines(), 4)
+}
+
+func TestWriteAPIImpl_MemoryBudget(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	opts := write.DefaultOptions().SetBatchSize(100).SetFlushInterval(60_000).SetMemoryBudget(25).SetOverflowPolicy(write.OverflowDropNewest)
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, opts)
+	errCh := writeAPI.Errors()
+	var errs []error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		for err := range errCh {
+			errs = append(errs, err)
+		}
+		wg.Done()
+	}()
+	for i := 0; i < 3; i++ {
+		writeAPI.WriteRecord(fmt.Sprintf("test v=%di", i))
+	}
+	writeAPI.Flush()
+	writeAPI.WriteRecord("test v=3i")
+	writeAPI.Close()
+	wg.Wait()
+	assert.Equal(t, []string{"test v=0i", "test v=1i", "test v=3i"}, service.Lines())
+	assert.Equal(t, []error{write.ErrMemoryBudgetExceeded}, errs)
+}
+
+func TestWriteAPIImpl_MemoryBudgetErrorsNotRead(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	opts := write.DefaultOptions().SetBatchSize(100).SetFlushInterval(60_000).SetMemoryBudget(25).SetOverflowPolicy(write.OverflowDropNewest)
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, opts)
+	// errors channel is obtained, but not read
+	_ = writeAPI.Errors()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			writeAPI.WriteRecord(fmt.Sprintf("test v=%di", i))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "dropped lines block writing")
+	}
+	writeAPI.Close()
+	assert.Equal(t, []string{"test v=0i", "test v=1i"}, service.Lines())
+}
+
+func TestWriteAPIImpl_MemoryBudgetBlockRetries(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	service.SetReplyError(&http.Error{StatusCode: 503, Code: "unavailable", Message: "service unavailable"})
+	// server recovers, blocked writes are freed by retries without new batches
+	time.AfterFunc(500*time.Millisecond, func() {
+		service.SetReplyError(nil)
+	})
+	opts := write.DefaultOptions().SetBatchSize(1).SetFlushInterval(100).SetRetryInterval(100).
+		SetMemoryBudget(100).SetOverflowPolicy(write.OverflowBlock)
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, opts)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 20; i++ {
+			writeAPI.WritePoint(write.NewPointWithMeasurement("test").AddField("v", i))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "blocked writes are not freed")
+	}
+	writeAPI.Close()
+	assert.Len(t, service.Lines(), 20)
+}
+
+func TestWriteAPIImpl_TryWrite(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return service.DecodeLines(body)
+	})
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(1).SetBufferCapacity(2))
+	// first batch blocks write proc, second one blocks buffer proc
+	writeAPI.WriteRecord("test v=1i")
+	<-started
+	writeAPI.WritePoint(write.NewPointWithMeasurement("test").AddField("v", 2))
+	require.Eventually(t, func() bool { return len(writeAPI.bufferCh) == 0 }, time.Second, time.Millisecond)
+
+	assert.True(t, writeAPI.TryWriteRecord("test v=3i"))
+	assert.True(t, writeAPI.TryWritePoint(write.NewPointWithMeasurement("test").AddField("v", 4)))
+	assert.False(t, writeAPI.TryWriteRecord("test v=5i"))
+	assert.False(t, writeAPI.TryWritePoint(write.NewPointWithMeasurement("test").AddField("v", 5)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := writeAPI.WritePointCtx(ctx, write.NewPointWithMeasurement("test").AddField("v", 5))
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	close(release)
+	require.NoError(t, writeAPI.WritePointCtx(context.Background(), write.NewPointWithMeasurement("test").AddField("v", 5)))
+	writeAPI.Close()
+	assert.Equal(t, []string{"test v=1i", "test v=2i", "test v=3i", "test v=4i", "test v=5i"}, service.Lines())
+}
+
+func TestWriteAPIImpl_TryWriteMemoryBudget(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	opts := write.DefaultOptions().SetFlushInterval(60_000).SetMemoryBudget(20).SetBufferCapacity(10)
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, opts)
+	assert.True(t, writeAPI.TryWriteRecord("test v=1i"))
+	assert.True(t, writeAPI.TryWritePoint(write.NewPointWithMeasurement("test").AddField("v", 2)))
+	assert.False(t, writeAPI.TryWriteRecord("test v=3i"))
+	assert.False(t, writeAPI.TryWritePoint(write.NewPointWithMeasurement("test").AddField("v", 3)))
+	// encoding error is reported, point is not retried
+	assert.True(t, writeAPI.TryWritePoint(write.NewPointWithMeasurement("test")))
+	assert.Error(t, writeAPI.WritePointCtx(context.Background(), write.NewPointWithMeasurement("test")))
+	writeAPI.Flush()
+	assert.True(t, writeAPI.TryWriteRecord("test v=3i"))
+	writeAPI.Close()
+	assert.Equal(t, []string{"test v=1i", "test v=2i", "test v=3i"}, service.Lines())
+}
+
+func TestWriteAPIImpl_Concurrency(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	all := make(chan struct{})
+	closeAll := sync.OnceFunc(func() { close(all) })
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		if inFlight == 3 {
+			closeAll()
+		}
+		mu.Unlock()
+		select {
+		case <-all:
+		case <-time.After(time.Second):
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return service.DecodeLines(body)
+	})
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(1).SetConcurrency(3))
+	require.Len(t, writeAPI.writers, 3)
+	require.Len(t, writeAPI.buffers, 1)
+	for i := range 6 {
+		writeAPI.WriteRecord(fmt.Sprintf("test v=%di", i))
+	}
+	writeAPI.Close()
+	assert.Equal(t, 3, maxInFlight)
+	assert.ElementsMatch(t, []string{"test v=0i", "test v=1i", "test v=2i", "test v=3i", "test v=4i", "test v=5i"}, service.Lines())
+}
+
+func TestWriteAPIImpl_OrderedWrites(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		<-time.After(time.Duration(rand.Intn(1000)) * time.Microsecond)
+		return service.DecodeLines(body)
+	})
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(2).SetConcurrency(3).SetOrderedWrites(true))
+	require.Len(t, writeAPI.writers, 3)
+	require.Len(t, writeAPI.buffers, 3)
+	series := []string{"cpu,host=a", "cpu,host=b", "mem,host=a", "mem\\ used,host=a"}
+	for i := range 20 {
+		for _, s := range series {
+			writeAPI.WriteRecord(fmt.Sprintf("%s v=%di", s, i))
+		}
+	}
+	writeAPI.Close()
+	lines := service.Lines()
+	require.Len(t, lines, 20*len(series))
+	next := make(map[string]int)
+	for _, l := range lines {
+		key, value, _ := strings.Cut(l, " v=")
+		assert.Equal(t, fmt.Sprintf("%di", next[key]), value, key)
+		next[key]++
+	}
+	assert.Equal(t, seriesIndex("mem\\ used,host=a v=1", 7), seriesIndex("mem\\ used,host=a v=2 1", 7))
+}
+
 func TestGzipWithFlushing(t *testing.T) {
 	service := test.NewTestService(t, "http://localhost:8888")
 	log.Log.SetLogLevel(log.DebugLevel)
@@ -269,6 +541,32 @@ func TestFlushWithRetries(t *testing.T) {
 	assert.Equal(t, 2, len(service.Lines()))
 }
 
+func TestFlushWithPendingRetries(t *testing.T) {
+	service := test.NewTestService(t, "http://localhost:8888")
+	var mu sync.Mutex
+	calls := 0
+	service.SetRequestHandler(func(url string, body io.Reader) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// every other write fails, writes are slow to overlap with retries
+		<-time.After(5 * time.Millisecond)
+		calls++
+		if calls%2 == 1 {
+			return fmt.Errorf("spurious failure")
+		}
+		return service.DecodeLines(body)
+	})
+	// retry queue is retried in flush intervals, while it is flushed
+	writeAPI := NewWriteAPI("my-org", "my-bucket", service, write.DefaultOptions().SetBatchSize(1).SetFlushInterval(1).SetRetryInterval(1))
+	for i := 0; i < 20; i++ {
+		writeAPI.WriteRecord(fmt.Sprintf("test v=%di", i))
+		<-time.After(2 * time.Millisecond)
+		writeAPI.Flush()
+	}
+	writeAPI.Close()
+	assert.NotEmpty(t, service.Lines())
+}
+
 func TestWriteApiErrorHeaders(t *testing.T) {
 	calls := 0
 	var mu sync.Mutex
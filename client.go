@@ -16,6 +16,7 @@ import (
 
 	"github.com/influxdata/influxdb-client-go/v2/api"
 	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 	"github.com/influxdata/influxdb-client-go/v2/domain"
 	ilog "github.com/influxdata/influxdb-client-go/v2/internal/log"
 	"github.com/influxdata/influxdb-client-go/v2/log"
@@ -46,7 +47,7 @@ type Client interface {
 	Close()
 	// Options returns the options associated with client
 	Options() *Options
-	// ServerURL returns the url of the server url client talks to
+	// ServerURL returns the url of the server url client talks to, the active server in case of a client with failover
 	ServerURL() string
 	// HTTPService returns underlying HTTP service object used by client
 	HTTPService() http.Service
@@ -56,6 +57,16 @@ type Client interface {
 	// WriteAPIBlocking returns the synchronous, blocking, Write client.
 	// Ensures using a single WriteAPIBlocking instance for each org/bucket pair.
 	WriteAPIBlocking(org, bucket string) api.WriteAPIBlocking
+	// WriteAPIV1 returns the asynchronous, non-blocking, Write client for the /write endpoint of InfluxDB 1.x.
+	// Ensures using a single WriteAPIV1 instance for each target.
+	WriteAPIV1(target write.V1Target) api.WriteAPI
+	// WriteAPIBlockingV1 returns the synchronous, blocking, Write client for the /write endpoint of InfluxDB 1.x.
+	// Ensures using a single WriteAPIBlockingV1 instance for each target.
+	WriteAPIBlockingV1(target write.V1Target) api.WriteAPIBlocking
+	// RoutingWriteAPI returns a new asynchronous, non-blocking, Write client, which writes into multiple buckets.
+	// route selects bucket of points written without explicit destination, it can be nil.
+	// Returned client is closed by Close().
+	RoutingWriteAPI(route api.RouteFunc) api.RoutingWriteAPI
 	// QueryAPI returns Query client.
 	// Ensures using a single QueryAPI instance each org.
 	QueryAPI(org string) api.QueryAPI
@@ -73,6 +84,8 @@ type Client interface {
 	LabelsAPI() api.LabelsAPI
 	// TasksAPI returns Tasks API client
 	TasksAPI() api.TasksAPI
+	// TemplatesAPI returns Templates API client
+	TemplatesAPI() api.TemplatesAPI
 
 	APIClient() *domain.Client
 }
@@ -83,6 +96,7 @@ type clientImpl struct {
 	options       *Options
 	writeAPIs     map[string]api.WriteAPI
 	syncWriteAPIs map[string]api.WriteAPIBlocking
+	routingAPIs   []api.RoutingWriteAPI
 	lock          sync.Mutex
 	httpService   http.Service
 	apiClient     *domain.Client
@@ -93,6 +107,7 @@ type clientImpl struct {
 	bucketsAPI    api.BucketsAPI
 	labelsAPI     api.LabelsAPI
 	tasksAPI      api.TasksAPI
+	templatesAPI  api.TemplatesAPI
 }
 
 type clientDoer struct {
@@ -113,16 +128,62 @@ func NewClient(serverURL string, authToken string) Client {
 // authToken is an authentication token. It can be empty in case of connecting to newly installed InfluxDB server, which has not been set up yet.
 // In such case, calling Setup() will set authentication token
 func NewClientWithOptions(serverURL string, authToken string, options *Options) Client {
-	normServerURL := serverURL
-	if !strings.HasSuffix(normServerURL, "/") {
-		// For subsequent path parts concatenation, url has to end with '/'
-		normServerURL = serverURL + "/"
+	authorization := ""
+	if len(authToken) > 0 {
+		authorization = "Token " + authToken
+	}
+	service := http.NewService(normalizeServerURL(serverURL), authorization, options.httpOptions)
+	authStr := ""
+	if len(authToken) > 0 {
+		authStr = ", token '******'"
+	}
+	return newClient(serverURL, service, options, authStr)
+}
+
+// NewClientWithSession creates Client for connecting to given serverURL, which is authenticated by a session of the user with username and password.
+// The client signs in before the first request, signs in again when the session expires and signs out on Close.
+// Session authentication works with all APIs of the client, the authentication token is not used.
+func NewClientWithSession(serverURL string, username, password string, options *Options) Client {
+	service := http.NewSessionService(normalizeServerURL(serverURL), username, password, options.httpOptions)
+	return newClient(serverURL, service, options, ", user '"+username+"'")
+}
+
+// NewClientWithFailover creates Client for connecting to one of servers with serverURLs, with provided authentication token
+// and configured with custom Options. The first URL is the primary server, the other URLs are used in order of preference
+// when the active server fails with a connection error or a 5xx status code, for all APIs including writes and queries.
+// The servers are checked by ping before switching to them. After a failover, the client checks in the health check interval
+// of options whether a preferred server is healthy again and switches back to it.
+// ServerURL returns URL of the active server, the failover callback of options is called when the client switches servers.
+// It panics if serverURLs is empty.
+func NewClientWithFailover(serverURLs []string, authToken string, options *Options) Client {
+	if len(serverURLs) == 0 {
+		panic("NewClientWithFailover called without server URLs")
 	}
 	authorization := ""
+	authStr := ""
 	if len(authToken) > 0 {
 		authorization = "Token " + authToken
+		authStr = ", token '******'"
+	}
+	normServerURLs := make([]string, len(serverURLs))
+	for i, u := range serverURLs {
+		normServerURLs[i] = normalizeServerURL(u)
 	}
-	service := http.NewService(normServerURL, authorization, options.httpOptions)
+	service := http.NewFailoverService(normServerURLs, authorization, options.httpOptions)
+	return newClient(strings.Join(serverURLs, ", "), service, options, authStr)
+}
+
+// normalizeServerURL returns serverURL ending with '/'
+func normalizeServerURL(serverURL string) string {
+	if !strings.HasSuffix(serverURL, "/") {
+		// For subsequent path parts concatenation, url has to end with '/'
+		return serverURL + "/"
+	}
+	return serverURL
+}
+
+// newClient creates Client using service, authStr describes authentication in the log
+func newClient(serverURL string, service http.Service, options *Options, authStr string) Client {
 	doer := &clientDoer{service}
 
 	apiClient, _ := domain.NewClient(service.ServerURL(), doer)
@@ -139,11 +200,7 @@ func NewClientWithOptions(serverURL string, authToken string, options *Options)
 		log.Log.SetLogLevel(options.LogLevel())
 	}
 	if ilog.Level() >= log.InfoLevel {
-		tokenStr := ""
-		if len(authToken) > 0 {
-			tokenStr = ", token '******'"
-		}
-		ilog.Infof("Using URL '%s'%s", serverURL, tokenStr)
+		ilog.Infof("Using URL '%s'%s", serverURL, authStr)
 	}
 	if options.ApplicationName() == "" {
 		ilog.Warn("Application name is not set")
@@ -160,6 +217,10 @@ func (c *clientImpl) Options() *Options {
 }
 
 func (c *clientImpl) ServerURL() string {
+	if _, ok := c.httpService.(http.FailoverService); ok {
+		// URL of the active server
+		return c.httpService.ServerURL()
+	}
 	return c.serverURL
 }
 
@@ -220,6 +281,11 @@ func createKey(org, bucket string) string {
 	return org + "\t" + bucket
 }
 
+// createV1Key returns key of target, which differs from keys of org and bucket
+func createV1Key(target write.V1Target) string {
+	return "v1\n" + target.Database + "\t" + target.RetentionPolicy + "\t" + target.Username + "\t" + target.Password
+}
+
 func (c *clientImpl) WriteAPI(org, bucket string) api.WriteAPI {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -242,6 +308,36 @@ func (c *clientImpl) WriteAPIBlocking(org, bucket string) api.WriteAPIBlocking {
 	return c.syncWriteAPIs[key]
 }
 
+func (c *clientImpl) WriteAPIV1(target write.V1Target) api.WriteAPI {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := createV1Key(target)
+	if _, ok := c.writeAPIs[key]; !ok {
+		w := api.NewWriteAPIV1(target, c.httpService, c.options.writeOptions)
+		c.writeAPIs[key] = w
+	}
+	return c.writeAPIs[key]
+}
+
+func (c *clientImpl) WriteAPIBlockingV1(target write.V1Target) api.WriteAPIBlocking {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := createV1Key(target)
+	if _, ok := c.syncWriteAPIs[key]; !ok {
+		w := api.NewWriteAPIBlockingV1(target, c.httpService, c.options.writeOptions)
+		c.syncWriteAPIs[key] = w
+	}
+	return c.syncWriteAPIs[key]
+}
+
+func (c *clientImpl) RoutingWriteAPI(route api.RouteFunc) api.RoutingWriteAPI {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	w := api.NewRoutingWriteAPI(c.httpService, c.options.writeOptions, route)
+	c.routingAPIs = append(c.routingAPIs, w)
+	return w
+}
+
 func (c *clientImpl) Close() {
 	for key, w := range c.writeAPIs {
 		wa := w.(*api.WriteAPIImpl)
@@ -251,13 +347,22 @@ func (c *clientImpl) Close() {
 	for key := range c.syncWriteAPIs {
 		delete(c.syncWriteAPIs, key)
 	}
+	for _, w := range c.routingAPIs {
+		w.Close()
+	}
+	c.routingAPIs = nil
+	if s, ok := c.httpService.(http.SessionService); ok {
+		if err := s.SignOut(context.Background()); err != nil {
+			ilog.Errorf("Sign out failed: %s", err.Error())
+		}
+	}
 	if c.options.HTTPOptions().OwnHTTPClient() {
 		c.options.HTTPOptions().HTTPClient().CloseIdleConnections()
 	}
 }
 
 func (c *clientImpl) QueryAPI(org string) api.QueryAPI {
-	return api.NewQueryAPI(org, c.httpService)
+	return api.NewQueryAPIWithCompression(org, c.httpService, c.options.HTTPOptions().QueryCompression()...)
 }
 
 func (c *clientImpl) AuthorizationsAPI() api.AuthorizationsAPI {
@@ -322,3 +427,12 @@ func (c *clientImpl) TasksAPI() api.TasksAPI {
 	}
 	return c.tasksAPI
 }
+
+func (c *clientImpl) TemplatesAPI() api.TemplatesAPI {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.templatesAPI == nil {
+		c.templatesAPI = api.NewTemplatesAPI(c.apiClient)
+	}
+	return c.templatesAPI
+}
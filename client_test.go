@@ -8,15 +8,19 @@ import (
 	"context"
 	"fmt"
 	ilog "github.com/influxdata/influxdb-client-go/v2/log"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	ihttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 	"github.com/influxdata/influxdb-client-go/v2/domain"
 	http2 "github.com/influxdata/influxdb-client-go/v2/internal/http"
 	iwrite "github.com/influxdata/influxdb-client-go/v2/internal/write"
@@ -76,9 +80,22 @@ func TestWriteAPIManagement(t *testing.T) {
 			assert.Len(t, c.syncWriteAPIs, d.expectedCout)
 		})
 	}
+	target := write.V1Target{Database: "db", RetentionPolicy: "rp"}
+	w1 := c.WriteAPIV1(target)
+	assert.Same(t, w1, c.WriteAPIV1(target))
+	assert.NotSame(t, w1, c.WriteAPIV1(write.V1Target{Database: "db"}))
+	assert.Len(t, c.writeAPIs, 7)
+	wb1 := c.WriteAPIBlockingV1(target)
+	assert.Same(t, wb1, c.WriteAPIBlockingV1(target))
+	assert.Len(t, c.syncWriteAPIs, 6)
+	r1 := c.RoutingWriteAPI(nil)
+	r2 := c.RoutingWriteAPI(nil)
+	assert.NotSame(t, r1, r2)
+	assert.Len(t, c.routingAPIs, 2)
 	c.Close()
 	assert.Len(t, c.writeAPIs, 0)
 	assert.Len(t, c.syncWriteAPIs, 0)
+	assert.Len(t, c.routingAPIs, 0)
 }
 
 func TestUserAgentBase(t *testing.T) {
@@ -260,3 +277,148 @@ func TestHealthFail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, h)
 }
+
+func TestSessionClient(t *testing.T) {
+	var signIns, signOuts int
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/signin":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "my-user" || pass != "my-password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			signIns++
+			http.SetCookie(w, &http.Cookie{Name: "influxdb-oss-session", Value: fmt.Sprintf("s%d", signIns)})
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "/api/v2/signout":
+			signOuts++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		// the first session expires after the first request
+		if c, err := r.Cookie("influxdb-oss-session"); err != nil || (c.Value == "s1" && len(paths) > 0) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Empty(t, r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/api/v2/query" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte("#datatype,string,long,long\n#group,false,false,false\n#default,_result,,\n,result,table,_value\n,,0,1\n\n"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClientWithSession(server.URL, "my-user", "my-password", DefaultOptions())
+	require.NoError(t, c.WriteAPIBlocking("my-org", "my-bucket").WriteRecord(context.Background(), "a v=1i"))
+	res, err := c.QueryAPI("my-org").Query(context.Background(), "from(bucket:\"my-bucket\")")
+	require.NoError(t, err)
+	require.True(t, res.Next())
+	assert.EqualValues(t, 1, res.Record().Value())
+	require.NoError(t, res.Close())
+	assert.Equal(t, []string{"/api/v2/write", "/api/v2/query"}, paths)
+	assert.Equal(t, 2, signIns)
+
+	c.Close()
+	assert.Equal(t, 1, signOuts)
+
+	c = NewClientWithSession(server.URL, "my-user", "wrong", DefaultOptions())
+	err = c.WriteAPIBlocking("my-org", "my-bucket").WriteRecord(context.Background(), "a v=1i")
+	require.Error(t, err)
+	c.Close()
+	assert.Equal(t, 1, signOuts)
+}
+
+func TestCredentialsProviderClient(t *testing.T) {
+	var token atomic.Int32
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Token t%d", token.Load()) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		lines = append(lines, strings.TrimSpace(string(body)))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	provider := ihttp.CredentialsProviderFunc(func(context.Context) (string, time.Time, error) {
+		return fmt.Sprintf("Token t%d", token.Load()), time.Time{}, nil
+	})
+	c := NewClientWithOptions(server.URL, "", DefaultOptions().SetBatchSize(1).SetCredentialsProvider(provider))
+	defer c.Close()
+	writeAPI := c.WriteAPI("my-org", "my-bucket")
+	errCh := writeAPI.Errors()
+	go func() {
+		for err := range errCh {
+			assert.NoError(t, err)
+		}
+	}()
+	writeAPI.WriteRecord("a v=1i")
+	writeAPI.Flush()
+	// the token rotates, the client keeps writing
+	token.Add(1)
+	writeAPI.WriteRecord("a v=2i")
+	writeAPI.Flush()
+	assert.Equal(t, []string{"a v=1i", "a v=2i"}, lines)
+}
+
+func TestFailoverClient(t *testing.T) {
+	var primaryDown atomic.Bool
+	var paths []string
+	var mu sync.Mutex
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if name == "primary" && primaryDown.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			mu.Lock()
+			paths = append(paths, name+" "+r.URL.Path)
+			mu.Unlock()
+			if r.URL.Path == "/api/v2/query" {
+				w.Header().Set("Content-Type", "text/csv")
+				w.Write([]byte("#datatype,string,long,long\n#group,false,false,false\n#default,_result,,\n,result,table,_value\n,,0,1\n\n"))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	primary := httptest.NewServer(handler("primary"))
+	defer primary.Close()
+	secondary := httptest.NewServer(handler("secondary"))
+	defer secondary.Close()
+
+	var switched []string
+	c := NewClientWithFailover([]string{primary.URL, secondary.URL}, "my-token",
+		DefaultOptions().SetFailoverCallback(func(from, to string) {
+			switched = append(switched, to)
+		}))
+	defer c.Close()
+	assert.Equal(t, primary.URL+"/", c.ServerURL())
+	writeAPI := c.WriteAPIBlocking("my-org", "my-bucket")
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "a v=1i"))
+
+	primaryDown.Store(true)
+	require.NoError(t, writeAPI.WriteRecord(context.Background(), "a v=2i"))
+	res, err := c.QueryAPI("my-org").Query(context.Background(), "from(bucket:\"my-bucket\")")
+	require.NoError(t, err)
+	require.True(t, res.Next())
+	require.NoError(t, res.Close())
+	ok, err := c.Ping(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, secondary.URL+"/", c.ServerURL())
+	assert.Equal(t, []string{secondary.URL + "/"}, switched)
+	assert.Equal(t, []string{"primary /api/v2/write", "secondary /ping", "secondary /api/v2/write",
+		"secondary /api/v2/query", "secondary /ping"}, paths)
+
+	assert.PanicsWithValue(t, "NewClientWithFailover called without server URLs", func() {
+		NewClientWithFailover(nil, "my-token", DefaultOptions())
+	})
+}
@@ -0,0 +1,337 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package influxdb2
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds connection settings and options of a client, read from environment variables or an influx CLI config file
+type Config struct {
+	// ServerURL is the InfluxDB server base URL
+	ServerURL string
+	// Token is the authentication token
+	Token string
+	// Org is the default organization for WriteAPI and QueryAPI, empty if not set
+	Org string
+	// Bucket is the default bucket for WriteAPI, empty if not set
+	Bucket string
+	// Options of the client
+	Options *Options
+}
+
+// configSettings are names of settings in config files, environment variables have the upper-cased name prefixed with INFLUX_,
+// except url, which is read from INFLUX_HOST
+var configSettings = []string{"url", "token", "org", "bucket", "batch_size", "flush_interval", "gzip", "precision",
+	"tls_ca", "tls_cert", "tls_key", "skip_verify", "http_timeout", "log_level", "default_tags"}
+
+// envName returns name of environment variable of setting
+func envName(setting string) string {
+	if setting == "url" {
+		return "INFLUX_HOST"
+	}
+	return "INFLUX_" + strings.ToUpper(setting)
+}
+
+// ConfigFromEnv reads Config from environment variables.
+// INFLUX_HOST is the server URL, INFLUX_TOKEN the token, INFLUX_ORG and INFLUX_BUCKET the default org and bucket.
+// Options are set by INFLUX_BATCH_SIZE, INFLUX_FLUSH_INTERVAL (ms), INFLUX_GZIP (bool), INFLUX_PRECISION (ns, us, ms or s),
+// INFLUX_TLS_CA, INFLUX_TLS_CERT and INFLUX_TLS_KEY (paths of PEM files), INFLUX_SKIP_VERIFY (bool), INFLUX_HTTP_TIMEOUT (s),
+// INFLUX_LOG_LEVEL (0-3) and INFLUX_DEFAULT_TAGS (comma separated key=value pairs). Unset options have default values.
+func ConfigFromEnv() (*Config, error) {
+	settings := make(map[string]string)
+	for _, name := range configSettings {
+		if value, ok := os.LookupEnv(envName(name)); ok {
+			settings[name] = value
+		}
+	}
+	if settings["url"] == "" {
+		return nil, errors.New("INFLUX_HOST is not set")
+	}
+	cb := &configBuilder{cfg: &Config{Options: DefaultOptions()}}
+	for name, value := range settings {
+		if err := cb.set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", envName(name), err)
+		}
+	}
+	return cb.build()
+}
+
+// ConfigFromFile reads Config from profile of a config file of influx CLI.
+// Empty path is the path in INFLUX_CONFIGS_PATH, or ~/.influxdbv2/configs.
+// Empty profileName is the profile in INFLUX_ACTIVE_CONFIG, the profile marked active, or the default profile.
+// A profile is a TOML table with url, token and org, as written by influx CLI, and optionally bucket and options named like
+// environment variables of ConfigFromEnv in lower case without the INFLUX_ prefix, e.g. batch_size.
+// Default tags can also be set by a sub-table named tags, e.g. [default.tags].
+func ConfigFromFile(path, profileName string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv("INFLUX_CONFIGS_PATH")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot find config file: %w", err)
+		}
+		path = filepath.Join(home, ".influxdbv2", "configs")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+	defer f.Close()
+	profiles, err := parseConfigs(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if profileName == "" {
+		profileName = os.Getenv("INFLUX_ACTIVE_CONFIG")
+	}
+	if profileName == "" {
+		profileName = "default"
+		for name, settings := range profiles {
+			if settings["active"] == "true" {
+				profileName = name
+				break
+			}
+		}
+	}
+	profile, ok := profiles[profileName]
+	if !ok {
+		return nil, fmt.Errorf("%s: profile %s not found", path, profileName)
+	}
+	if profile["url"] == "" {
+		return nil, fmt.Errorf("%s: profile %s has no url", path, profileName)
+	}
+	cb := &configBuilder{cfg: &Config{Options: DefaultOptions()}}
+	for _, name := range configSettings {
+		if value, ok := profile[name]; ok {
+			if err := cb.set(name, value); err != nil {
+				return nil, fmt.Errorf("%s: invalid %s of profile %s: %w", path, name, profileName, err)
+			}
+		}
+	}
+	for key, value := range profiles[profileName+".tags"] {
+		cb.cfg.Options.AddDefaultTag(key, value)
+	}
+	return cb.build()
+}
+
+// NewClientFromEnv creates Client configured by environment variables, as described by ConfigFromEnv.
+// The returned Config holds the default org and bucket.
+func NewClientFromEnv() (Client, *Config, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg.NewClient(), cfg, nil
+}
+
+// NewClientFromConfig creates Client configured by profile of an influx CLI config file, as described by ConfigFromFile.
+// The returned Config holds the default org and bucket.
+func NewClientFromConfig(path, profileName string) (Client, *Config, error) {
+	cfg, err := ConfigFromFile(path, profileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg.NewClient(), cfg, nil
+}
+
+// NewClient creates Client with server URL, token and options of c
+func (c *Config) NewClient() Client {
+	return NewClientWithOptions(c.ServerURL, c.Token, c.Options)
+}
+
+// configBuilder sets settings of Config
+type configBuilder struct {
+	cfg        *Config
+	tlsCA      string
+	tlsCert    string
+	tlsKey     string
+	skipVerify bool
+}
+
+// set applies setting name with value
+func (b *configBuilder) set(name, value string) error {
+	var err error
+	switch name {
+	case "url":
+		b.cfg.ServerURL = value
+	case "token":
+		b.cfg.Token = value
+	case "org":
+		b.cfg.Org = value
+	case "bucket":
+		b.cfg.Bucket = value
+	case "batch_size":
+		var n uint64
+		if n, err = strconv.ParseUint(value, 10, 32); err == nil && n > 0 {
+			b.cfg.Options.SetBatchSize(uint(n))
+		} else if err == nil {
+			err = errors.New("batch size must be positive")
+		}
+	case "flush_interval":
+		var n uint64
+		if n, err = strconv.ParseUint(value, 10, 32); err == nil {
+			b.cfg.Options.SetFlushInterval(uint(n))
+		}
+	case "gzip":
+		var v bool
+		if v, err = strconv.ParseBool(value); err == nil {
+			b.cfg.Options.SetUseGZip(v)
+		}
+	case "precision":
+		var p time.Duration
+		if p, err = parsePrecision(value); err == nil {
+			b.cfg.Options.SetPrecision(p)
+		}
+	case "tls_ca":
+		b.tlsCA = value
+	case "tls_cert":
+		b.tlsCert = value
+	case "tls_key":
+		b.tlsKey = value
+	case "skip_verify":
+		b.skipVerify, err = strconv.ParseBool(value)
+	case "http_timeout":
+		var n uint64
+		if n, err = strconv.ParseUint(value, 10, 32); err == nil {
+			b.cfg.Options.SetHTTPRequestTimeout(uint(n))
+		}
+	case "log_level":
+		var n uint64
+		if n, err = strconv.ParseUint(value, 10, 32); err == nil {
+			b.cfg.Options.SetLogLevel(uint(n))
+		}
+	case "default_tags":
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("tag %q is not key=value", pair)
+			}
+			b.cfg.Options.AddDefaultTag(strings.TrimSpace(key), strings.TrimSpace(val))
+		}
+	}
+	return err
+}
+
+// build sets TLS configuration from the TLS settings and returns the Config
+func (b *configBuilder) build() (*Config, error) {
+	if b.tlsCA == "" && b.tlsCert == "" && b.tlsKey == "" && !b.skipVerify {
+		return b.cfg, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: b.skipVerify}
+	if b.tlsCA != "" {
+		pem, err := os.ReadFile(b.tlsCA)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", b.tlsCA)
+		}
+	}
+	if b.tlsCert != "" || b.tlsKey != "" {
+		cert, err := tls.LoadX509KeyPair(b.tlsCert, b.tlsKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	b.cfg.Options.SetTLSConfig(tlsConfig)
+	return b.cfg, nil
+}
+
+// parsePrecision parses precision name of write API
+func parsePrecision(s string) (time.Duration, error) {
+	switch s {
+	case "ns":
+		return time.Nanosecond, nil
+	case "us":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	}
+	return 0, fmt.Errorf("unknown precision %q", s)
+}
+
+// parseConfigs parses tables of a TOML config file of influx CLI into values by key by table name.
+// Only tables and key/value pairs with string, boolean and number values are supported.
+func parseConfigs(r io.Reader) (map[string]map[string]string, error) {
+	profiles := make(map[string]map[string]string)
+	var table map[string]string
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: invalid table header", lineNum)
+			}
+			name := strings.Trim(strings.TrimSpace(line[1:end]), `"'`)
+			table = make(map[string]string)
+			profiles[name] = table
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNum)
+		}
+		if table == nil {
+			return nil, fmt.Errorf("line %d: key outside of a table", lineNum)
+		}
+		v, err := parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		table[strings.Trim(strings.TrimSpace(key), `"'`)] = v
+	}
+	return profiles, scanner.Err()
+}
+
+// parseConfigValue parses TOML value, a quoted string or a bare value, followed by an optional comment
+func parseConfigValue(s string) (string, error) {
+	if s == "" {
+		return "", errors.New("missing value")
+	}
+	switch s[0] {
+	case '"':
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				return strconv.Unquote(s[:i+1])
+			}
+		}
+		return "", errors.New("unterminated string")
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		return s[1 : end+1], nil
+	}
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s), nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package influxdb2_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes self-signed certificate and its key into dir and returns their paths
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "influxdb"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certPath, keyPath
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	certPath, keyPath := writeCertificate(t, t.TempDir())
+	t.Setenv("INFLUX_HOST", "https://localhost:8086")
+	t.Setenv("INFLUX_TOKEN", "my-token")
+	t.Setenv("INFLUX_ORG", "my-org")
+	t.Setenv("INFLUX_BUCKET", "my-bucket")
+	t.Setenv("INFLUX_BATCH_SIZE", "100")
+	t.Setenv("INFLUX_FLUSH_INTERVAL", "500")
+	t.Setenv("INFLUX_GZIP", "true")
+	t.Setenv("INFLUX_PRECISION", "ms")
+	t.Setenv("INFLUX_TLS_CA", certPath)
+	t.Setenv("INFLUX_TLS_CERT", certPath)
+	t.Setenv("INFLUX_TLS_KEY", keyPath)
+	t.Setenv("INFLUX_HTTP_TIMEOUT", "30")
+	t.Setenv("INFLUX_LOG_LEVEL", "2")
+	t.Setenv("INFLUX_DEFAULT_TAGS", "dc=eu, host = h1,")
+
+	cfg, err := influxdb2.ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "https://localhost:8086", cfg.ServerURL)
+	assert.Equal(t, "my-token", cfg.Token)
+	assert.Equal(t, "my-org", cfg.Org)
+	assert.Equal(t, "my-bucket", cfg.Bucket)
+	opts := cfg.Options
+	assert.EqualValues(t, 100, opts.BatchSize())
+	assert.EqualValues(t, 500, opts.FlushInterval())
+	assert.True(t, opts.UseGZip())
+	assert.Equal(t, time.Millisecond, opts.Precision())
+	assert.EqualValues(t, 30, opts.HTTPRequestTimeout())
+	assert.EqualValues(t, 2, opts.LogLevel())
+	assert.Equal(t, map[string]string{"dc": "eu", "host": "h1"}, opts.WriteOptions().DefaultTags())
+	require.NotNil(t, opts.TLSConfig())
+	assert.NotNil(t, opts.TLSConfig().RootCAs)
+	assert.Len(t, opts.TLSConfig().Certificates, 1)
+	assert.False(t, opts.TLSConfig().InsecureSkipVerify)
+
+	for env, value := range map[string]string{
+		"INFLUX_BATCH_SIZE":   "0",
+		"INFLUX_GZIP":         "maybe",
+		"INFLUX_PRECISION":    "m",
+		"INFLUX_DEFAULT_TAGS": "dc",
+		"INFLUX_TLS_CA":       keyPath,
+		"INFLUX_TLS_KEY":      filepath.Join(t.TempDir(), "missing.pem"),
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			_, err := influxdb2.ConfigFromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv("INFLUX_HOST", "")
+	_, _, err := influxdb2.NewClientFromEnv()
+	assert.EqualError(t, err, "INFLUX_HOST is not set")
+
+	t.Setenv("INFLUX_HOST", "http://localhost:8086")
+	t.Setenv("INFLUX_ORG", "my-org")
+	t.Setenv("INFLUX_SKIP_VERIFY", "true")
+	client, cfg, err := influxdb2.NewClientFromEnv()
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, "http://localhost:8086", client.ServerURL())
+	assert.Equal(t, "my-org", cfg.Org)
+	assert.Empty(t, cfg.Bucket)
+	assert.True(t, client.Options().TLSConfig().InsecureSkipVerify)
+}
+
+func TestConfigFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "configs")
+	require.NoError(t, os.WriteFile(path, []byte(`[default]
+  url = "http://localhost:8086"
+  token = "default-token"
+  org = "my-org"
+  active = false
+
+# production cluster
+[prod]
+  url = "https://influx.example.com" # comment
+  token = 'prod\token'
+  org = "prod-org"
+  active = true
+  bucket = "metrics"
+  batch_size = 1000
+  gzip = true
+  precision = "s"
+
+[prod.tags]
+  region = "eu\"1"
+`), 0o600))
+
+	cfg, err := influxdb2.ConfigFromFile(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://influx.example.com", cfg.ServerURL)
+	assert.Equal(t, `prod\token`, cfg.Token)
+	assert.Equal(t, "prod-org", cfg.Org)
+	assert.Equal(t, "metrics", cfg.Bucket)
+	assert.EqualValues(t, 1000, cfg.Options.BatchSize())
+	assert.True(t, cfg.Options.UseGZip())
+	assert.Equal(t, time.Second, cfg.Options.Precision())
+	assert.Equal(t, map[string]string{"region": `eu"1`}, cfg.Options.WriteOptions().DefaultTags())
+
+	cfg, err = influxdb2.ConfigFromFile(path, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8086", cfg.ServerURL)
+	assert.Equal(t, "default-token", cfg.Token)
+	assert.Empty(t, cfg.Bucket)
+	assert.EqualValues(t, 5_000, cfg.Options.BatchSize())
+
+	t.Setenv("INFLUX_CONFIGS_PATH", path)
+	t.Setenv("INFLUX_ACTIVE_CONFIG", "default")
+	client, cfg, err := influxdb2.NewClientFromConfig("", "")
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, "http://localhost:8086", client.ServerURL())
+	assert.Equal(t, "my-org", cfg.Org)
+
+	_, err = influxdb2.ConfigFromFile(path, "missing")
+	assert.EqualError(t, err, path+": profile missing not found")
+	_, err = influxdb2.ConfigFromFile(filepath.Join(dir, "missing"), "")
+	assert.Error(t, err)
+
+	for name, content := range map[string]string{
+		"header":   "[default\nurl = \"http://localhost:8086\"",
+		"no value": "[default]\nurl",
+		"no table": "url = \"http://localhost:8086\"",
+		"string":   "[default]\nurl = \"http://localhost:8086",
+		"no url":   "[default]\norg = \"my-org\"",
+		"option":   "[default]\nurl = \"http://localhost:8086\"\nflush_interval = -1",
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+			_, err := influxdb2.ConfigFromFile(p, "default")
+			assert.Error(t, err)
+		})
+	}
+}
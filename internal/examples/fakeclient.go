@@ -109,3 +109,8 @@ func (c *FakeClient) LabelsAPI() api.LabelsAPI {
 func (c *FakeClient) TasksAPI() api.TasksAPI {
 	return nil
 }
+
+// TemplatesAPI returns nil
+func (c *FakeClient) TemplatesAPI() api.TemplatesAPI {
+	return nil
+}
@@ -0,0 +1,338 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+// Package schedule provides computation of task schedules defined by every or cron task options
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes times at which a task is scheduled
+type Schedule interface {
+	// Next returns the first scheduled time strictly after t, or zero time if there is none.
+	Next(t time.Time) time.Time
+}
+
+// Times returns all scheduled times within the closed interval [start, stop]
+func Times(s Schedule, start, stop time.Time) []time.Time {
+	var times []time.Time
+	for t := s.Next(start.Add(-time.Nanosecond)); !t.IsZero() && !t.After(stop); t = s.Next(t) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// everySchedule is a schedule with a fixed interval, aligned to the Unix epoch
+type everySchedule struct {
+	every time.Duration
+}
+
+// Every returns schedule, which runs each interval d, aligned to the Unix epoch
+func Every(d time.Duration) Schedule {
+	return &everySchedule{every: d}
+}
+
+func (s *everySchedule) Next(t time.Time) time.Time {
+	if s.every <= 0 {
+		return time.Time{}
+	}
+	n := t.UnixNano()
+	e := int64(s.every)
+	rem := n % e
+	if rem < 0 {
+		rem += e
+	}
+	return time.Unix(0, n-rem+e).UTC()
+}
+
+// fluxDurationUnits maps units of a Flux duration literal to their fixed length
+var fluxDurationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+// durationPart is a magnitude with unit of a Flux duration literal
+type durationPart struct {
+	magnitude int64
+	unit      string
+}
+
+// parseDurationParts splits a Flux duration literal into its parts, checking the units are valid
+func parseDurationParts(s string) ([]durationPart, error) {
+	if s == "" {
+		return nil, fmt.Errorf("invalid duration ''")
+	}
+	var parts []durationPart
+	for rest := s; rest != ""; {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("invalid duration '%s'", s)
+		}
+		n, err := strconv.ParseInt(rest[:i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration '%s': %w", s, err)
+		}
+		rest = rest[i:]
+		j := 0
+		for j < len(rest) && (rest[j] < '0' || rest[j] > '9') {
+			j++
+		}
+		unit := rest[:j]
+		rest = rest[j:]
+		if _, ok := fluxDurationUnits[unit]; !ok && unit != "mo" && unit != "y" {
+			return nil, fmt.Errorf("invalid duration '%s': unknown unit '%s'", s, unit)
+		}
+		parts = append(parts, durationPart{magnitude: n, unit: unit})
+	}
+	return parts, nil
+}
+
+// ValidateDuration checks s is a valid Flux duration literal, such as 1h30m or 1mo.
+// A leading minus sign is accepted.
+func ValidateDuration(s string) error {
+	_, err := parseDurationParts(strings.TrimPrefix(s, "-"))
+	return err
+}
+
+// ParseDuration parses a Flux duration literal, such as 1h30m or 2d.
+// Calendar units (mo, y), which don't have a fixed length, are not supported.
+func ParseDuration(s string) (time.Duration, error) {
+	parts, err := parseDurationParts(s)
+	if err != nil {
+		return 0, err
+	}
+	var d time.Duration
+	for _, p := range parts {
+		u, ok := fluxDurationUnits[p.unit]
+		if !ok {
+			return 0, fmt.Errorf("duration '%s' uses calendar unit '%s', which is not supported", s, p.unit)
+		}
+		d += time.Duration(p.magnitude) * u
+	}
+	return d, nil
+}
+
+// cronSchedule is a schedule defined by a cron expression. Each field is a bit set of allowed values.
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// domStar and dowStar are true if day of month, resp. day of week, are not restricted
+	domStar, dowStar bool
+}
+
+// cronField describes a field of a cron expression
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondField = cronField{name: "second", min: 0, max: 59}
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// day of week 7 is an alternative for Sunday
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronDescriptors maps predefined cron schedules to their expressions
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron parses a cron expression with 5 fields (minute, hour, day of month, month, day of week),
+// or 6 fields with leading seconds. Predefined schedules, such as @daily or @every 1h, are supported as well.
+// Times are computed in UTC.
+func ParseCron(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %w", expr, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid cron expression '%s': interval must be positive", expr)
+		}
+		return Every(d), nil
+	}
+	if d, ok := cronDescriptors[spec]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron expression '%s': expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+	s := &cronSchedule{}
+	var err error
+	for i, f := range []struct {
+		field cronField
+		set   *uint64
+	}{
+		{secondField, &s.second},
+		{minuteField, &s.minute},
+		{hourField, &s.hour},
+		{domField, &s.dom},
+		{monthField, &s.month},
+		{dowField, &s.dow},
+	} {
+		if *f.set, err = parseCronField(fields[i], f.field); err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %w", expr, err)
+		}
+	}
+	// Sunday can be expressed also as 7
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = isStar(fields[3])
+	s.dowStar = isStar(fields[5])
+	return s, nil
+}
+
+// isStar returns true if the cron field doesn't restrict values
+func isStar(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseCronField parses comma separated list of values, ranges and steps into a bit set
+func parseCronField(expr string, field cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+		step := uint(1)
+		if hasStep {
+			n, err := strconv.ParseUint(stepExpr, 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid %s step '%s'", field.name, stepExpr)
+			}
+			step = uint(n)
+		}
+		var low, high uint
+		switch {
+		case isStar(rangeExpr):
+			low, high = field.min, field.max
+		case strings.Contains(rangeExpr, "-"):
+			lowExpr, highExpr, _ := strings.Cut(rangeExpr, "-")
+			var err error
+			if low, err = parseCronValue(lowExpr, field); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(highExpr, field); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid %s range '%s'", field.name, rangeExpr)
+			}
+		default:
+			var err error
+			if low, err = parseCronValue(rangeExpr, field); err != nil {
+				return 0, err
+			}
+			high = low
+			if hasStep {
+				high = field.max
+			}
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// parseCronValue parses a single numeric or named value of the cron field
+func parseCronValue(expr string, field cronField) (uint, error) {
+	if v, ok := field.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(expr, 10, 8)
+	if err != nil || uint(n) < field.min || uint(n) > field.max {
+		return 0, fmt.Errorf("invalid %s '%s'", field.name, expr)
+	}
+	return uint(n), nil
+}
+
+// maxCronYears limits the search for the next time of a schedule, which never matches (e.g. 30th February)
+const maxCronYears = 5
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	// round up to the next whole second
+	t = t.UTC().Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + maxCronYears
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = t.Truncate(time.Hour).Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Truncate(time.Minute).Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
+
+// dayMatches returns true if day of t matches the schedule.
+// When both day of month and day of week are restricted, a day matching either of them is accepted.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
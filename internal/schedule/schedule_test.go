@@ -0,0 +1,122 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseTime(t *testing.T, s string) time.Time {
+	tm, err := time.Parse(time.RFC3339, s)
+	require.NoError(t, err)
+	return tm
+}
+
+func TestParseDuration(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		out time.Duration
+		err string
+	}{
+		{"1h", time.Hour, ""},
+		{"1h30m", 90 * time.Minute, ""},
+		{"2d", 48 * time.Hour, ""},
+		{"1w", 7 * 24 * time.Hour, ""},
+		{"10s500ms", 10*time.Second + 500*time.Millisecond, ""},
+		{"5us", 5 * time.Microsecond, ""},
+		{"", 0, "invalid duration ''"},
+		{"h", 0, "invalid duration 'h'"},
+		{"10", 0, "invalid duration '10': unknown unit ''"},
+		{"10x", 0, "invalid duration '10x': unknown unit 'x'"},
+		{"1mo", 0, "duration '1mo' uses calendar unit 'mo', which is not supported"},
+	} {
+		d, err := ParseDuration(c.in)
+		if c.err != "" {
+			assert.EqualError(t, err, c.err, c.in)
+			continue
+		}
+		require.NoError(t, err, c.in)
+		assert.Equal(t, c.out, d, c.in)
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	for _, d := range []string{"1h", "1mo", "1y2mo3d", "-5m", "10us"} {
+		assert.NoError(t, ValidateDuration(d), d)
+	}
+	for _, d := range []string{"", "1", "h1", "1x", "--1h", "1h-"} {
+		assert.Error(t, ValidateDuration(d), d)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s := Every(time.Hour)
+	assert.Equal(t, mustParseTime(t, "2022-01-01T11:00:00Z"), s.Next(mustParseTime(t, "2022-01-01T10:30:00Z")))
+	assert.Equal(t, mustParseTime(t, "2022-01-01T12:00:00Z"), s.Next(mustParseTime(t, "2022-01-01T11:00:00Z")))
+
+	// aligned to the Unix epoch, which was Thursday
+	s = Every(7 * 24 * time.Hour)
+	assert.Equal(t, mustParseTime(t, "2022-01-06T00:00:00Z"), s.Next(mustParseTime(t, "2022-01-01T00:00:00Z")))
+
+	times := Times(Every(15*time.Minute), mustParseTime(t, "2022-01-01T10:00:00Z"), mustParseTime(t, "2022-01-01T11:00:00Z"))
+	require.Len(t, times, 5)
+	assert.Equal(t, mustParseTime(t, "2022-01-01T10:00:00Z"), times[0])
+	assert.Equal(t, mustParseTime(t, "2022-01-01T11:00:00Z"), times[4])
+
+	assert.True(t, Every(0).Next(time.Now()).IsZero())
+}
+
+func TestParseCron(t *testing.T) {
+	for _, c := range []struct {
+		expr  string
+		from  string
+		times []string
+	}{
+		{"0 * * * *", "2022-01-01T10:30:00Z", []string{"2022-01-01T11:00:00Z", "2022-01-01T12:00:00Z"}},
+		{"*/15 * * * *", "2022-01-01T10:50:00Z", []string{"2022-01-01T11:00:00Z", "2022-01-01T11:15:00Z"}},
+		{"30 2 * * *", "2022-01-01T10:00:00Z", []string{"2022-01-02T02:30:00Z", "2022-01-03T02:30:00Z"}},
+		{"0 0 1 */6 *", "2022-02-01T00:00:00Z", []string{"2022-07-01T00:00:00Z", "2023-01-01T00:00:00Z"}},
+		{"0 9 * * mon-fri", "2022-01-07T10:00:00Z", []string{"2022-01-10T09:00:00Z", "2022-01-11T09:00:00Z"}},
+		{"0 0 * * 7", "2022-01-01T00:00:00Z", []string{"2022-01-02T00:00:00Z", "2022-01-09T00:00:00Z"}},
+		// day of month or day of week
+		{"0 0 13 * fri", "2022-05-01T00:00:00Z", []string{"2022-05-06T00:00:00Z", "2022-05-13T00:00:00Z", "2022-05-20T00:00:00Z"}},
+		{"0 0 29 feb *", "2022-01-01T00:00:00Z", []string{"2024-02-29T00:00:00Z"}},
+		{"30 0 0 * * *", "2022-01-01T00:00:00Z", []string{"2022-01-01T00:00:30Z", "2022-01-02T00:00:30Z"}},
+		{"0,30 8-9 * * *", "2022-01-01T00:00:00Z", []string{"2022-01-01T08:00:00Z", "2022-01-01T08:30:00Z", "2022-01-01T09:00:00Z", "2022-01-01T09:30:00Z", "2022-01-02T08:00:00Z"}},
+		{"@daily", "2022-01-01T10:00:00Z", []string{"2022-01-02T00:00:00Z"}},
+		{"@every 1h", "2022-01-01T10:10:00Z", []string{"2022-01-01T11:00:00Z"}},
+	} {
+		s, err := ParseCron(c.expr)
+		require.NoError(t, err, c.expr)
+		tm := mustParseTime(t, c.from)
+		for _, expected := range c.times {
+			tm = s.Next(tm)
+			assert.Equal(t, mustParseTime(t, expected), tm, c.expr)
+		}
+	}
+
+	s, err := ParseCron("0 0 30 feb *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+
+	for expr, msg := range map[string]string{
+		"* * * *":         "invalid cron expression '* * * *': expected 5 or 6 fields, got 4",
+		"60 * * * *":      "invalid cron expression '60 * * * *': invalid minute '60'",
+		"* * 0 * *":       "invalid cron expression '* * 0 * *': invalid day of month '0'",
+		"* * * foo *":     "invalid cron expression '* * * foo *': invalid month 'foo'",
+		"*/0 * * * *":     "invalid cron expression '*/0 * * * *': invalid minute step '0'",
+		"10-5 * * * *":    "invalid cron expression '10-5 * * * *': invalid minute range '10-5'",
+		"@every 1mo":      "invalid cron expression '@every 1mo': duration '1mo' uses calendar unit 'mo', which is not supported",
+		"@every 0s":       "invalid cron expression '@every 0s': interval must be positive",
+		"* * * * * * * *": "invalid cron expression '* * * * * * * *': expected 5 or 6 fields, got 8",
+	} {
+		_, err := ParseCron(expr)
+		assert.EqualError(t, err, msg)
+	}
+}
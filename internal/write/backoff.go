@@ -0,0 +1,49 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"sync"
+	"time"
+)
+
+// backoff is retry state shared by services writing batches concurrently.
+// A retryable error of one service pauses writes of all services.
+type backoff struct {
+	mu       sync.Mutex
+	attempts uint
+	until    time.Time
+}
+
+// retryAttempts returns number of failed writes since the last successful write
+func (b *backoff) retryAttempts() uint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts
+}
+
+// fail counts a failed write and pauses writes until the given time
+func (b *backoff) fail(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts++
+	if until.After(b.until) {
+		b.until = until
+	}
+}
+
+// succeed resets retry attempts after a successful write
+func (b *backoff) succeed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts = 0
+}
+
+// paused returns true if writes are paused after a failed write
+func (b *backoff) paused() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.until)
+}
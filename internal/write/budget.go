@@ -0,0 +1,87 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"context"
+	"sync"
+)
+
+// budget limits bytes of lines held in memory
+type budget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+	// freed is closed when bytes are released
+	freed chan struct{}
+}
+
+func newBudget(limit int) *budget {
+	return &budget{limit: limit, freed: make(chan struct{})}
+}
+
+// tryAcquire reserves n bytes, it returns false if the budget would be exceeded
+func (b *budget) tryAcquire(n int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used > 0 && b.used+n > b.limit {
+		return false
+	}
+	b.used += n
+	return true
+}
+
+// acquire reserves n bytes, it waits until there are enough free bytes or ctx is done.
+// More bytes than the limit are reserved when nothing else is reserved.
+func (b *budget) acquire(ctx context.Context, n int) error {
+	for {
+		b.mu.Lock()
+		if b.used == 0 || b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		freed := b.freed
+		b.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve reserves n bytes regardless of the limit
+func (b *budget) reserve(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used += n
+}
+
+// release frees n bytes
+func (b *budget) release(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+	if b.used < 0 {
+		b.used = 0
+	}
+	close(b.freed)
+	b.freed = make(chan struct{})
+}
+
+// exceeded returns true if more bytes than the limit are reserved
+func (b *budget) exceeded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used > b.limit
+}
+
+// size returns reserved bytes
+func (b *budget) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
@@ -0,0 +1,63 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"sync"
+	"time"
+)
+
+// ackedBatch is ID of a written batch and the time until it is remembered
+type ackedBatch struct {
+	id      string
+	expires time.Time
+}
+
+// dedup remembers IDs of written batches for a time window, it is shared by services writing concurrently
+type dedup struct {
+	mu     sync.Mutex
+	window time.Duration
+	acked  map[string]time.Time
+	// order of acked batches by expiration
+	order []ackedBatch
+}
+
+func newDedup(window time.Duration) *dedup {
+	return &dedup{window: window, acked: make(map[string]time.Time)}
+}
+
+// acknowledged returns true if batch with id was written within the window
+func (d *dedup) acknowledged(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(time.Now())
+	_, ok := d.acked[id]
+	return ok
+}
+
+// acknowledge remembers batch with id as written
+func (d *dedup) acknowledge(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	d.expire(now)
+	expires := now.Add(d.window)
+	d.acked[id] = expires
+	d.order = append(d.order, ackedBatch{id: id, expires: expires})
+}
+
+// expire forgets batches written before the window, d.mu must be locked
+func (d *dedup) expire(now time.Time) {
+	i := 0
+	for ; i < len(d.order) && !now.Before(d.order[i].expires); i++ {
+		// the batch could be acknowledged again later
+		if d.acked[d.order[i].id].Equal(d.order[i].expires) {
+			delete(d.acked, d.order[i].id)
+		}
+	}
+	if i > 0 {
+		d.order = d.order[i:]
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	http2 "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+var (
+	// lineErrorRegexp matches a line error of a line protocol parsing error, e.g. "line 2: unable to parse 'cpu value': missing fields"
+	lineErrorRegexp = regexp.MustCompile(`^\s*line (\d+): (.+)$`)
+	// unableToParseRegexp matches an error with the rejected record, e.g. "unable to parse 'cpu value': invalid field format"
+	unableToParseRegexp = regexp.MustCompile(`unable to parse '(.*)': (.+)$`)
+)
+
+// lineErrorsBody is a JSON error body with errors of individual lines, as sent by InfluxDB 3
+type lineErrorsBody struct {
+	Data []struct {
+		ErrorMessage string `json:"error_message"`
+		LineNumber   int    `json:"line_number"`
+	} `json:"data"`
+	// Line is the first line with malformed data, as sent by InfluxDB 2
+	Line int `json:"line"`
+}
+
+// NewPartialWriteError creates PartialWriteError from error of writing batch, when the server reported rejected lines.
+// Rejected lines are mapped to records and points of batch, lines which are not found in batch are not reported.
+// It returns nil if perror doesn't identify any rejected line of batch.
+func NewPartialWriteError(batch *Batch, perror *http2.Error) *write.PartialWriteError {
+	if perror.StatusCode == 0 {
+		return nil
+	}
+	lines := strings.Split(batch.Batch, "\n")
+	var rejected []write.RejectedLine
+	add := func(line int, reason string) {
+		if line < 1 || line > len(lines) {
+			return
+		}
+		for _, r := range rejected {
+			if r.Line == line {
+				return
+			}
+		}
+		r := write.RejectedLine{Line: line, Record: strings.TrimSuffix(lines[line-1], "\r"), Reason: reason}
+		if line <= len(batch.Points) {
+			r.Point = batch.Points[line-1]
+		}
+		rejected = append(rejected, r)
+	}
+	var body lineErrorsBody
+	if len(perror.Body) > 0 && json.Unmarshal(perror.Body, &body) == nil {
+		for _, d := range body.Data {
+			add(d.LineNumber, d.ErrorMessage)
+		}
+	}
+	for _, m := range strings.Split(perror.Message, "\n") {
+		if match := lineErrorRegexp.FindStringSubmatch(m); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			add(line, match[2])
+		} else if match := unableToParseRegexp.FindStringSubmatch(m); match != nil {
+			add(findLine(lines, match[1]), m)
+		}
+	}
+	if len(rejected) == 0 && body.Line > 0 {
+		add(body.Line, perror.Message)
+	}
+	if len(rejected) == 0 {
+		return nil
+	}
+	return &write.PartialWriteError{Rejected: rejected, Err: perror}
+}
+
+// rejectedBatch creates a batch of lines of batch rejected in partial write
+func rejectedBatch(batch *Batch, pwerr *write.PartialWriteError) *Batch {
+	var sb strings.Builder
+	var points []*write.Point
+	for i, r := range pwerr.Rejected {
+		sb.WriteString(r.Record)
+		sb.WriteByte('\n')
+		if r.Point != nil {
+			if points == nil {
+				points = make([]*write.Point, len(pwerr.Rejected))
+			}
+			points[i] = r.Point
+		}
+	}
+	return &Batch{Batch: sb.String(), Points: points, RetryAttempts: batch.RetryAttempts, Expires: batch.Expires}
+}
+
+// findLine returns number of the line equal to record, 0 if there is none
+func findLine(lines []string, record string) int {
+	for i, l := range lines {
+		if strings.TrimSuffix(l, "\r") == record {
+			return i + 1
+		}
+	}
+	return 0
+}
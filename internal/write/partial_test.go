@@ -0,0 +1,99 @@
+// Copyright 2020-2021 InfluxData, Inc. All rights reserved.
+// Use of this source code is governed by MIT
+// license that can be found in the LICENSE file.
+
+package write
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPartialWriteError(t *testing.T) {
+	batch := "cpu v=1\ncpu value\nmem v=x\nmem v=2\n"
+	tests := []struct {
+		name     string
+		err      *http.Error
+		rejected []write.RejectedLine
+	}{
+		{
+			name: "InfluxDB 3 data",
+			err: &http.Error{StatusCode: 400, Body: []byte(`{"error":"partial write of line protocol occurred","data":[` +
+				`{"original_line":"cpu value","line_number":2,"error_message":"No fields were provided"},` +
+				`{"original_line":"mem v=x","line_number":3,"error_message":"invalid column type"},` +
+				`{"original_line":"xx","line_number":7,"error_message":"out of batch"}]}`)},
+			rejected: []write.RejectedLine{
+				{Line: 2, Record: "cpu value", Reason: "No fields were provided"},
+				{Line: 3, Record: "mem v=x", Reason: "invalid column type"},
+			},
+		},
+		{
+			name: "InfluxDB 2 line errors",
+			err: &http.Error{StatusCode: 400, Code: "invalid", Message: "failed to parse line protocol:\nerrors encountered on line(s):\n" +
+				"line 2: unable to parse 'cpu value': missing fields\nline 3: unable to parse 'mem v=x': invalid boolean"},
+			rejected: []write.RejectedLine{
+				{Line: 2, Record: "cpu value", Reason: "unable to parse 'cpu value': missing fields"},
+				{Line: 3, Record: "mem v=x", Reason: "unable to parse 'mem v=x': invalid boolean"},
+			},
+		},
+		{
+			name: "InfluxDB 2 line",
+			err: &http.Error{StatusCode: 400, Code: "invalid", Message: "unable to parse points",
+				Body: []byte(`{"code":"invalid","line":3,"message":"unable to parse points"}`)},
+			rejected: []write.RejectedLine{{Line: 3, Record: "mem v=x", Reason: "unable to parse points"}},
+		},
+		{
+			name: "unable to parse",
+			err:  &http.Error{StatusCode: 400, Code: "invalid", Message: "partial write: unable to parse 'mem v=x': invalid number\nunable to parse 'cpu value': missing fields"},
+			rejected: []write.RejectedLine{
+				{Line: 3, Record: "mem v=x", Reason: "partial write: unable to parse 'mem v=x': invalid number"},
+				{Line: 2, Record: "cpu value", Reason: "unable to parse 'cpu value': missing fields"},
+			},
+		},
+		{
+			name: "unknown record",
+			err:  &http.Error{StatusCode: 400, Code: "invalid", Message: "unable to parse 'cpu value,': missing fields"},
+		},
+		{
+			name: "no lines",
+			err:  &http.Error{StatusCode: 400, Code: "invalid", Message: "partial write: field type conflict dropped=1"},
+		},
+		{
+			name: "connection error",
+			err:  http.NewError(errors.New("connection refused")),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewPartialWriteError(NewBatch(batch, 100), test.err)
+			if test.rejected == nil {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Equal(t, test.rejected, err.Rejected)
+			assert.Equal(t, test.err, err.Err)
+		})
+	}
+}
+
+func TestNewPartialWriteError_Points(t *testing.T) {
+	p1 := write.NewPointWithMeasurement("cpu").AddField("v", 1)
+	p2 := write.NewPointWithMeasurement("mem").AddField("v", 2)
+	b := NewBatch("cpu v=1i\nm\nmem v=2i\n", 100)
+	b.Points = []*write.Point{p1, nil, p2}
+	err := NewPartialWriteError(b, &http.Error{StatusCode: 400, Message: "errors encountered on line(s):\nline 3: field type conflict\nline 2: missing fields"})
+	require.NotNil(t, err)
+	assert.Equal(t, []write.RejectedLine{
+		{Line: 3, Record: "mem v=2i", Point: p2, Reason: "field type conflict"},
+		{Line: 2, Record: "m", Reason: "missing fields"},
+	}, err.Rejected)
+	assert.Equal(t, "partial write, 2 rejected lines: line 3: field type conflict; line 2: missing fields", err.Error())
+	var httpErr *http.Error
+	assert.True(t, errors.As(err, &httpErr))
+}
@@ -8,22 +8,22 @@ package write
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/url"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	http2 "github.com/influxdata/influxdb-client-go/v2/api/http"
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
-	"github.com/influxdata/influxdb-client-go/v2/internal/gzip"
 	"github.com/influxdata/influxdb-client-go/v2/internal/log"
 	ilog "github.com/influxdata/influxdb-client-go/v2/log"
-	lp "github.com/influxdata/line-protocol"
 )
 
 // Batch holds information for sending points batch
@@ -36,6 +36,10 @@ type Batch struct {
 	Evicted bool
 	// time when this batch expires
 	Expires time.Time
+	// written points indexed by line of batch, nil for lines of records
+	Points []*write.Point
+	// ID is hash of lines identifying the batch when writes are idempotent, it is set on the first write
+	ID string
 }
 
 // NewBatch creates new batch
@@ -64,15 +68,19 @@ type Service struct {
 	errorCb              BatchErrorCallback
 	retryDelay           uint
 	retryAttempts        uint
+	// limits memory of batches, nil if there is no memory budget
+	budget *budget
+	// retry backoff shared with services writing concurrently, nil if the service writes alone
+	backoff *backoff
+	// IDs of written batches, nil if writes are not deduplicated
+	dedup *dedup
+	// credentials for InfluxDB 1.x, empty username if the authorization of httpService is used
+	username string
+	password string
 }
 
 // NewService creates new write service
 func NewService(org string, bucket string, httpService http2.Service, options *write.Options) *Service {
-
-	retryBufferLimit := options.RetryBufferLimit() / options.BatchSize()
-	if retryBufferLimit == 0 {
-		retryBufferLimit = 1
-	}
 	u, _ := url.Parse(httpService.ServerAPIURL())
 	u, _ = u.Parse("write")
 	params := u.Query()
@@ -83,7 +91,43 @@ func NewService(org string, bucket string, httpService http2.Service, options *w
 		params.Set("consistency", string(options.Consistency()))
 	}
 	u.RawQuery = params.Encode()
-	writeURL := u.String()
+	return newService(org, bucket, u.String(), httpService, options)
+}
+
+// NewV1Service creates new write service writing into the /write endpoint of InfluxDB 1.x
+func NewV1Service(target write.V1Target, httpService http2.Service, options *write.Options) *Service {
+	u, _ := url.Parse(httpService.ServerURL())
+	u, _ = u.Parse("write")
+	params := u.Query()
+	params.Set("db", target.Database)
+	if target.RetentionPolicy != "" {
+		params.Set("rp", target.RetentionPolicy)
+	}
+	params.Set("precision", v1PrecisionToString(options.Precision()))
+	if options.Consistency() != "" {
+		params.Set("consistency", string(options.Consistency()))
+	}
+	u.RawQuery = params.Encode()
+	s := newService("", target.Database, u.String(), httpService, options)
+	s.username = target.Username
+	s.password = target.Password
+	return s
+}
+
+// newService creates new write service writing into writeURL
+func newService(org string, bucket string, writeURL string, httpService http2.Service, options *write.Options) *Service {
+	retryBufferLimit := options.RetryBufferLimit() / options.BatchSize()
+	if retryBufferLimit == 0 {
+		retryBufferLimit = 1
+	}
+	var b *budget
+	if options.MemoryBudget() > 0 {
+		b = newBudget(int(options.MemoryBudget()))
+	}
+	var d *dedup
+	if options.IdempotentWrites() && options.DedupWindow() > 0 {
+		d = newDedup(time.Duration(options.DedupWindow()) * time.Millisecond)
+	}
 	return &Service{
 		org:                  org,
 		bucket:               bucket,
@@ -94,7 +138,56 @@ func NewService(org string, bucket string, httpService http2.Service, options *w
 		retryExponentialBase: 2,
 		retryDelay:           options.RetryInterval(),
 		retryAttempts:        0,
+		budget:               b,
+		dedup:                d,
+	}
+}
+
+// NewWorkers creates n services writing batches concurrently.
+// Services share memory budget, retry backoff and IDs of written batches, retry buffer limit is divided among them.
+func NewWorkers(org string, bucket string, httpService http2.Service, options *write.Options, n int) []*Service {
+	return newWorkers(n, func() *Service { return NewService(org, bucket, httpService, options) })
+}
+
+// NewV1Workers creates n services writing batches concurrently into the /write endpoint of InfluxDB 1.x, like NewWorkers
+func NewV1Workers(target write.V1Target, httpService http2.Service, options *write.Options, n int) []*Service {
+	return newWorkers(n, func() *Service { return NewV1Service(target, httpService, options) })
+}
+
+// newWorkers creates n services created by newService, which share memory budget, retry backoff and IDs of written batches
+func newWorkers(n int, newService func() *Service) []*Service {
+	if n <= 1 {
+		return []*Service{newService()}
 	}
+	workers := make([]*Service, n)
+	b := &backoff{}
+	for i := range workers {
+		s := newService()
+		s.retryQueue = newQueue(max(s.retryQueue.limit/n, 1))
+		if i > 0 {
+			s.budget = workers[0].budget
+			s.dedup = workers[0].dedup
+		}
+		s.backoff = b
+		workers[i] = s
+	}
+	return workers
+}
+
+// NewSibling creates a service writing into bucket of org, which shares memory budget, retry backoff, IDs of written batches and batch error callback with w.
+// Retry backoff is created by the first call, w must not write concurrently with it.
+func (w *Service) NewSibling(org string, bucket string) *Service {
+	w.lock.Lock()
+	if w.backoff == nil {
+		w.backoff = &backoff{}
+	}
+	w.lock.Unlock()
+	s := NewService(org, bucket, w.httpService, w.writeOptions)
+	s.budget = w.budget
+	s.backoff = w.backoff
+	s.dedup = w.dedup
+	s.errorCb = w.errorCb
+	return s
 }
 
 // SetBatchErrorCallback sets callback allowing custom handling of failed writes.
@@ -109,14 +202,18 @@ func (w *Service) SetBatchErrorCallback(cb BatchErrorCallback) {
 // If there are some batches in retry queue, those are written and incoming batch is added to end of retry queue.
 // Immediate write is allowed only in case there was success or not retryable error.
 // Otherwise, delay is checked based on recent batch.
-// If write of batch fails with retryable error (connection errors and HTTP code >= 429),
+// If write of batch fails with retryable error (connection errors and HTTP code >= 429, unless retry policy of write options decides otherwise),
 // Batch retry time is calculated based on #of attempts.
 // If writes continues failing and # of attempts reaches maximum or total retry time reaches maxRetryTime,
 // batch is discarded.
+// If the server rejects some lines of a batch, the batch is not retried and *write.PartialWriteError is returned.
+// Services created by NewWorkers don't write while another of them waits for retrying, the batch is added to retry queue instead.
 func (w *Service) HandleWrite(ctx context.Context, batch *Batch) error {
 	log.Debug("Write proc: received write request")
+	w.trimRetryQueue()
 	batchToWrite := batch
 	retrying := false
+	var partialErrs []error
 	for {
 		select {
 		case <-ctx.Done():
@@ -124,7 +221,11 @@ func (w *Service) HandleWrite(ctx context.Context, batch *Batch) error {
 			return ctx.Err()
 		default:
 		}
-		if !w.retryQueue.isEmpty() {
+		if batchToWrite != nil && batchToWrite == batch && w.retryQueue.isEmpty() && w.Paused() {
+			log.Warn("Write proc: writes are paused, storing batch to queue")
+			w.pushRetry(batch)
+			batchToWrite = nil
+		} else if !w.retryQueue.isEmpty() {
 			log.Debug("Write proc: taking batch from retry queue")
 			if !retrying {
 				b := w.retryQueue.first()
@@ -135,27 +236,24 @@ func (w *Service) HandleWrite(ctx context.Context, batch *Batch) error {
 					if !b.Evicted {
 						w.retryQueue.pop()
 					}
+					w.discard(b, write.DeadLetterExpired, nil)
 
 					continue
 				}
 
 				// Can we write? In case of retryable error we must wait a bit
-				if w.lastWriteAttempt.IsZero() || time.Now().After(w.lastWriteAttempt.Add(time.Millisecond*time.Duration(w.retryDelay))) {
+				if (w.lastWriteAttempt.IsZero() || time.Now().After(w.lastWriteAttempt.Add(time.Millisecond*time.Duration(w.retryDelay)))) && !w.Paused() {
 					retrying = true
 				} else {
 					log.Warn("Write proc: cannot write yet, storing batch to queue")
-					if w.retryQueue.push(batch) {
-						log.Error("Write proc: Retry buffer full, discarding oldest batch")
-					}
+					w.pushRetry(batch)
 					batchToWrite = nil
 				}
 			}
 			if retrying {
 				batchToWrite = w.retryQueue.first()
 				if batch != nil { //store actual batch to retry queue
-					if w.retryQueue.push(batch) {
-						log.Error("Write proc: Retry buffer full, discarding oldest batch")
-					}
+					w.pushRetry(batch)
 					batch = nil
 				}
 			}
@@ -164,33 +262,39 @@ func (w *Service) HandleWrite(ctx context.Context, batch *Batch) error {
 		if batchToWrite != nil {
 			perror := w.WriteBatch(ctx, batchToWrite)
 			if perror != nil {
-				if isIgnorableError(perror) {
+				if pwerr := NewPartialWriteError(batchToWrite, perror); pwerr != nil {
+					log.Errorf("Write error: %s\n", pwerr.Error())
+					partialErrs = append(partialErrs, pwerr)
+					w.deadLetter(rejectedBatch(batchToWrite, pwerr), write.DeadLetterPartialWrite, pwerr)
+				} else if isIgnorableError(perror) {
 					log.Warnf("Write error: %s", perror.Error())
+					w.deadLetter(batchToWrite, write.DeadLetterIgnoredError, perror)
 				} else {
-					if w.writeOptions.MaxRetries() != 0 && (perror.StatusCode == 0 || perror.StatusCode >= http.StatusTooManyRequests) {
+					retry, retryDelay := w.retryDecision(perror, w.attempts())
+					w.RecordResult(!retry)
+					if w.writeOptions.MaxRetries() != 0 && retry {
 						log.Errorf("Write error: %s, batch kept for retrying\n", perror.Error())
-						if perror.RetryAfter > 0 {
-							w.retryDelay = perror.RetryAfter * 1000
-						} else {
-							w.retryDelay = w.computeRetryDelay(w.retryAttempts)
+						w.retryDelay = retryDelay
+						if w.backoff != nil {
+							w.backoff.fail(w.lastWriteAttempt.Add(time.Millisecond * time.Duration(w.retryDelay)))
 						}
 						if w.errorCb != nil && !w.errorCb(batchToWrite, *perror) {
 							log.Error("Callback rejected batch, discarding")
 							if !batchToWrite.Evicted {
 								w.retryQueue.pop()
 							}
+							w.discard(batchToWrite, write.DeadLetterCallbackRejected, perror)
 							return perror
 						}
 						// store new batch (not taken from queue)
 						if !batchToWrite.Evicted && batchToWrite != w.retryQueue.first() {
-							if w.retryQueue.push(batch) {
-								log.Error("Retry buffer full, discarding oldest batch")
-							}
+							w.pushRetry(batch)
 						} else if batchToWrite.RetryAttempts == w.writeOptions.MaxRetries() {
 							log.Error("Reached maximum number of retries, discarding batch")
 							if !batchToWrite.Evicted {
 								w.retryQueue.pop()
 							}
+							w.discard(batchToWrite, write.DeadLetterMaxRetries, perror)
 						}
 						batchToWrite.RetryAttempts++
 						w.retryAttempts++
@@ -209,27 +313,205 @@ func (w *Service) HandleWrite(ctx context.Context, batch *Batch) error {
 							logMessage += fmt.Sprintf("\nSelected Response Headers:\n%s", logHeaders)
 						}
 						log.Error(logMessage)
+						// batches from retry queue are kept there
+						if !retrying {
+							w.discard(batchToWrite, write.DeadLetterWriteFailed, perror)
+						}
+					}
+					if batchToWrite.ID != "" {
+						log.Errorf("Write failed (batch %s, retry attempts %d): Status Code %d",
+							batchToWrite.ID,
+							batchToWrite.RetryAttempts,
+							perror.StatusCode)
+					} else {
+						log.Errorf("Write failed (retry attempts %d): Status Code %d",
+							batchToWrite.RetryAttempts,
+							perror.StatusCode)
 					}
-					log.Errorf("Write failed (retry attempts %d): Status Code %d",
-						batchToWrite.RetryAttempts,
-						perror.StatusCode)
 					return perror
 				}
 			}
 
+			w.RecordResult(true)
 			w.retryDelay = w.writeOptions.RetryInterval()
 			w.retryAttempts = 0
+			if w.backoff != nil {
+				w.backoff.succeed()
+			}
 			if retrying && !batchToWrite.Evicted {
 				w.retryQueue.pop()
 			}
+			w.release(batchToWrite)
 			batchToWrite = nil
 		} else {
 			break
 		}
 	}
+	return errors.Join(partialErrs...)
+}
+
+// Paused returns true if writes are paused by a failed write of another service sharing backoff, or by the circuit breaker of write options.
+// The circuit breaker is asked last, as a write allowed by a half-open circuit is its probe.
+func (w *Service) Paused() bool {
+	if w.backoff != nil && w.backoff.paused() {
+		return true
+	}
+	breaker := w.writeOptions.CircuitBreaker()
+	return breaker != nil && !breaker.Allow()
+}
+
+// RetryDelay returns whether write failed with perror is retried and the delay before the next write, after attempts failed writes.
+// Connection errors and errors with status code 429 or higher are retried, unless retry policy of write options decides otherwise.
+// The delay is Retry-After sent by the server or exponential delay computed from retry options.
+func (w *Service) RetryDelay(perror *http2.Error, attempts uint) (bool, time.Duration) {
+	retry, delay := w.retryDecision(perror, attempts)
+	return retry, time.Duration(delay) * time.Millisecond
+}
+
+// retryDecision returns whether write failed with perror is retried and the retry delay in ms, according to the retry policy of write options.
+// By default, connection errors and errors with status code 429 or higher are retried.
+func (w *Service) retryDecision(perror *http2.Error, attempts uint) (bool, uint) {
+	retry := perror.StatusCode == 0 || perror.StatusCode >= http.StatusTooManyRequests
+	var delay time.Duration
+	if policy := w.writeOptions.RetryPolicy(); policy != nil {
+		retry, delay = policy.Retry(perror, attempts)
+	}
+	if !retry {
+		return false, 0
+	}
+	switch {
+	case delay > 0:
+		return true, uint(max(delay/time.Millisecond, 1))
+	case perror.RetryAfter > 0:
+		return true, perror.RetryAfter * 1000
+	default:
+		return true, w.computeRetryDelay(attempts)
+	}
+}
+
+// RecordResult records result of a write in the circuit breaker of write options, if set
+func (w *Service) RecordResult(success bool) {
+	breaker := w.writeOptions.CircuitBreaker()
+	switch {
+	case breaker == nil:
+	case success:
+		breaker.RecordSuccess()
+	default:
+		breaker.RecordFailure()
+	}
+}
+
+// attempts returns number of failed writes since the last successful write, shared with other services, if any
+func (w *Service) attempts() uint {
+	if w.backoff != nil {
+		return w.backoff.retryAttempts()
+	}
+	return w.retryAttempts
+}
+
+// pushRetry adds batch to the retry queue, the oldest batch is discarded if the queue is full
+func (w *Service) pushRetry(batch *Batch) {
+	if batch == nil {
+		return
+	}
+	oldest := w.retryQueue.first()
+	if w.retryQueue.push(batch) {
+		log.Error("Write proc: Retry buffer full, discarding oldest batch")
+		w.discard(oldest, write.DeadLetterRetryBufferFull, nil)
+	}
+}
+
+// HasRetries returns true if there are batches in the retry queue, they are retried by HandleWrite
+func (w *Service) HasRetries() bool {
+	return !w.retryQueue.isEmpty()
+}
+
+// AcquireBuffer reserves memory budget for line added to the write buffer of WriteAPI, point is the point encoded in line, nil for records.
+// When the budget is exceeded, the overflow policy of write options applies, it can block until memory is freed or ctx is done.
+// It returns write.ErrMemoryBudgetExceeded if line was discarded.
+func (w *Service) AcquireBuffer(ctx context.Context, line string, point *write.Point) error {
+	if w.budget == nil {
+		return nil
+	}
+	switch w.writeOptions.OverflowPolicy() {
+	case write.OverflowDropNewest:
+		if !w.budget.tryAcquire(len(line)) {
+			log.Error("Memory budget exceeded, discarding line")
+			b := &Batch{Batch: line}
+			if point != nil {
+				b.Points = []*write.Point{point}
+			}
+			w.deadLetter(b, write.DeadLetterMemoryBudget, nil)
+			return write.ErrMemoryBudgetExceeded
+		}
+	case write.OverflowBlock:
+		return w.budget.acquire(ctx, len(line))
+	default:
+		w.budget.reserve(len(line))
+	}
 	return nil
 }
 
+// TryAcquireBuffer reserves memory budget for line added to the write buffer of WriteAPI,
+// it returns false if the budget would be exceeded, regardless of the overflow policy
+func (w *Service) TryAcquireBuffer(line string) bool {
+	return w.budget == nil || w.budget.tryAcquire(len(line))
+}
+
+// ReleaseBuffer frees memory budget of line, which was not added to the write buffer
+func (w *Service) ReleaseBuffer(line string) {
+	if w.budget != nil {
+		w.budget.release(len(line))
+	}
+}
+
+// trimRetryQueue discards the oldest batches from the retry queue while the memory budget is exceeded, according to drop oldest policy
+func (w *Service) trimRetryQueue() {
+	if w.budget == nil {
+		return
+	}
+	if policy := w.writeOptions.OverflowPolicy(); policy == write.OverflowDropNewest || policy == write.OverflowBlock {
+		return
+	}
+	for w.budget.exceeded() && !w.retryQueue.isEmpty() {
+		log.Error("Write proc: memory budget exceeded, discarding oldest batch")
+		w.discard(w.retryQueue.pop(), write.DeadLetterMemoryBudget, nil)
+	}
+}
+
+// release frees memory budget of batch, which was written or discarded
+func (w *Service) release(batch *Batch) {
+	if w.budget != nil {
+		w.budget.release(len(batch.Batch))
+	}
+}
+
+// discard releases memory of discarded batch and sends it to the dead letter sink
+func (w *Service) discard(batch *Batch, reason write.DeadLetterReason, err error) {
+	w.release(batch)
+	w.deadLetter(batch, reason, err)
+}
+
+// deadLetter sends discarded batch to the dead letter sink of write options, if set
+func (w *Service) deadLetter(batch *Batch, reason write.DeadLetterReason, err error) {
+	sink := w.writeOptions.DeadLetterSink()
+	if sink == nil {
+		return
+	}
+	letter := &write.DeadLetter{
+		ID:            w.batchID(batch),
+		Reason:        reason,
+		Batch:         batch.Batch,
+		Points:        batch.Points,
+		Err:           err,
+		RetryAttempts: batch.RetryAttempts,
+		Time:          time.Now(),
+	}
+	if serr := sink.Send(letter); serr != nil {
+		log.Errorf("Cannot send discarded batch to dead letter sink: %s\n", serr.Error())
+	}
+}
+
 // Non-retryable errors
 const (
 	errStringHintedHandoffNotEmpty = "hinted handoff queue not empty"
@@ -293,124 +575,158 @@ func pow(x, y uint) uint {
 	return p
 }
 
-// WriteBatch performs actual writing via HTTP service
+// batchID returns ID of batch when writes are idempotent, or empty string.
+// The ID is hash of the lines, so that the same lines written again have the same ID.
+func (w *Service) batchID(batch *Batch) string {
+	if batch.ID == "" && w.writeOptions.IdempotentWrites() {
+		sum := sha256.Sum256([]byte(batch.Batch))
+		batch.ID = hex.EncodeToString(sum[:16])
+	}
+	return batch.ID
+}
+
+// WriteBatch performs actual writing via HTTP service.
+// When writes are idempotent, the batch ID is sent in the Idempotency-Key header
+// and a batch already written within the dedup window is not written again.
 func (w *Service) WriteBatch(ctx context.Context, batch *Batch) *http2.Error {
 	var body io.Reader
 	var err error
 	body = strings.NewReader(batch.Batch)
 
+	id := w.batchID(batch)
+	if w.dedup != nil && w.dedup.acknowledged(id) {
+		log.Warnf("Batch %s was already written, skipping", id)
+		return nil
+	}
 	if log.Level() >= ilog.DebugLevel {
 		log.Debugf("Writing batch: %s", batch.Batch)
 	}
-	if w.writeOptions.UseGZip() {
-		body, err = gzip.CompressWithGzip(body)
+	codec := w.writeOptions.Compression()
+	if codec != nil && len(batch.Batch) >= int(w.writeOptions.CompressionThreshold()) {
+		body, err = compress(codec, batch.Batch)
 		if err != nil {
 			return http2.NewError(err)
 		}
+	} else {
+		codec = nil
 	}
 	w.lock.Lock()
 	w.lastWriteAttempt = time.Now()
 	w.lock.Unlock()
 	perror := w.httpService.DoPostRequest(ctx, w.url, body, func(req *http.Request) {
-		if w.writeOptions.UseGZip() {
-			req.Header.Set("Content-Encoding", "gzip")
+		if codec != nil {
+			req.Header.Set("Content-Encoding", codec.Encoding())
+		}
+		if id != "" {
+			req.Header.Set("Idempotency-Key", id)
+		}
+		if w.username != "" {
+			req.SetBasicAuth(w.username, w.password)
 		}
 	}, func(r *http.Response) error {
 		return r.Body.Close()
 	})
+	if perror == nil && w.dedup != nil {
+		w.dedup.acknowledge(id)
+	}
 	return perror
 }
 
+// compress returns reader of data compressed by codec
+func compress(codec http2.Codec, data string) (io.Reader, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data) / 4)
+	cw := codec.NewWriter(&buf)
+	if _, err := io.WriteString(cw, data); err != nil {
+		cw.Close()
+		return nil, err
+	}
+	if err := cw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
 // Flush sends batches from retry queue immediately, without retrying
 func (w *Service) Flush() {
 	for !w.retryQueue.isEmpty() {
 		b := w.retryQueue.pop()
 		if time.Now().After(b.Expires) {
 			log.Error("Oldest batch in retry queue expired, discarding")
+			w.discard(b, write.DeadLetterExpired, nil)
 			continue
 		}
 		if err := w.WriteBatch(context.Background(), b); err != nil {
 			log.Errorf("Error flushing batch from retry queue: %w", err.Unwrap())
+			if pwerr := NewPartialWriteError(b, err); pwerr != nil {
+				w.deadLetter(rejectedBatch(b, pwerr), write.DeadLetterPartialWrite, pwerr)
+			} else {
+				w.deadLetter(b, write.DeadLetterWriteFailed, err)
+			}
 		}
+		w.release(b)
 	}
 }
 
-// pointWithDefaultTags encapsulates Point with default tags
-type pointWithDefaultTags struct {
-	point       *write.Point
-	defaultTags map[string]string
-}
-
-// Name returns the name of measurement of a point.
-func (p *pointWithDefaultTags) Name() string {
-	return p.point.Name()
-}
-
-// Time is the timestamp of a Point.
-func (p *pointWithDefaultTags) Time() time.Time {
-	return p.point.Time()
-}
-
-// FieldList returns a slice containing the fields of a Point.
-func (p *pointWithDefaultTags) FieldList() []*lp.Field {
-	return p.point.FieldList()
+// encodeBuffer holds an encoder with its buffer, which are reused by EncodePoints
+type encodeBuffer struct {
+	encoder *write.Encoder
+	buf     []byte
 }
 
-// TagList returns tags from point along with default tags
-// If point of tag can override default tag
-func (p *pointWithDefaultTags) TagList() []*lp.Tag {
-	tags := make([]*lp.Tag, 0, len(p.point.TagList())+len(p.defaultTags))
-	tags = append(tags, p.point.TagList()...)
-	for k, v := range p.defaultTags {
-		if !existTag(p.point.TagList(), k) {
-			tags = append(tags, &lp.Tag{
-				Key:   k,
-				Value: v,
-			})
-		}
-	}
-	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
-	return tags
-}
+// maxPooledBufferSize is the maximum capacity of a buffer returned to the pool, larger buffers are left to GC
+const maxPooledBufferSize = 1 << 20
 
-func existTag(tags []*lp.Tag, key string) bool {
-	for _, tag := range tags {
-		if key == tag.Key {
-			return true
-		}
-	}
-	return false
+var encodeBuffers = sync.Pool{
+	New: func() interface{} {
+		return &encodeBuffer{encoder: write.NewEncoder(), buf: make([]byte, 0, 1024)}
+	},
 }
 
-// EncodePoints creates line protocol string from points
+// EncodePoints creates line protocol string from points.
+// Points are validated against the schema registry of write options, if set.
+// When writes are idempotent, points without timestamp are encoded with the current time.
 func (w *Service) EncodePoints(points ...*write.Point) (string, error) {
-	var buffer bytes.Buffer
-	e := lp.NewEncoder(&buffer)
-	e.SetFieldTypeSupport(lp.UintSupport)
-	e.FailOnFieldErr(true)
-	e.SetPrecision(w.writeOptions.Precision())
+	b := encodeBuffers.Get().(*encodeBuffer)
+	defer func() {
+		if cap(b.buf) <= maxPooledBufferSize {
+			encodeBuffers.Put(b)
+		}
+	}()
+	b.encoder.SetPrecision(w.writeOptions.Precision()).SetDefaultTags(w.writeOptions.DefaultTags()).SetDefaultTime(w.defaultTime())
+	b.buf = b.buf[:0]
+	registry := w.writeOptions.SchemaRegistry()
 	for _, point := range points {
-		_, err := e.Encode(w.pointToEncode(point))
+		var err error
+		if registry != nil {
+			point, err = registry.Check(point, w.writeOptions.CoerceToSchema())
+			if err != nil {
+				return "", err
+			}
+		}
+		b.buf, err = b.encoder.Append(b.buf, point)
 		if err != nil {
 			return "", err
 		}
 	}
-	return buffer.String(), nil
+	return string(b.buf), nil
 }
 
-// pointToEncode determines whether default tags should be applied
-// and returns point with default tags instead of point
-func (w *Service) pointToEncode(point *write.Point) lp.Metric {
-	var m lp.Metric
-	if len(w.writeOptions.DefaultTags()) > 0 {
-		m = &pointWithDefaultTags{
-			point:       point,
-			defaultTags: w.writeOptions.DefaultTags(),
-		}
-	} else {
-		m = point
+// FreezeTimestamps returns line protocol records with the current time added to records without timestamp, when writes are idempotent.
+// Otherwise, records are returned unchanged.
+func (w *Service) FreezeTimestamps(records string) string {
+	if !w.writeOptions.IdempotentWrites() {
+		return records
+	}
+	return write.SetMissingTimestamps(records, time.Now(), w.writeOptions.Precision())
+}
+
+// defaultTime returns timestamp of encoded points without timestamp, zero time if the server sets it
+func (w *Service) defaultTime() time.Time {
+	if w.writeOptions.IdempotentWrites() {
+		return time.Now()
 	}
-	return m
+	return time.Time{}
 }
 
 // WriteURL returns current write URL
@@ -418,6 +734,14 @@ func (w *Service) WriteURL() string {
 	return w.url
 }
 
+// v1PrecisionToString returns precision parameter of InfluxDB 1.x /write endpoint
+func v1PrecisionToString(precision time.Duration) string {
+	if precision == time.Microsecond {
+		return "u"
+	}
+	return precisionToString(precision)
+}
+
 func precisionToString(precision time.Duration) string {
 	prec := "ns"
 	switch precision {
@@ -5,9 +5,12 @@
 package write
 
 import (
+	"compress/flate"
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	ilog "log"
 	ihttp "net/http"
 	"net/http/httptest"
@@ -719,3 +722,493 @@ func TestHttpErrorHeaders(t *testing.T) {
 	assert.Equal(t, "Not All Correct", err.(*http.Error).Header.Get("X-Test-Val1"))
 	assert.Equal(t, "Atlas LV-3B", err.(*http.Error).Header.Get("X-Test-Val2"))
 }
+
+func TestPartialWriteError(t *testing.T) {
+	server := httptest.NewServer(ihttp.HandlerFunc(func(w ihttp.ResponseWriter, r *ihttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(ihttp.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"partial write of line protocol occurred","data":[{"original_line":"cpu value","line_number":2,"error_message":"No fields were provided"}]}`))
+	}))
+	defer server.Close()
+	srv := NewService("my-org", "my-bucket", http.NewService(server.URL, "", http.DefaultOptions()), write.DefaultOptions())
+
+	err := srv.HandleWrite(context.Background(), NewBatch("cpu v=1\ncpu value\n", 20))
+	var pwerr *write.PartialWriteError
+	require.ErrorAs(t, err, &pwerr)
+	assert.Equal(t, []write.RejectedLine{{Line: 2, Record: "cpu value", Reason: "No fields were provided"}}, pwerr.Rejected)
+	var httpErr *http.Error
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, ihttp.StatusBadRequest, httpErr.StatusCode)
+	// batch is not retried
+	assert.True(t, srv.retryQueue.isEmpty())
+}
+
+func TestDeadLetterSink(t *testing.T) {
+	ctx := context.Background()
+	newService := func(opts *write.Options, replyError *http.Error) (*Service, *write.ChannelDeadLetterSink) {
+		hs := test.NewTestService(t, "http://localhost:8086")
+		hs.SetReplyError(replyError)
+		sink := write.NewChannelDeadLetterSink(10)
+		return NewService("my-org", "my-bucket", hs, opts.SetDeadLetterSink(sink)), sink
+	}
+	assertLetter := func(t *testing.T, sink *write.ChannelDeadLetterSink, reason write.DeadLetterReason, batch string, err error) {
+		t.Helper()
+		require.Len(t, sink.Letters(), 1)
+		letter := <-sink.Letters()
+		assert.Equal(t, reason, letter.Reason)
+		assert.Equal(t, batch, letter.Batch)
+		assert.Equal(t, err, letter.Err)
+		assert.False(t, letter.Time.IsZero())
+	}
+
+	t.Run("write failed", func(t *testing.T) {
+		perror := &http.Error{StatusCode: 400, Code: "invalid", Message: "bad request"}
+		srv, sink := newService(write.DefaultOptions(), perror)
+		require.Error(t, srv.HandleWrite(ctx, NewBatch("1\n", 100)))
+		assertLetter(t, sink, write.DeadLetterWriteFailed, "1\n", perror)
+	})
+	t.Run("ignored error", func(t *testing.T) {
+		perror := &http.Error{StatusCode: 500, Code: "internal error", Message: "partial write: points beyond retention policy dropped=1"}
+		srv, sink := newService(write.DefaultOptions(), perror)
+		require.NoError(t, srv.HandleWrite(ctx, NewBatch("1\n", 100)))
+		assertLetter(t, sink, write.DeadLetterIgnoredError, "1\n", perror)
+	})
+	t.Run("partial write", func(t *testing.T) {
+		perror := &http.Error{StatusCode: 400, Code: "invalid", Message: "errors encountered on line(s):\nline 2: bad"}
+		srv, sink := newService(write.DefaultOptions(), perror)
+		p := write.NewPointWithMeasurement("m").AddField("v", 2)
+		b := NewBatch("1\n2\n3\n", 100)
+		b.Points = []*write.Point{nil, p, nil}
+		err := srv.HandleWrite(ctx, b)
+		var pwerr *write.PartialWriteError
+		require.ErrorAs(t, err, &pwerr)
+		require.Len(t, sink.Letters(), 1)
+		letter := <-sink.Letters()
+		assert.Equal(t, write.DeadLetterPartialWrite, letter.Reason)
+		assert.Equal(t, "2\n", letter.Batch)
+		assert.Equal(t, []*write.Point{p}, letter.Points)
+		assert.Equal(t, pwerr, letter.Err)
+	})
+	t.Run("callback rejected", func(t *testing.T) {
+		perror := &http.Error{StatusCode: 429}
+		srv, sink := newService(write.DefaultOptions(), perror)
+		srv.SetBatchErrorCallback(func(batch *Batch, error2 http.Error) bool {
+			return false
+		})
+		require.Error(t, srv.HandleWrite(ctx, NewBatch("1\n", 100)))
+		assertLetter(t, sink, write.DeadLetterCallbackRejected, "1\n", perror)
+	})
+	t.Run("max retries", func(t *testing.T) {
+		perror := &http.Error{StatusCode: 429}
+		srv, sink := newService(write.DefaultOptions().SetMaxRetries(1).SetRetryInterval(1), perror)
+		require.Error(t, srv.HandleWrite(ctx, NewBatch("1\n", 1000)))
+		assert.Len(t, sink.Letters(), 0)
+		<-time.After(time.Millisecond * time.Duration(srv.retryDelay+1))
+		require.Error(t, srv.HandleWrite(ctx, NewBatch("2\n", 1000)))
+		assertLetter(t, sink, write.DeadLetterMaxRetries, "1\n", perror)
+	})
+	t.Run("retry buffer full", func(t *testing.T) {
+		srv, sink := newService(write.DefaultOptions().SetBatchSize(1).SetRetryBufferLimit(1).SetRetryInterval(10_000), &http.Error{StatusCode: 429})
+		require.Error(t, srv.HandleWrite(ctx, NewBatch("1\n", 100_000)))
+		require.NoError(t, srv.HandleWrite(ctx, NewBatch("2\n", 100_000)))
+		assertLetter(t, sink, write.DeadLetterRetryBufferFull, "1\n", nil)
+	})
+	t.Run("expired", func(t *testing.T) {
+		srv, sink := newService(write.DefaultOptions(), &http.Error{StatusCode: 429})
+		require.Error(t, srv.HandleWrite(ctx, NewBatch("1\n", 1)))
+		<-time.After(2 * time.Millisecond)
+		srv.Flush()
+		assertLetter(t, sink, write.DeadLetterExpired, "1\n", nil)
+	})
+}
+
+func TestMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	t.Run("drop newest", func(t *testing.T) {
+		hs := test.NewTestService(t, "http://localhost:8086")
+		sink := write.NewChannelDeadLetterSink(10)
+		opts := write.DefaultOptions().SetMemoryBudget(12).SetOverflowPolicy(write.OverflowDropNewest).SetDeadLetterSink(sink)
+		srv := NewService("my-org", "my-bucket", hs, opts)
+		require.NoError(t, srv.AcquireBuffer(ctx, "m v=1\n", nil))
+		p := write.NewPointWithMeasurement("m").AddField("v", 2)
+		assert.Equal(t, write.ErrMemoryBudgetExceeded, srv.AcquireBuffer(ctx, "m v=2i\n", p))
+		require.Len(t, sink.Letters(), 1)
+		letter := <-sink.Letters()
+		assert.Equal(t, write.DeadLetterMemoryBudget, letter.Reason)
+		assert.Equal(t, "m v=2i\n", letter.Batch)
+		assert.Equal(t, []*write.Point{p}, letter.Points)
+		require.NoError(t, srv.AcquireBuffer(ctx, "m v=3\n", nil))
+		assert.Equal(t, 12, srv.budget.size())
+
+		require.NoError(t, srv.HandleWrite(ctx, NewBatch("m v=1\nm v=3\n", 100)))
+		assert.Equal(t, 0, srv.budget.size())
+		assert.NoError(t, srv.AcquireBuffer(ctx, "m v=2i\n", nil))
+	})
+	t.Run("drop oldest", func(t *testing.T) {
+		hs := test.NewTestService(t, "http://localhost:8086")
+		sink := write.NewChannelDeadLetterSink(10)
+		opts := write.DefaultOptions().SetMemoryBudget(10).SetRetryInterval(1).SetDeadLetterSink(sink)
+		srv := NewService("my-org", "my-bucket", hs, opts)
+		hs.SetReplyError(&http.Error{StatusCode: 503})
+		require.NoError(t, srv.AcquireBuffer(ctx, "m v=1\n", nil))
+		require.Error(t, srv.HandleWrite(ctx, NewBatch("m v=1\n", 100)))
+		assert.Equal(t, 1, srv.retryQueue.list.Len())
+
+		require.NoError(t, srv.AcquireBuffer(ctx, "m v=2\n", nil))
+		require.NoError(t, srv.AcquireBuffer(ctx, "m v=3\n", nil))
+		hs.SetReplyError(nil)
+		require.NoError(t, srv.HandleWrite(ctx, NewBatch("m v=2\nm v=3\n", 100)))
+		require.Len(t, sink.Letters(), 1)
+		letter := <-sink.Letters()
+		assert.Equal(t, write.DeadLetterMemoryBudget, letter.Reason)
+		assert.Equal(t, "m v=1\n", letter.Batch)
+		assert.Equal(t, []string{"m v=2", "m v=3"}, hs.Lines())
+		assert.Equal(t, 0, srv.budget.size())
+	})
+	t.Run("block", func(t *testing.T) {
+		hs := test.NewTestService(t, "http://localhost:8086")
+		opts := write.DefaultOptions().SetMemoryBudget(10).SetOverflowPolicy(write.OverflowBlock)
+		srv := NewService("my-org", "my-bucket", hs, opts)
+		require.NoError(t, srv.AcquireBuffer(ctx, "m v=1\n", nil))
+		acquired := make(chan struct{})
+		go func() {
+			_ = srv.AcquireBuffer(ctx, "m v=2\n", nil)
+			close(acquired)
+		}()
+		select {
+		case <-acquired:
+			require.Fail(t, "acquired over budget")
+		case <-time.After(20 * time.Millisecond):
+		}
+		require.NoError(t, srv.HandleWrite(ctx, NewBatch("m v=1\n", 100)))
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			require.Fail(t, "not acquired after release")
+		}
+		assert.Equal(t, 6, srv.budget.size())
+
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, srv.AcquireBuffer(tctx, "m v=3\n", nil), context.DeadlineExceeded)
+		assert.Equal(t, 6, srv.budget.size())
+		assert.False(t, srv.TryAcquireBuffer("m v=3\n"))
+		srv.ReleaseBuffer("m v=2\n")
+		assert.True(t, srv.TryAcquireBuffer("m v=3\n"))
+		assert.Equal(t, 6, srv.budget.size())
+	})
+	t.Run("no budget", func(t *testing.T) {
+		srv := NewService("my-org", "my-bucket", test.NewTestService(t, "http://localhost:8086"), write.DefaultOptions())
+		assert.Nil(t, srv.budget)
+		assert.NoError(t, srv.AcquireBuffer(ctx, "m v=1\n", nil))
+	})
+}
+
+func TestWorkers(t *testing.T) {
+	log.Log.SetLogLevel(log.DebugLevel)
+	hs := test.NewTestService(t, "http://localhost:8086")
+	opts := write.DefaultOptions().SetBatchSize(10).SetRetryBufferLimit(60).SetRetryInterval(50).SetMemoryBudget(1000)
+	ctx := context.Background()
+	assert.Len(t, NewWorkers("my-org", "my-bucket", hs, opts, 0), 1)
+
+	workers := NewWorkers("my-org", "my-bucket", hs, opts, 3)
+	require.Len(t, workers, 3)
+	for _, w := range workers {
+		assert.Equal(t, 2, w.retryQueue.limit)
+		assert.Same(t, workers[0].budget, w.budget)
+		assert.Same(t, workers[0].backoff, w.backoff)
+	}
+
+	// a retryable error of one worker pauses writes of other workers
+	hs.SetReplyError(&http.Error{StatusCode: 503})
+	require.Error(t, workers[0].HandleWrite(ctx, NewBatch("1\n", opts.MaxRetryTime())))
+	assert.Equal(t, 1, hs.Requests())
+	assert.EqualValues(t, 1, workers[0].backoff.retryAttempts())
+	hs.SetReplyError(nil)
+	require.NoError(t, workers[1].HandleWrite(ctx, NewBatch("2\n", opts.MaxRetryTime())))
+	assert.Equal(t, 1, hs.Requests())
+	assert.Equal(t, 1, workers[1].retryQueue.list.Len())
+
+	// writes continue when the retry delay has passed
+	<-time.After(time.Until(workers[0].lastWriteAttempt.Add(time.Millisecond * time.Duration(workers[0].retryDelay))))
+	require.NoError(t, workers[1].HandleWrite(ctx, NewBatch("3\n", opts.MaxRetryTime())))
+	assert.Equal(t, []string{"2", "3"}, hs.Lines())
+	assert.True(t, workers[1].retryQueue.isEmpty())
+	assert.EqualValues(t, 0, workers[0].backoff.retryAttempts())
+	require.NoError(t, workers[2].HandleWrite(ctx, NewBatch("4\n", opts.MaxRetryTime())))
+	assert.Equal(t, []string{"2", "3", "4"}, hs.Lines())
+	// the first worker retries its batch with the next write
+	require.NoError(t, workers[0].HandleWrite(ctx, NewBatch("5\n", opts.MaxRetryTime())))
+	assert.Equal(t, []string{"2", "3", "4", "1", "5"}, hs.Lines())
+}
+
+func TestRetryPolicy(t *testing.T) {
+	hs := test.NewTestService(t, "http://localhost:8086")
+	var attempts []uint
+	opts := write.DefaultOptions().SetRetryPolicy(write.RetryPolicyFunc(func(err *http.Error, a uint) (bool, time.Duration) {
+		attempts = append(attempts, a)
+		switch err.StatusCode {
+		case 400:
+			return true, 3 * time.Second
+		case 500:
+			return true, 0
+		}
+		return false, 0
+	}))
+	ctx := context.Background()
+	srv := NewService("my-org", "my-bucket", hs, opts)
+
+	hs.SetReplyError(&http.Error{StatusCode: 400})
+	require.Error(t, srv.HandleWrite(ctx, NewBatch("1\n", opts.MaxRetryTime())))
+	assert.EqualValues(t, 3_000, srv.retryDelay)
+	assert.Equal(t, 1, srv.retryQueue.list.Len())
+
+	// zero delay is the default delay
+	srv.lastWriteAttempt = time.Time{}
+	hs.SetReplyError(&http.Error{StatusCode: 500, RetryAfter: 7})
+	require.Error(t, srv.HandleWrite(ctx, NewBatch("2\n", opts.MaxRetryTime())))
+	assert.EqualValues(t, 7_000, srv.retryDelay)
+	srv.lastWriteAttempt = time.Time{}
+	hs.SetReplyError(&http.Error{StatusCode: 500})
+	require.Error(t, srv.HandleWrite(ctx, NewBatch("3\n", opts.MaxRetryTime())))
+	assertBetween(t, srv.retryDelay, 20_000, 40_000)
+	assert.Equal(t, 3, srv.retryQueue.list.Len())
+
+	assert.Equal(t, []uint{0, 1, 2}, attempts)
+
+	// 503 is not retried by the policy
+	srv = NewService("my-org", "my-bucket", hs, opts)
+	hs.SetReplyError(&http.Error{StatusCode: 503})
+	require.Error(t, srv.HandleWrite(ctx, NewBatch("4\n", opts.MaxRetryTime())))
+	assert.True(t, srv.retryQueue.isEmpty())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	hs := test.NewTestService(t, "http://localhost:8086")
+	var states []write.CircuitState
+	breaker := write.NewCircuitBreaker(2, 50*time.Millisecond).SetStateChangeCallback(func(_, to write.CircuitState) {
+		states = append(states, to)
+	})
+	opts := write.DefaultOptions().SetRetryInterval(1).SetCircuitBreaker(breaker)
+	ctx := context.Background()
+	srv := NewService("my-org", "my-bucket", hs, opts)
+
+	// a non-retryable error is a response of the server
+	hs.SetReplyError(&http.Error{StatusCode: 400})
+	require.Error(t, srv.HandleWrite(ctx, NewBatch("0\n", opts.MaxRetryTime())))
+	hs.SetReplyError(&http.Error{StatusCode: 503})
+	require.Error(t, srv.HandleWrite(ctx, NewBatch("1\n", opts.MaxRetryTime())))
+	assert.Equal(t, write.CircuitClosed, breaker.State())
+	<-time.After(5 * time.Millisecond)
+	require.Error(t, srv.HandleWrite(ctx, NewBatch("2\n", opts.MaxRetryTime())))
+	assert.Equal(t, write.CircuitOpen, breaker.State())
+	assert.Equal(t, 3, hs.Requests())
+
+	// open circuit stops writes
+	<-time.After(5 * time.Millisecond)
+	hs.SetReplyError(nil)
+	require.NoError(t, srv.HandleWrite(ctx, NewBatch("3\n", opts.MaxRetryTime())))
+	assert.Equal(t, 3, hs.Requests())
+	assert.Equal(t, 3, srv.retryQueue.list.Len())
+
+	// after cool-down, a successful write closes the circuit
+	<-time.After(50 * time.Millisecond)
+	require.NoError(t, srv.HandleWrite(ctx, NewBatch("4\n", opts.MaxRetryTime())))
+	assert.Equal(t, write.CircuitClosed, breaker.State())
+	assert.Equal(t, []string{"1", "2", "3", "4"}, hs.Lines())
+	assert.Equal(t, []write.CircuitState{write.CircuitOpen, write.CircuitHalfOpen, write.CircuitClosed}, states)
+}
+
+func TestRetryDelay(t *testing.T) {
+	opts := write.DefaultOptions()
+	srv := NewService("my-org", "my-bucket", test.NewTestService(t, "http://localhost:8086"), opts)
+	retry, delay := srv.RetryDelay(&http.Error{StatusCode: 503, RetryAfter: 3}, 4)
+	assert.True(t, retry)
+	assert.Equal(t, 3*time.Second, delay)
+	retry, delay = srv.RetryDelay(&http.Error{StatusCode: 429}, 1)
+	assert.True(t, retry)
+	assert.True(t, delay >= 10*time.Second && delay <= 20*time.Second, delay)
+	retry, _ = srv.RetryDelay(&http.Error{StatusCode: 400}, 0)
+	assert.False(t, retry)
+}
+
+func TestIdempotentWrites(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	status := ihttp.StatusServiceUnavailable
+	server := httptest.NewServer(ihttp.HandlerFunc(func(w ihttp.ResponseWriter, r *ihttp.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	sink := write.NewChannelDeadLetterSink(1)
+	opts := write.DefaultOptions().SetIdempotentWrites(true).SetRetryInterval(1).SetDeadLetterSink(sink)
+	srv := NewService("my-org", "my-bucket", http.NewService(server.URL, "", http.DefaultOptions()), opts)
+
+	// retried batch keeps its ID
+	b := NewBatch("m v=1i 1\n", opts.MaxRetryTime())
+	require.Error(t, srv.HandleWrite(context.Background(), b))
+	require.NotEmpty(t, b.ID)
+	assert.Len(t, b.ID, 32)
+	mu.Lock()
+	status = ihttp.StatusNoContent
+	mu.Unlock()
+	<-time.After(10 * time.Millisecond)
+	require.NoError(t, srv.HandleWrite(context.Background(), nil))
+	assert.Equal(t, []string{b.ID, b.ID}, keys)
+
+	// batch with the same lines is not written again within the dedup window
+	b2 := NewBatch("m v=1i 1\n", opts.MaxRetryTime())
+	require.NoError(t, srv.HandleWrite(context.Background(), b2))
+	assert.Equal(t, b.ID, b2.ID)
+	assert.Len(t, keys, 2)
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=2i 1\n", opts.MaxRetryTime())))
+	require.Len(t, keys, 3)
+	assert.NotEqual(t, b.ID, keys[2])
+
+	// dead letters carry the batch ID
+	mu.Lock()
+	status = ihttp.StatusBadRequest
+	mu.Unlock()
+	b3 := NewBatch("m v=3i 1\n", opts.MaxRetryTime())
+	require.Error(t, srv.HandleWrite(context.Background(), b3))
+	letter := <-sink.Letters()
+	assert.Equal(t, b3.ID, letter.ID)
+
+	// expired IDs are forgotten
+	mu.Lock()
+	status = ihttp.StatusNoContent
+	mu.Unlock()
+	srv.dedup = newDedup(5 * time.Millisecond)
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=1i 1\n", opts.MaxRetryTime())))
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=1i 1\n", opts.MaxRetryTime())))
+	assert.Len(t, keys, 5)
+	<-time.After(10 * time.Millisecond)
+	assert.False(t, srv.dedup.acknowledged(b.ID))
+	assert.Empty(t, srv.dedup.acked)
+	assert.Empty(t, srv.dedup.order)
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=1i 1\n", opts.MaxRetryTime())))
+	assert.Len(t, keys, 6)
+
+	// points and records without timestamp get the current time
+	now := time.Now()
+	line, err := srv.EncodePoints(write.NewPointWithMeasurement("m").AddField("v", 1))
+	require.NoError(t, err)
+	p, err := write.ParseLineProtocol(strings.TrimSuffix(line, "\n"), time.Nanosecond)
+	require.NoError(t, err)
+	require.Len(t, p, 1)
+	assert.False(t, p[0].Time().Before(now))
+	p, err = write.ParseLineProtocol(srv.FreezeTimestamps("m v=1i"), time.Nanosecond)
+	require.NoError(t, err)
+	require.Len(t, p, 1)
+	assert.False(t, p[0].Time().Before(now))
+	assert.Equal(t, "m v=1i 1", srv.FreezeTimestamps("m v=1i 1"))
+}
+
+func TestIdempotentWritesDisabled(t *testing.T) {
+	hs := test.NewTestService(t, "http://localhost:8086")
+	opts := write.DefaultOptions()
+	srv := NewService("my-org", "my-bucket", hs, opts)
+	b := NewBatch("m v=1i\n", opts.MaxRetryTime())
+	require.NoError(t, srv.HandleWrite(context.Background(), b))
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=1i\n", opts.MaxRetryTime())))
+	assert.Empty(t, b.ID)
+	assert.Len(t, hs.Lines(), 2)
+	assert.Equal(t, "m v=1i", srv.FreezeTimestamps("m v=1i"))
+	line, err := srv.EncodePoints(write.NewPointWithMeasurement("m").AddField("v", 1))
+	require.NoError(t, err)
+	assert.Equal(t, "m v=1i\n", line)
+
+	// dedup window 0 disables deduplication
+	opts.SetIdempotentWrites(true).SetDedupWindow(0)
+	srv = NewService("my-org", "my-bucket", hs, opts)
+	assert.Nil(t, srv.dedup)
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=1i 1\n", opts.MaxRetryTime())))
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=1i 1\n", opts.MaxRetryTime())))
+	assert.Len(t, hs.Lines(), 4)
+}
+
+func TestV1Service(t *testing.T) {
+	hs := http.NewService("http://localhost:8086/", "Token my-token", http.DefaultOptions())
+	opts := write.DefaultOptions().SetPrecision(time.Microsecond).SetConsistency(write.ConsistencyAll)
+	srv := NewV1Service(write.V1Target{Database: "my-db", RetentionPolicy: "autogen"}, hs, opts)
+	assert.Equal(t, "http://localhost:8086/write?consistency=all&db=my-db&precision=u&rp=autogen", srv.WriteURL())
+	srv = NewV1Service(write.V1Target{Database: "my db"}, hs, write.DefaultOptions())
+	assert.Equal(t, "http://localhost:8086/write?db=my+db&precision=ns", srv.WriteURL())
+
+	var mu sync.Mutex
+	var auths []string
+	server := httptest.NewServer(ihttp.HandlerFunc(func(w ihttp.ResponseWriter, r *ihttp.Request) {
+		mu.Lock()
+		auths = append(auths, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.WriteHeader(ihttp.StatusNoContent)
+	}))
+	defer server.Close()
+	hs = http.NewService(server.URL+"/", "Token my-token", http.DefaultOptions())
+	workers := NewV1Workers(write.V1Target{Database: "my-db", Username: "user", Password: "pass"}, hs, write.DefaultOptions(), 2)
+	require.Len(t, workers, 2)
+	assert.Same(t, workers[0].backoff, workers[1].backoff)
+	require.NoError(t, workers[0].HandleWrite(context.Background(), NewBatch("m v=1i\n", 10_000)))
+	srv = NewV1Service(write.V1Target{Database: "my-db"}, hs, write.DefaultOptions())
+	require.NoError(t, srv.HandleWrite(context.Background(), NewBatch("m v=1i\n", 10_000)))
+	assert.Equal(t, []string{"Basic dXNlcjpwYXNz", "Token my-token"}, auths)
+}
+
+// deflateCodec is a custom codec
+type deflateCodec struct{}
+
+func (deflateCodec) Encoding() string { return "deflate" }
+
+func (deflateCodec) NewWriter(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.BestSpeed)
+	return fw
+}
+
+func (deflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+func TestCompression(t *testing.T) {
+	type request struct {
+		encoding string
+		body     string
+	}
+	var mu sync.Mutex
+	var requests []request
+	server := httptest.NewServer(ihttp.HandlerFunc(func(w ihttp.ResponseWriter, r *ihttp.Request) {
+		var body io.Reader = r.Body
+		switch r.Header.Get("Content-Encoding") {
+		case "gzip":
+			body, _ = gzip.NewReader(r.Body)
+		case "deflate":
+			body = flate.NewReader(r.Body)
+		}
+		b, _ := io.ReadAll(body)
+		mu.Lock()
+		requests = append(requests, request{encoding: r.Header.Get("Content-Encoding"), body: string(b)})
+		mu.Unlock()
+		w.WriteHeader(ihttp.StatusNoContent)
+	}))
+	defer server.Close()
+	hs := http.NewService(server.URL+"/", "", http.DefaultOptions())
+	small := "m v=1i\n"
+	large := strings.Repeat("m v=1i\n", 20)
+
+	opts := write.DefaultOptions().SetCompression(http.GzipCodec(gzip.BestCompression)).SetCompressionThreshold(100)
+	srv := NewService("my-org", "my-bucket", hs, opts)
+	require.Nil(t, srv.WriteBatch(context.Background(), NewBatch(small, 10_000)))
+	require.Nil(t, srv.WriteBatch(context.Background(), NewBatch(large, 10_000)))
+	opts.SetCompression(deflateCodec{}).SetCompressionThreshold(0)
+	require.Nil(t, srv.WriteBatch(context.Background(), NewBatch(small, 10_000)))
+	opts.SetCompression(nil)
+	require.Nil(t, srv.WriteBatch(context.Background(), NewBatch(large, 10_000)))
+	assert.Equal(t, []request{
+		{encoding: "", body: small},
+		{encoding: "gzip", body: large},
+		{encoding: "deflate", body: small},
+		{encoding: "", body: large},
+	}, requests)
+}
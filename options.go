@@ -153,6 +153,79 @@ func (o *Options) SetUseGZip(useGZip bool) *Options {
 	return o
 }
 
+// Compression returns codec compressing write requests, nil if requests are not compressed
+func (o *Options) Compression() http.Codec {
+	return o.WriteOptions().Compression()
+}
+
+// SetCompression sets codec compressing write requests, e.g. http.GzipCodec(gzip.BestSpeed).
+// Nil turns compression off.
+func (o *Options) SetCompression(codec http.Codec) *Options {
+	o.WriteOptions().SetCompression(codec)
+	return o
+}
+
+// CompressionThreshold returns minimum size of batch in bytes, which is compressed
+func (o *Options) CompressionThreshold() uint {
+	return o.WriteOptions().CompressionThreshold()
+}
+
+// SetCompressionThreshold sets minimum size of batch in bytes, which is compressed
+func (o *Options) SetCompressionThreshold(threshold uint) *Options {
+	o.WriteOptions().SetCompressionThreshold(threshold)
+	return o
+}
+
+// QueryCompression returns codecs accepted for compressed query responses
+func (o *Options) QueryCompression() []http.Codec {
+	return o.HTTPOptions().QueryCompression()
+}
+
+// SetQueryCompression sets codecs accepted for compressed query responses, in order of preference.
+// Without codecs, query responses are requested uncompressed.
+func (o *Options) SetQueryCompression(codecs ...http.Codec) *Options {
+	o.HTTPOptions().SetQueryCompression(codecs...)
+	return o
+}
+
+// CredentialsProvider returns provider of authorization of requests
+func (o *Options) CredentialsProvider() http.CredentialsProvider {
+	return o.HTTPOptions().CredentialsProvider()
+}
+
+// SetCredentialsProvider sets provider of authorization of requests, which is asked for the authorization before requests,
+// instead of using the authorization token. The authorization is cached until it expires,
+// when the server responds with 401 Unauthorized, it is refreshed and the request is sent once again.
+// It allows rotating the token without creating a new client.
+func (o *Options) SetCredentialsProvider(provider http.CredentialsProvider) *Options {
+	o.HTTPOptions().SetCredentialsProvider(provider)
+	return o
+}
+
+// HealthCheckInterval returns interval in ms of checking whether a preferred server is healthy again after a failover
+func (o *Options) HealthCheckInterval() uint {
+	return o.HTTPOptions().HealthCheckInterval()
+}
+
+// SetHealthCheckInterval sets interval in ms of checking whether a server preferred to the active server
+// of a client created by NewClientWithFailover is healthy again after a failover
+func (o *Options) SetHealthCheckInterval(healthCheckIntervalMs uint) *Options {
+	o.HTTPOptions().SetHealthCheckInterval(healthCheckIntervalMs)
+	return o
+}
+
+// FailoverCallback returns callback called when a client created by NewClientWithFailover switches servers
+func (o *Options) FailoverCallback() func(from, to string) {
+	return o.HTTPOptions().FailoverCallback()
+}
+
+// SetFailoverCallback sets callback called with server URLs when a client created by NewClientWithFailover switches
+// from a failed server to a healthy server, or back to a preferred server. The callback must not block.
+func (o *Options) SetFailoverCallback(callback func(from, to string)) *Options {
+	o.HTTPOptions().SetFailoverCallback(callback)
+	return o
+}
+
 // HTTPClient returns the http.Client that is configured to be used
 // for HTTP requests. It will return the one that has been set using
 // SetHTTPClient or it will construct a default client using the
@@ -5,6 +5,7 @@
 package influxdb2_test
 
 import (
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	ihttp "github.com/influxdata/influxdb-client-go/v2/api/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -34,6 +36,12 @@ func TestDefaultOptions(t *testing.T) {
 	assert.EqualValues(t, 20, opts.HTTPRequestTimeout())
 	assert.EqualValues(t, 0, opts.LogLevel())
 	assert.EqualValues(t, "", opts.ApplicationName())
+	assert.Nil(t, opts.Compression())
+	assert.EqualValues(t, 0, opts.CompressionThreshold())
+	assert.Len(t, opts.QueryCompression(), 1)
+	assert.Nil(t, opts.CredentialsProvider())
+	assert.EqualValues(t, 30_000, opts.HealthCheckInterval())
+	assert.Nil(t, opts.FailoverCallback())
 }
 
 func TestSettingsOptions(t *testing.T) {
@@ -55,7 +63,14 @@ func TestSettingsOptions(t *testing.T) {
 		SetHTTPRequestTimeout(50).
 		SetLogLevel(3).
 		AddDefaultTag("t", "a").
-		SetApplicationName("Monitor/1.1")
+		SetApplicationName("Monitor/1.1").
+		SetCompressionThreshold(512).
+		SetQueryCompression().
+		SetCredentialsProvider(ihttp.CredentialsProviderFunc(func(context.Context) (string, time.Time, error) {
+			return "Token my-token", time.Time{}, nil
+		})).
+		SetHealthCheckInterval(5_000).
+		SetFailoverCallback(func(from, to string) {})
 	assert.EqualValues(t, 5, opts.BatchSize())
 	assert.EqualValues(t, true, opts.UseGZip())
 	assert.EqualValues(t, 5_000, opts.FlushInterval())
@@ -75,6 +90,14 @@ func TestSettingsOptions(t *testing.T) {
 	}
 	assert.EqualValues(t, 3, opts.LogLevel())
 	assert.Len(t, opts.WriteOptions().DefaultTags(), 1)
+	assert.EqualValues(t, 512, opts.CompressionThreshold())
+	assert.Empty(t, opts.QueryCompression())
+	assert.NotNil(t, opts.CredentialsProvider())
+	assert.EqualValues(t, 5_000, opts.HealthCheckInterval())
+	assert.NotNil(t, opts.FailoverCallback())
+	opts.SetCompression(ihttp.GzipCodec(gzip.BestSpeed))
+	assert.Equal(t, ihttp.GzipCodec(gzip.BestSpeed), opts.Compression())
+	assert.True(t, opts.UseGZip())
 
 	client := &http.Client{
 		Transport: &http.Transport{},